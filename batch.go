@@ -0,0 +1,157 @@
+package gremgoser
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// AddVBatch takes a label and a slice of structs and adds them as vertices to the graph. The vertices are sent in
+// traversals of ClientConfig.BatchSize vertices, ClientConfig.BatchConcurrency of them at the same time, instead
+// of one request per vertex. There is a result per element of the slice, in the same order, holding the added
+// vertex or the error of the element. The error is only set when the batch cannot be sent at all.
+func (c *Client) AddVBatch(label string, data interface{}) ([]BatchResult, error) {
+	return c.AddVBatchContext(context.Background(), label, data)
+}
+
+// AddVBatchContext is the context aware version of AddVBatch.
+func (c *Client) AddVBatchContext(ctx context.Context, label string, data interface{}) ([]BatchResult, error) {
+	return c.batch(ctx, data, func(q *script, d reflect.Value) error {
+		if _, ok := idField(d); !ok {
+			return ErrorInterfaceHasNoIdField
+		}
+		q.add("addV(%s)", q.str(label))
+		return codecOf(d.Type()).addProps(q, d)
+	})
+}
+
+// AddEBatch takes a slice of edge structs and adds them as edges to the graph like AddEdge, the vertices are the
+// ids in the fields tagged `graph:"outV"` and `graph:"inV"`. The edges are sent and their results returned like in
+// AddVBatch. The traversal of a chunk stops at an edge whose vertices do not exist, so the edges of that chunk fail
+// with ErrorNotFound although the edges before it may have been added.
+func (c *Client) AddEBatch(edges interface{}) ([]BatchResult, error) {
+	return c.AddEBatchContext(context.Background(), edges)
+}
+
+// AddEBatchContext is the context aware version of AddEBatch.
+func (c *Client) AddEBatchContext(ctx context.Context, edges interface{}) ([]BatchResult, error) {
+	return c.batch(ctx, edges, func(q *script, d reflect.Value) error {
+		sc := codecOf(d.Type())
+		label, ok := sc.labelOf(d)
+		if !ok {
+			return ErrorEdgeHasNoLabel
+		}
+		out, in, ok := sc.verticesOf(d)
+		if !ok {
+			return ErrorEdgeHasNoVertices
+		}
+		oid, err := q.id(out.Interface())
+		if err != nil {
+			return err
+		}
+		l := q.str(label)
+		iid, err := q.id(in.Interface())
+		if err != nil {
+			return err
+		}
+		q.add("V(%s).addE(%s).to(g.V(%s))", oid, l, iid)
+		return sc.addProps(q, d)
+	})
+}
+
+// batch splits a slice of structs into chunks and sends every chunk as a single traversal. The steps of the
+// elements written by add are chained and labeled, so a select returns the element of every step. Elements that
+// cannot be written fail on their own and are left out of their chunk.
+func (c *Client) batch(ctx context.Context, data interface{}, add func(q *script, d reflect.Value) error) ([]BatchResult, error) {
+	c.verbose("passed interface: %s", lazyDump{data})
+	if c.pool.isDisposed() {
+		return nil, ErrorConnectionDisposed
+	}
+	s := getValue(data)
+	if s.Kind() != reflect.Slice {
+		return nil, ErrorNotSlice
+	}
+
+	results := make([]BatchResult, s.Len())
+	for i := range results {
+		results[i].Index = i
+	}
+	size, concurrency := 100, 1
+	if c.conf.BatchSize > 0 {
+		size = c.conf.BatchSize
+	}
+	if c.conf.BatchConcurrency > 0 {
+		concurrency = c.conf.BatchConcurrency
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for start := 0; start < len(results); start += size {
+		end := start + size
+		if end > len(results) {
+			end = len(results)
+		}
+		q := c.newScript()
+		q.add("g")
+		var chunk []int
+		for i := start; i < end; i++ {
+			n, bindings := q.query.Len(), len(q.bindings)
+			q.add(".")
+			if err := add(q, getValue(s.Index(i).Interface())); err != nil {
+				q.truncate(n, bindings)
+				results[i].Err = err
+				continue
+			}
+			q.add(".as('i%d')", len(chunk))
+			chunk = append(chunk, i)
+		}
+		if len(chunk) == 0 {
+			continue
+		}
+		if len(chunk) > 1 {
+			labels := make([]string, len(chunk))
+			for j := range chunk {
+				labels[j] = fmt.Sprintf("'i%d'", j)
+			}
+			q.add(".select(%s)", strings.Join(labels, ", "))
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(query string, bindings map[string]interface{}, chunk []int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			resp, err := c.ExecuteContext(ctx, query, bindings, nil)
+			c.batchResults(results, chunk, resp, err)
+		}(q.String(), q.params(), chunk)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// batchResults sets the results of the elements of a chunk from the response of its traversal, a single element
+// is returned as is and several as a map by the labels of their steps
+func (c *Client) batchResults(results []BatchResult, chunk []int, resp []*GremlinRespData, err error) {
+	for j, i := range chunk {
+		switch {
+		case err != nil:
+			results[i].Err = err
+		case len(resp) == 0:
+			results[i].Err = ErrorNotFound
+		case len(chunk) == 1:
+			results[i].Data = resp[0]
+		default:
+			m, ok := (*resp[0])[fmt.Sprintf("i%d", j)].(map[string]interface{})
+			if !ok {
+				results[i].Err = ErrorNotFound
+				continue
+			}
+			d := GremlinRespData(m)
+			results[i].Data = &d
+		}
+	}
+}
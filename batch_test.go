@@ -0,0 +1,168 @@
+package gremgoser
+
+import (
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stepLabel matches the step labels of a batch traversal
+var stepLabel = regexp.MustCompile(`\.as\('(i\d+)'\)`)
+
+// newBatchMock answers the traversals of a batch with an element per step label, the id of an element is its
+// label. Traversals of missing vertices return nothing and traversals with a 'boom' value fail.
+func newBatchMock() *recordingMock {
+	return &recordingMock{answer: func(gremlin string) (int, []interface{}) {
+		labels := stepLabel.FindAllStringSubmatch(gremlin, -1)
+		switch {
+		case strings.Contains(gremlin, "'boom'"):
+			return 597, nil
+		case strings.Contains(gremlin, "V(999)"):
+			return 200, nil
+		case len(labels) == 1:
+			return 200, []interface{}{map[string]interface{}{"id": labels[0][1], "label": "person", "type": "vertex"}}
+		}
+		selected := map[string]interface{}{}
+		for _, l := range labels {
+			selected[l[1]] = map[string]interface{}{"id": l[1], "label": "person", "type": "vertex"}
+		}
+		return 200, []interface{}{selected}
+	}}
+}
+
+func TestAddVBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newBatchMock()
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conf := NewClientConfig(u)
+	conf.SetBatchSize(2)
+	conf.SetBatchConcurrency(2)
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	people := []interface{}{
+		&IntPerson{Key: 1, Name: "a"},
+		&IntPerson{Key: 2, Name: "b"},
+		struct{ A string }{"no id"},
+		&IntPerson{Key: 3, Name: "c"},
+		&IntPerson{Key: 4, Name: "boom"},
+	}
+	results, err := g.AddVBatch("person", people)
+	assert.Nil(err)
+	assert.Equal(5, len(results))
+	for i, r := range results {
+		assert.Equal(i, r.Index)
+	}
+	assert.Nil(results[0].Err)
+	assert.Equal("i0", (*results[0].Data)["id"])
+	assert.Nil(results[1].Err)
+	assert.Equal("i1", (*results[1].Data)["id"])
+
+	// an element that cannot be written fails on its own and leaves no step behind
+	assert.Equal(ErrorInterfaceHasNoIdField, results[2].Err)
+	assert.Nil(results[2].Data)
+	assert.Nil(results[3].Err)
+	assert.Equal("i0", (*results[3].Data)["id"])
+
+	// a failing traversal fails its chunk
+	assert.True(errors.Is(results[4].Err, Error597ScriptEvaluationError))
+	assert.Nil(results[4].Data)
+
+	m.mu.Lock()
+	scripts := append([]string(nil), m.scripts...)
+	m.mu.Unlock()
+	sort.Strings(scripts)
+	assert.Equal([]string{
+		"g.addV('person').property('id', 1).property('name', 'a').as('i0').addV('person').property('id', 2).property('name', 'b').as('i1').select('i0', 'i1')",
+		"g.addV('person').property('id', 3).property('name', 'c').as('i0')",
+		"g.addV('person').property('id', 4).property('name', 'boom').as('i0')",
+	}, scripts)
+
+	// bindings of a skipped element are dropped
+	q := g.newScript()
+	q.add("g.addV(%s)", q.str("person"))
+	q.truncate(len("g"), 0)
+	q.add(".addV(%s)", q.str("user"))
+	assert.Equal("g.addV(_p0)", q.String())
+	assert.Equal(map[string]interface{}{"_p0": "user"}, q.params())
+
+	// only slices are batched
+	_, err = g.AddVBatch("person", &IntPerson{Key: 1})
+	assert.Equal(ErrorNotSlice, err)
+	results, err = g.AddVBatch("person", []IntPerson{})
+	assert.Nil(err)
+	assert.Equal(0, len(results))
+}
+
+func TestAddEBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newBatchMock()
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conf := NewClientConfig(u)
+	conf.SetDisableBindings()
+	conf.SetBatchSize(3)
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	results, err := g.AddEBatch([]Knows{
+		{Label: "knows", OutV: 1, InV: 2, Weight: 0.5},
+		{OutV: 1, InV: 3},
+		{Label: "knows", OutV: 2, InV: 3},
+		{Label: "knows", OutV: 999, InV: 3},
+	})
+	assert.Nil(err)
+	assert.Nil(results[0].Err)
+	assert.Equal("i0", (*results[0].Data)["id"])
+	assert.Equal(ErrorEdgeHasNoLabel, results[1].Err)
+	assert.Nil(results[2].Err)
+	assert.Equal("i1", (*results[2].Data)["id"])
+	assert.Equal(ErrorNotFound, results[3].Err)
+
+	m.mu.Lock()
+	scripts := append([]string(nil), m.scripts...)
+	m.mu.Unlock()
+	sort.Strings(scripts)
+	assert.Equal([]string{
+		"g.V(1).addE('knows').to(g.V(2)).property('weight', 0.5).property('since', 0).as('i0').V(2).addE('knows').to(g.V(3)).property('weight', 0).property('since', 0).as('i1').select('i0', 'i1')",
+		"g.V(999).addE('knows').to(g.V(3)).property('weight', 0).property('since', 0).as('i0')",
+	}, scripts)
+
+	// edges need their vertices
+	results, err = g.AddEBatch([]struct {
+		Label string `graph:"label"`
+		OutV  int64  `graph:"outV"`
+	}{{Label: "knows", OutV: 1}})
+	assert.Nil(err)
+	assert.Equal(ErrorEdgeHasNoVertices, results[0].Err)
+}
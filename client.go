@@ -2,10 +2,13 @@ package gremgoser
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,96 +16,122 @@ import (
 	"github.com/intwinelabs/logger"
 
 	"github.com/google/uuid"
+	"github.com/intwinelabs/gremgoser/traversal"
 )
 
 func newClient(conf *ClientConfig) *Client {
-	return &Client{
+	c := &Client{
 		conf:             conf,
-		requests:         make(chan []byte, 3), // c.requests takes any request and delivers it to the WriteWorker for dispatch to Gremlin Server
-		responses:        make(chan []byte, 3), // c.responses takes raw responses from ReadWorker and delivers it for sorting to handelResponse
 		results:          &sync.Map{},
+		values:           &sync.Map{},
+		diagnostics:      &sync.Map{},
 		responseNotifier: &sync.Map{},
 		respMutex:        &sync.Mutex{}, // c.mutex ensures that sorting is thread safe
 	}
+	if conf != nil {
+		c.pool = newPool(c, conf.PoolSize, conf.MinIdle, conf.MaxInFlight)
+	} else {
+		c.pool = newPool(c, 1, 1, 0)
+	}
+	return c
 }
 
 // NewClient returns a gremgoser client for interaction with the Gremlin Server specified in the host IP.
 func NewClient(conf *ClientConfig) (*Client, chan error) {
-	errs := make(chan error)
+	// the channel is buffered so the errors of a failed connect are returned without a reader waiting
+	errs := make(chan error, 1)
 	if conf.URI == "" {
 		errs <- ErrorInvalidURI
 		return nil, errs
 	}
 
-	c := newClient(conf)
-	c.errs = errs
-
-	ws := &Ws{
-		debug:     conf.Debug,
-		verbose:   conf.Verbose,
-		uri:       conf.URI,
-		connected: false,
-		quit:      make(chan struct{}),
-		logger:    conf.Logger,
-	}
-
 	// check for configs
 	if conf.Logger == nil {
 		conf.Logger = logger.New()
 	}
-	if conf.Timeout != 0 {
-		ws.timeout = conf.Timeout
-	} else {
-		ws.timeout = 300 * time.Second
-	}
-	if conf.PingInterval != 0 {
-		ws.pingInterval = conf.PingInterval
-	} else {
-		ws.pingInterval = 60 * time.Second
+	if conf.WritingWait == 0 {
+		conf.WritingWait = 120 * time.Second
 	}
-	if conf.WritingWait != 0 {
-		ws.writingWait = conf.WritingWait
-	} else {
-		ws.writingWait = 120 * time.Second
-	}
-	if conf.ReadingWait != 0 {
-		ws.readingWait = conf.ReadingWait
-	} else {
-		ws.readingWait = 120 * time.Second
+	if conf.ReadingWait == 0 {
+		conf.ReadingWait = 120 * time.Second
 	}
-	c.conn = ws
-	c.conf = conf
+
+	c := newClient(conf)
+	c.errs = errs
 
 	// Connects to Gremlin Server
-	err := c.conn.connect()
+	c.setState(StateConnecting)
+	err := c.pool.fill()
 	if err != nil {
 		c.debug("error connecting to %s: %s", conf.URI, err)
+		c.setState(StateDisconnected)
 		errs <- ErrorWSConnection
 		return nil, errs
 	}
-
-	quit := c.conn.(*Ws).quit
-
-	go c.writeWorker(c.errs, quit)
-	go c.readWorker(c.errs, quit)
-	go c.conn.ping(c.errs)
+	c.setState(StateConnected)
 
 	return c, c.errs
 }
 
-// Reconnect tries to reconnect the underlying ws connection
+// Reconnect tries to reconnect the underlying ws connections
 func (c *Client) Reconnect() {
-	if !c.conn.isConnected() {
-		err := c.conn.connect()
+	if !c.pool.isConnected() {
+		err := c.pool.reconnect()
 		if err != nil {
-			c.errs <- err
+			c.reportError(err)
 		}
 	}
 }
 
+// HasErrored reports whether a connection of the client failed since it was created
+func (c *Client) HasErrored() bool {
+	c.pool.mu.Lock()
+	defer c.pool.mu.Unlock()
+	return c.Errored
+}
+
 // IsConnected return bool
 func (c *Client) IsConnected() bool {
-	return c.conn.isConnected()
+	return c.pool.isConnected()
+}
+
+// setState records a connection state transition and hands it to the configured callback
+func (c *Client) setState(state ConnectionState) {
+	c.pool.mu.Lock()
+	if c.pool.state == state {
+		c.pool.mu.Unlock()
+		return
+	}
+	c.pool.state = state
+	c.pool.mu.Unlock()
+	c.debug("connection state: %s", state)
+	if c.conf.OnStateChange != nil {
+		c.conf.OnStateChange(state)
+	}
+}
+
+// replay writes an idempotent request again after the connection it was in flight on was lost
+func (c *Client) replay(id uuid.UUID, msg []byte) {
+	if _, ok := c.responseNotifier.Load(id); !ok { // the requester stopped waiting
+		return
+	}
+	// drop partial results of the lost attempt
+	c.respMutex.Lock()
+	c.deleteResponse(id)
+	c.respMutex.Unlock()
+	c.debug("replaying request: %s", id)
+	c.pool.replayable(id, msg)
+	if err := c.dispatchRequest(context.Background(), id, msg); err != nil {
+		c.debug("error replaying request %s: %s", id, err)
+	}
+}
+
+// mutatingStep matches the steps that make a script unsafe to replay after a connection loss
+var mutatingStep = regexp.MustCompile(`\b(addV|addE|property|drop|mergeV|mergeE|sideEffect|tx)\s*\(`)
+
+// isIdempotent reports whether a script only reads from the graph and can be replayed safely
+func isIdempotent(query string) bool {
+	return !mutatingStep.MatchString(query)
 }
 
 // debug prints to the configured logger if debug is enabled
@@ -126,47 +155,139 @@ func (c *Client) veryVerbose(frmt string, i ...interface{}) {
 	}
 }
 
-func (c *Client) executeRequest(query string, bindings, rebindings map[string]interface{}) ([]*GremlinRespData, error) {
-	req := prepareRequest(query, bindings, rebindings)
-	msg, err := packageRequest(req)
+// lazyDump defers dumping a value with spew until a log line is actually written
+type lazyDump struct {
+	v interface{}
+}
+
+func (d lazyDump) String() string {
+	return spew.Sdump(d.v)
+}
+
+func (c *Client) executeRequest(ctx context.Context, query string, bindings, rebindings map[string]interface{}) ([]*GremlinRespData, error) {
+	id, err := c.sendRequest(ctx, query, bindings, rebindings)
 	if err != nil {
-		c.debug("error packing request: %s", err)
 		return nil, err
 	}
+	return c.retrieveResponse(ctx, id)
+}
+
+// sendRequest registers and dispatches a script evaluation, the response is retrieved by its request id
+func (c *Client) sendRequest(ctx context.Context, query string, bindings, rebindings map[string]interface{}) (uuid.UUID, error) {
+	return c.submitRequest(ctx, prepareRequest(query, bindings, rebindings), uuid.Nil, isIdempotent(query))
+}
+
+// submitRequest serializes, registers and dispatches a prepared request. Requests of a session go to the
+// connection of the session and are never replayed, the session does not survive its connection.
+func (c *Client) submitRequest(ctx context.Context, req *GremlinRequest, session uuid.UUID, idempotent bool) (uuid.UUID, error) {
+	if err := ctx.Err(); err != nil {
+		return uuid.Nil, err
+	}
+	msg, err := c.serializer().serializeRequest(req)
+	if err != nil {
+		c.debug("error packing request: %s", err)
+		return uuid.Nil, err
+	}
 	c.debug("packed request: %+v", req)
 	id := req.RequestId
-	c.responseNotifier.Store(id, make(chan int, 1))
-	c.dispatchRequest(msg)
-	resp := c.retrieveResponse(id)
-	return resp, nil
+	c.responseNotifier.Store(id, make(chan error, 1))
+	if session != uuid.Nil {
+		err = c.dispatchSessionRequest(ctx, session, id, msg)
+	} else {
+		if c.conf.ReplayIdempotent && idempotent {
+			c.pool.replayable(id, msg)
+		}
+		err = c.dispatchRequest(ctx, id, msg)
+	}
+	if err != nil {
+		c.responseNotifier.Delete(id)
+		return uuid.Nil, err
+	}
+	return id, nil
 }
 
 func (c *Client) authenticate(requestId uuid.UUID) (err error) {
-	c.conf.AuthReq.RequestId = requestId
-	msg, err := packageRequest(c.conf.AuthReq)
+	if c.conf.AuthReq == nil {
+		return ErrorNoAuth
+	}
+	// every connection of the pool answers its own challenges, so the shared auth request is copied
+	req := *c.conf.AuthReq
+	req.RequestId = requestId
+	msg, err := c.serializer().serializeRequest(&req)
 	if err != nil {
 		c.debug("error authenticating to ws server: %s", err)
 		return err
 	}
-	c.dispatchRequest(msg)
-	return err
+	return c.dispatchRequest(context.Background(), requestId, msg)
 }
 
 // Execute formats a raw Gremlin query, sends it to Gremlin Server, and returns the result.
 func (c *Client) Execute(query string, bindings, rebindings map[string]interface{}) ([]*GremlinRespData, error) {
-	c.verbose("connection: %+v", c.conn)
-	if c.conn.isDisposed() {
+	return c.ExecuteContext(context.Background(), query, bindings, rebindings)
+}
+
+// ExecuteContext is like Execute but stops waiting for the response and returns ctx.Err() when the context is done.
+func (c *Client) ExecuteContext(ctx context.Context, query string, bindings, rebindings map[string]interface{}) ([]*GremlinRespData, error) {
+	if c.pool.isDisposed() {
 		return nil, ErrorConnectionDisposed
 	}
 	c.verbose("query: %s", query)
-	resp, err := c.executeRequest(query, bindings, rebindings)
+	resp, err := c.executeRequest(ctx, query, bindings, rebindings)
 	c.verbose("response: %+v", spew.Sprint(resp))
 	return resp, err
 }
 
+// ExecuteValues formats a raw Gremlin query, sends it to Gremlin Server, and returns the results with their
+// GraphSON types decoded to Go types like int64, time.Time, uuid.UUID, *Vertex, *Edge and *Path.
+func (c *Client) ExecuteValues(query string, bindings, rebindings map[string]interface{}) ([]interface{}, error) {
+	return c.ExecuteValuesContext(context.Background(), query, bindings, rebindings)
+}
+
+// ExecuteValuesContext is the context aware version of ExecuteValues.
+func (c *Client) ExecuteValuesContext(ctx context.Context, query string, bindings, rebindings map[string]interface{}) ([]interface{}, error) {
+	if c.pool.isDisposed() {
+		return nil, ErrorConnectionDisposed
+	}
+	c.verbose("query: %s", query)
+	id, err := c.sendRequest(ctx, query, bindings, rebindings)
+	if err != nil {
+		return nil, err
+	}
+	values, err := c.retrieveValues(ctx, id)
+	c.verbose("values: %+v", spew.Sprint(values))
+	return values, err
+}
+
+// ExecuteBytecode submits the traversal as bytecode, which Gremlin Server runs without compiling a Groovy script,
+// and returns the results like ExecuteValues. A traversal spawned from traversal.NewSource("modern") runs on the
+// traversal source the server binds to modern.
+func (c *Client) ExecuteBytecode(t *traversal.Traversal) ([]interface{}, error) {
+	return c.ExecuteBytecodeContext(context.Background(), t)
+}
+
+// ExecuteBytecodeContext is the context aware version of ExecuteBytecode.
+func (c *Client) ExecuteBytecodeContext(ctx context.Context, t *traversal.Traversal) ([]interface{}, error) {
+	if c.pool.isDisposed() {
+		return nil, ErrorConnectionDisposed
+	}
+	c.verbose("traversal: %s", t)
+	id, err := c.submitRequest(ctx, prepareBytecodeRequest(t), uuid.Nil, isIdempotent(t.String()))
+	if err != nil {
+		return nil, err
+	}
+	values, err := c.retrieveValues(ctx, id)
+	c.verbose("values: %+v", spew.Sprint(values))
+	return values, err
+}
+
 // Get formats a raw Gremlin query, sends it to Gremlin Server, and populates the passed []interface.
 func (c *Client) Get(query string, bindings map[string]interface{}, ptr interface{}) error {
-	if c.conn.isDisposed() {
+	return c.GetContext(context.Background(), query, bindings, ptr)
+}
+
+// GetContext is the context aware version of Get.
+func (c *Client) GetContext(ctx context.Context, query string, bindings map[string]interface{}, ptr interface{}) error {
+	if c.pool.isDisposed() {
 		return ErrorConnectionDisposed
 	}
 	var strct reflect.Value
@@ -179,7 +300,7 @@ func (c *Client) Get(query string, bindings map[string]interface{}, ptr interfac
 	}
 
 	var respSlice []*GremlinData
-	respDataSlice, err := c.executeRequest(query, bindings, nil)
+	respDataSlice, err := c.executeRequest(ctx, query, bindings, nil)
 	if err != nil {
 		return err
 	}
@@ -198,7 +319,9 @@ func (c *Client) Get(query string, bindings map[string]interface{}, ptr interfac
 	}
 	decoder := json.NewDecoder(bytes.NewReader(obj))
 	decoder.UseNumber()
-	if _, ok := (*respDataSlice[0])["properties"]; ok {
+	// vertices come with properties, edges may have none
+	first := *respDataSlice[0]
+	if _, ok := first["properties"]; ok || first["type"] == "edge" {
 		err := decoder.Decode(&respSlice)
 		//err := json.Unmarshal(obj, &respSlice)
 		if err != nil {
@@ -215,461 +338,345 @@ func (c *Client) Get(query string, bindings map[string]interface{}, ptr interfac
 		return nil
 	}
 
-	c.veryVerbose("Response Data Slice: %s", spew.Sdump(respSlice))
+	c.veryVerbose("Response Data Slice: %s", lazyDump{respSlice})
 
 	// get the underlying struct type
 	sType := reflect.TypeOf(strct.Interface()).Elem()
+	if sType.Kind() != reflect.Struct {
+		return errors.New("the passed interface is not a slice of structs")
+	}
+
+	// the struct fields are reflected once per type
+	sc := codecOf(sType)
+	if sc.id < 0 {
+		return errors.New("the passed interface must have an Id field")
+	}
 
 	// create new slice to later copy back
 	lenRespSlice := len(respSlice)
 	sSlice := reflect.MakeSlice(reflect.SliceOf(sType), lenRespSlice, lenRespSlice+1)
-	// iterate over the GremlinData respSlice
+	// iterate over the GremlinData respSlice and populate the structs in place
 	for j, innerItem := range respSlice {
-		// create a new struct to populate
-		s := reflect.New(sType)
-		// check for Id field
-		ok := s.Elem().FieldByName("Id").IsValid()
-		if !ok {
-			return errors.New("the passed interface must have an Id field")
-		}
-		uuidType := s.Elem().FieldByName("Id").Kind()
-		// iterate over fields and populate
-		for i := 0; i < s.Elem().NumField(); i++ {
-			// get graph tag for field
-			tag := sType.Field(i).Tag.Get("graph")
-			name, opts := parseTag(tag)
-			c.veryVerbose("Struct Field ==> Name: %s, Opts: %s", name, opts)
-			if len(name) == 0 && len(opts) == 0 {
-				continue
-			}
-			// get the current field
-			f := s.Elem().Field(i)
-			// check if we can modify
-			if f.CanSet() {
-				isPtr, isSlice := false, false
-				var kind reflect.Kind
-				// if it's a pointer or slice get the underlying interface
-				if f.Kind() == reflect.Ptr {
-					isPtr = true
-					kind = f.Type().Elem().Kind()
-				} else if f.Kind() == reflect.Slice {
-					isSlice = true
-					kind = f.Type().Elem().Kind()
-				} else {
-					kind = f.Kind()
-				}
-				_ = isSlice
-				c.veryVerbose("Struct Field Type: %s", kind)
-				if f.Kind() == uuidType { // if its the Id field we look in the base response map
-					// create a UUID
-					f.Set(reflect.ValueOf(innerItem.Id))
-				} else { // it is a property and we have to looks at the properties map
-					props := innerItem.Properties
-					// check if the key is in the map
-					if _, ok := props[name]; ok {
-						// get the properties slice
-						propSlice := reflect.ValueOf(props[name])
-						propSliceLen := propSlice.Len()
-						// check the length, if its 1 we set it as a single value otherwise we need to create a slice
-						if propSliceLen == 1 {
-							// get the value of the property we are looking for
-							v, err := getPropertyValue(propSlice.Index(0).Interface())
-							if err != nil {
-								return err
-							}
-							switch kind {
-							case reflect.String: // Set as string
-								vString, ok := v.(string)
-								if ok {
-									if isPtr {
-										f.Set(reflect.ValueOf(&vString))
-									} else {
-										f.SetString(vString)
-									}
-								}
-							case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64: // Set as int
-								vNumber, ok := v.(json.Number)
-								if ok {
-									vInt, _ := vNumber.Int64()
-									if !f.OverflowInt(vInt) {
-										if isPtr {
-											f.Set(reflect.ValueOf(&vInt))
-										} else {
-											f.SetInt(vInt)
-										}
-									}
-								}
-							case reflect.Float32, reflect.Float64: // Set as float
-								vNumber, ok := v.(json.Number)
-								if ok {
-									vFloat, _ := vNumber.Float64()
-									if !f.OverflowFloat(vFloat) {
-										if isPtr {
-											f.Set(reflect.ValueOf(&vFloat))
-										} else {
-											f.SetFloat(vFloat)
-										}
-									}
-								}
-							case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64: // Set as uint
-								vNumber, ok := v.(json.Number)
-								if ok {
-									vInt, _ := vNumber.Int64()
-									vUint := uint64(vInt)
-									if !f.OverflowUint(vUint) {
-										if isPtr {
-											f.Set(reflect.ValueOf(&vUint))
-										} else {
-											f.SetUint(vUint)
-										}
-									}
-								}
-							case reflect.Bool: // Set as bool
-								vBool, ok := v.(bool)
-								if ok {
-									if isPtr {
-										f.Set(reflect.ValueOf(&vBool))
-									} else {
-										f.SetBool(vBool)
-									}
-								}
-							case reflect.Struct, reflect.Map: // take JSON string and unmarshal into struct or map
-								vString, ok := v.(string)
-								if ok {
-									s := reflect.New(f.Type()).Interface()
-									json.Unmarshal([]byte(vString), s)
-									if isPtr {
-										f.Set(reflect.ValueOf(s))
-									} else {
-										f.Set(reflect.ValueOf(s).Elem())
-									}
-								}
-							}
-							// this is a special case
-							if isSlice && opts.Contains("struct") { // take JSON string and unmarshal into slice
-								vString, ok := v.(string) // the data is stored as a struct string in the graph
-								if ok {
-									sSlice := reflect.SliceOf(f.Type().Elem())
-									s := reflect.New(sSlice)
-									json.Unmarshal([]byte(vString), s.Interface())
-									if isPtr {
-										sInterface := s.Interface()
-										f.Set(reflect.ValueOf(sInterface))
-									} else {
-										f.Set(s.Elem())
-									}
-								}
-							}
-						} else if propSliceLen > 1 { // we need to creates slices for the properties
-							pSlice := reflect.MakeSlice(reflect.SliceOf(f.Type().Elem()), propSliceLen, propSliceLen+1)
-							// now we iterate over the properties
-							for i := 0; i < propSliceLen; i++ {
-								// get the value of the property we are looking for
-								v, err := getPropertyValue(propSlice.Index(i).Interface())
-								if err != nil {
-									return err
-								}
-								switch kind {
-								case reflect.String: // Set as string
-									vString, ok := v.(string)
-									if ok {
-										pSlice.Index(i).SetString(vString)
-									}
-								case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64: // Set as int
-									vNumber, ok := v.(json.Number)
-									if ok {
-										vInt, _ := vNumber.Int64()
-										if !pSlice.Index(i).OverflowInt(vInt) {
-											pSlice.Index(i).SetInt(vInt)
-										}
-									}
-								case reflect.Float32, reflect.Float64: // Set as float
-									vNumber, ok := v.(json.Number)
-									if ok {
-										vFloat, _ := vNumber.Float64()
-										if !pSlice.Index(i).OverflowFloat(vFloat) {
-											pSlice.Index(i).SetFloat(vFloat)
-										}
-									}
-								case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64: // Set as uint
-									vNumber, ok := v.(json.Number)
-									if ok {
-										vInt, _ := vNumber.Int64()
-										vUint := uint64(vInt)
-										if !pSlice.Index(i).OverflowUint(vUint) {
-											pSlice.Index(i).SetUint(vUint)
-										}
-									}
-								case reflect.Bool: // Set as bool
-									vBool, ok := v.(bool)
-									if ok {
-										pSlice.Index(i).SetBool(vBool)
-									}
-								}
-							}
-							// set the field to the created slice
-							f.Set(pSlice)
-						}
-					}
-				}
-			}
+		if err := sc.decode(innerItem, sSlice.Index(j)); err != nil {
+			return err
 		}
-		// update slice
-		sSlice.Index(j).Set(s.Elem())
 	}
 	// Copy the new slice to the passed data slice
 	strct.Set(sSlice)
 
-	c.veryVerbose("Interface de-serialized: %+v", spew.Sdump(ptr))
+	c.veryVerbose("Interface de-serialized: %+v", lazyDump{ptr})
 
 	return nil
 }
 
+// GetAs runs a raw Gremlin query and decodes the result into a slice of T, using the graph tags of T like Get.
+func GetAs[T any](ctx context.Context, c *Client, query string, bindings map[string]interface{}) ([]T, error) {
+	var items []T
+	if err := c.GetContext(ctx, query, bindings, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetOne is like GetAs but returns the first result only, or ErrorNotFound when the result is empty.
+func GetOne[T any](ctx context.Context, c *Client, query string, bindings map[string]interface{}) (T, error) {
+	var item T
+	items, err := GetAs[T](ctx, c, query, bindings)
+	if err != nil {
+		return item, err
+	}
+	if len(items) == 0 {
+		return item, ErrorNotFound
+	}
+	return items[0], nil
+}
+
 // Close closes the underlying connection and marks the client as closed.
 func (c *Client) Close() {
-	if c.conn != nil {
-		c.conn.close()
+	if c.pool != nil {
+		c.pool.close()
+		c.setState(StateClosed)
 	}
 }
 
 // AddV takes a label and a interface and adds it a vertex to the graph
 func (c *Client) AddV(label string, data interface{}) ([]*GremlinRespData, error) {
-	c.verbose("passed interface: %s", spew.Sdump(data))
-	if c.conn.isDisposed() {
+	return c.AddVContext(context.Background(), label, data)
+}
+
+// AddVContext is the context aware version of AddV.
+func (c *Client) AddVContext(ctx context.Context, label string, data interface{}) ([]*GremlinRespData, error) {
+	c.verbose("passed interface: %s", lazyDump{data})
+	if c.pool.isDisposed() {
 		return nil, ErrorConnectionDisposed
 	}
 	d := getValue(data)
 
-	id := d.FieldByName("Id")
-	if !id.IsValid() {
+	if _, ok := idField(d); !ok {
 		return nil, ErrorInterfaceHasNoIdField
 	}
 
-	q := fmt.Sprintf("g.addV('%s')", label)
-
-	tagLength := 0
-
-	for i := 0; i < d.NumField(); i++ {
-		tag := d.Type().Field(i).Tag.Get("graph")
-		name, opts := parseTag(tag)
-		if len(name) == 0 && len(opts) == 0 {
-			continue
-		}
-		tagLength++
-		val := d.Field(i).Interface()
-		if reflect.TypeOf(val).Kind() == reflect.Ptr {
-			val = reflect.ValueOf(val).Elem().Interface()
-		}
-		if len(opts) == 0 {
-			return nil, fmt.Errorf("gremgoser: interface field graph tag does not contain a tag option type, field type: %T", val)
-		} else if opts.Contains("string") || opts.Contains("partitionKey") {
-			q = fmt.Sprintf("%s.property('%s', '%s')", q, name, escapeString(fmt.Sprintf("%s", val)))
-		} else if opts.Contains("bool") || opts.Contains("number") {
-			q = fmt.Sprintf("%s.property('%s', %v)", q, name, val)
-		} else if opts.Contains("struct") || opts.Contains("[]struct") {
-			jsonBytes, err := json.Marshal(val)
-			if err != nil {
-				return nil, err
-			}
-			q = fmt.Sprintf("%s.property('%s', '%s')", q, name, jsonBytes)
-		} else if opts.Contains("[]string") {
-			s := reflect.ValueOf(val)
-			for i := 0; i < s.Len(); i++ {
-				q = fmt.Sprintf("%s.property('%s', '%s')", q, name, escapeString(fmt.Sprintf("%s", s.Index(i).Interface())))
-			}
-		} else if opts.Contains("[]bool") || opts.Contains("[]number") {
-			s := reflect.ValueOf(val)
-			for i := 0; i < s.Len(); i++ {
-				q = fmt.Sprintf("%s.property('%s', %v)", q, name, s.Index(i).Interface())
-			}
-		}
-	}
-
-	if tagLength == 0 {
-		return nil, ErrorInterfaceHasNoIdField
+	q := c.newScript()
+	q.add("g.addV(%s)", q.str(label))
+	if err := codecOf(d.Type()).addProps(q, d); err != nil {
+		return nil, err
 	}
 
-	return c.Execute(q, nil, nil)
+	return c.ExecuteContext(ctx, q.String(), q.params(), nil)
 }
 
 // UpdateV takes a interface and updates the vertex in the graph
 func (c *Client) UpdateV(data interface{}) ([]*GremlinRespData, error) {
-	c.verbose("passed interface: %s", spew.Sdump(data))
-	if c.conn.isDisposed() {
+	return c.UpdateVContext(context.Background(), data)
+}
+
+// UpdateVContext is the context aware version of UpdateV.
+func (c *Client) UpdateVContext(ctx context.Context, data interface{}) ([]*GremlinRespData, error) {
+	c.verbose("passed interface: %s", lazyDump{data})
+	if c.pool.isDisposed() {
 		return nil, ErrorConnectionDisposed
 	}
 	d := getValue(data)
 
-	id := d.FieldByName("Id")
-	if !id.IsValid() {
-		return nil, ErrorInterfaceHasNoIdField
+	q := c.newScript()
+	id, err := q.idOf(data)
+	if err != nil {
+		return nil, err
+	}
+	q.add("g.V(%s)", id)
+	if err := codecOf(d.Type()).updateV(q, d); err != nil {
+		return nil, err
 	}
 
-	q := fmt.Sprintf("g.V('%s')", id)
+	return c.ExecuteContext(ctx, q.String(), q.params(), nil)
+}
 
-	tagLength := 0
+// UpsertV takes a label and a pointer to a struct and adds the vertex to the graph when no vertex with its id and
+// partition key exists, or updates it otherwise. All tagged properties are set like in UpdateV and the resulting
+// vertex is decoded back into the struct. The id is written as the 'id' property Cosmos DB looks vertices up by,
+// servers like TinkerGraph only find the vertex again with ClientConfig.IdToken set.
+func (c *Client) UpsertV(label string, data interface{}) error {
+	return c.UpsertVContext(context.Background(), label, data)
+}
 
-	for i := 0; i < d.NumField(); i++ {
-		tag := d.Type().Field(i).Tag.Get("graph")
-		name, opts := parseTag(tag)
-		if (len(name) == 0 && len(opts) == 0) || d.Type().Field(i).Name == "Id" {
-			continue
-		}
-		tagLength++
-		val := d.Field(i).Interface()
-		if len(opts) == 0 {
-			return nil, fmt.Errorf("gremgoser: interface field graph tag does not contain a tag option type, field type: %T", val)
-		} else if opts.Contains("partitionKey") {
-			q = fmt.Sprintf("%s.has('%s', '%s')", q, name, escapeString(fmt.Sprintf("%s", val)))
-		} else if opts.Contains("string") {
-			q = fmt.Sprintf("%s.property('%s', '%s')", q, name, escapeString(fmt.Sprintf("%s", val)))
-		} else if opts.Contains("bool") || opts.Contains("number") {
-			q = fmt.Sprintf("%s.property('%s', %v)", q, name, val)
-		} else if opts.Contains("struct") || opts.Contains("[]struct") {
-			jsonBytes, err := json.Marshal(val)
-			if err != nil {
-				return nil, err
-			}
-			q = fmt.Sprintf("%s.property('%s', '%s')", q, name, jsonBytes)
-		} else if opts.Contains("[]string") {
-			// drop the properties
-			q = fmt.Sprintf("%s.sideEffect(properties('%s').drop())", q, name)
-			s := reflect.ValueOf(val)
-			for i := 0; i < s.Len(); i++ {
-				q = fmt.Sprintf("%s.property(list, '%s', '%s')", q, name, escapeString(fmt.Sprintf("%s", s.Index(i).Interface())))
-			}
-		} else if opts.Contains("[]bool") || opts.Contains("[]number") {
-			// drop the properties
-			q = fmt.Sprintf("%s.sideEffect(properties('%s').drop())", q, name)
-			s := reflect.ValueOf(val)
-			for i := 0; i < s.Len(); i++ {
-				q = fmt.Sprintf("%s.property(list, '%s', %v)", q, name, s.Index(i).Interface())
-			}
-		}
+// UpsertVContext is the context aware version of UpsertV.
+func (c *Client) UpsertVContext(ctx context.Context, label string, data interface{}) error {
+	c.verbose("passed interface: %s", lazyDump{data})
+	if c.pool.isDisposed() {
+		return ErrorConnectionDisposed
+	}
+	ptr := reflect.ValueOf(data)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return ErrorNotStructPointer
 	}
+	d := ptr.Elem()
 
-	if tagLength == 0 {
-		return nil, ErrorInterfaceHasNoIdField
+	f, ok := idField(d)
+	if !ok || f.IsZero() {
+		return ErrorInterfaceHasNoIdField
+	}
+	q := c.newScript()
+	id, err := q.id(f.Interface())
+	if err != nil {
+		return err
+	}
+	if err := codecOf(d.Type()).upsertV(q, label, id, d); err != nil {
+		return err
 	}
 
-	return c.Execute(q, nil, nil)
+	// decode the vertex into a new value so a failing decode leaves the struct as it was
+	items := reflect.New(reflect.SliceOf(d.Type()))
+	if err := c.GetContext(ctx, q.String(), q.params(), items.Interface()); err != nil {
+		return err
+	}
+	if items.Elem().Len() == 0 {
+		return ErrorNotFound
+	}
+	d.Set(items.Elem().Index(0))
+	return nil
 }
 
 // DropV takes a interface and drops the vertex from the graph
 func (c *Client) DropV(data interface{}) ([]*GremlinRespData, error) {
-	c.verbose("passed interface: %s", spew.Sdump(data))
-	if c.conn.isDisposed() {
+	return c.DropVContext(context.Background(), data)
+}
+
+// DropVContext is the context aware version of DropV.
+func (c *Client) DropVContext(ctx context.Context, data interface{}) ([]*GremlinRespData, error) {
+	c.verbose("passed interface: %s", lazyDump{data})
+	if c.pool.isDisposed() {
 		return nil, ErrorConnectionDisposed
 	}
-	d := getValue(data)
-
-	id := d.FieldByName("Id")
-	if !id.IsValid() {
-		return nil, ErrorInterfaceHasNoIdField
+	q := c.newScript()
+	id, err := q.idOf(data)
+	if err != nil {
+		return nil, err
 	}
-
-	q := fmt.Sprintf("g.V('%s').drop()", id)
-	return c.Execute(q, nil, nil)
+	q.add("g.V(%s).drop()", id)
+	return c.ExecuteContext(ctx, q.String(), q.params(), nil)
 }
 
 // AddE takes a label, from UUID and to UUID then creates a edge between the two vertex in the graph
 func (c *Client) AddE(label string, from, to interface{}) ([]*GremlinRespData, error) {
-	if c.conn.isDisposed() {
+	return c.AddEContext(context.Background(), label, from, to)
+}
+
+// AddEContext is the context aware version of AddE.
+func (c *Client) AddEContext(ctx context.Context, label string, from, to interface{}) ([]*GremlinRespData, error) {
+	if c.pool.isDisposed() {
 		return nil, ErrorConnectionDisposed
 	}
-	df := getValue(from)
-	fid := df.FieldByName("Id")
-	if !fid.IsValid() {
-		return nil, ErrorInterfaceHasNoIdField
+	q := c.newScript()
+	fid, err := q.idOf(from)
+	if err != nil {
+		return nil, err
 	}
-
-	dt := getValue(to)
-	tid := dt.FieldByName("Id")
-	if !tid.IsValid() {
-		return nil, ErrorInterfaceHasNoIdField
+	l := q.str(label)
+	tid, err := q.idOf(to)
+	if err != nil {
+		return nil, err
 	}
-
-	q := fmt.Sprintf("g.V('%s').addE('%s').to(g.V('%s'))", fid.Interface(), label, tid.Interface())
-	return c.Execute(q, nil, nil)
+	q.add("g.V(%s).addE(%s).to(g.V(%s))", fid, l, tid)
+	return c.ExecuteContext(ctx, q.String(), q.params(), nil)
 }
 
 // AddEById takes a label, from UUID and to UUID then creates a edge between the two vertex in the graph
 func (c *Client) AddEById(label string, from, to uuid.UUID) ([]*GremlinRespData, error) {
-	if c.conn.isDisposed() {
+	return c.AddEByIdContext(context.Background(), label, from, to)
+}
+
+// AddEByIdContext is the context aware version of AddEById.
+func (c *Client) AddEByIdContext(ctx context.Context, label string, from, to uuid.UUID) ([]*GremlinRespData, error) {
+	if c.pool.isDisposed() {
 		return nil, ErrorConnectionDisposed
 	}
-	q := fmt.Sprintf("g.V('%s').addE('%s').to(g.V('%s'))", from.String(), label, to.String())
-	return c.Execute(q, nil, nil)
+	q := c.newScript()
+	q.add("g.V(%s).addE(%s).to(g.V(%s))", q.str(from.String()), q.str(label), q.str(to.String()))
+	return c.ExecuteContext(ctx, q.String(), q.params(), nil)
 }
 
 // AddEWithProps takes a label, from UUID and to UUID then creates a edge between the two vertex in the graph
 func (c *Client) AddEWithProps(label string, from, to interface{}, props map[string]interface{}) ([]*GremlinRespData, error) {
-	if c.conn.isDisposed() {
+	return c.AddEWithPropsContext(context.Background(), label, from, to, props)
+}
+
+// AddEWithPropsContext is the context aware version of AddEWithProps.
+func (c *Client) AddEWithPropsContext(ctx context.Context, label string, from, to interface{}, props map[string]interface{}) ([]*GremlinRespData, error) {
+	if c.pool.isDisposed() {
 		return nil, ErrorConnectionDisposed
 	}
-	df := getValue(from)
-	fid := df.FieldByName("Id")
-	if !fid.IsValid() {
-		return nil, ErrorInterfaceHasNoIdField
-	}
-
-	dt := getValue(to)
-	tid := dt.FieldByName("Id")
-	if !tid.IsValid() {
-		return nil, ErrorInterfaceHasNoIdField
+	q := c.newScript()
+	fid, err := q.idOf(from)
+	if err != nil {
+		return nil, err
 	}
-
-	q := fmt.Sprintf("g.V('%s').addE('%s').to(g.V('%s'))", fid.Interface().(uuid.UUID).String(), label, tid.Interface().(uuid.UUID).String())
-	p, err := buildProps(props)
+	l := q.str(label)
+	tid, err := q.idOf(to)
 	if err != nil {
 		return nil, err
 	}
-	q = q + p
-	return c.Execute(q, nil, nil)
+	q.add("g.V(%s).addE(%s).to(g.V(%s))", fid, l, tid)
+	if err := buildProps(q, props); err != nil {
+		return nil, err
+	}
+	return c.ExecuteContext(ctx, q.String(), q.params(), nil)
 }
 
 // AddEWithPropsById takes a label, from UUID and to UUID then creates a edge between the two vertex in the graph
 func (c *Client) AddEWithPropsById(label string, from, to uuid.UUID, props map[string]interface{}) ([]*GremlinRespData, error) {
-	if c.conn.isDisposed() {
+	return c.AddEWithPropsByIdContext(context.Background(), label, from, to, props)
+}
+
+// AddEWithPropsByIdContext is the context aware version of AddEWithPropsById.
+func (c *Client) AddEWithPropsByIdContext(ctx context.Context, label string, from, to uuid.UUID, props map[string]interface{}) ([]*GremlinRespData, error) {
+	if c.pool.isDisposed() {
 		return nil, ErrorConnectionDisposed
 	}
-	q := fmt.Sprintf("g.V('%s').addE('%s').to(g.V('%s'))", from.String(), label, to.String())
-	p, err := buildProps(props)
+	q := c.newScript()
+	q.add("g.V(%s).addE(%s).to(g.V(%s))", q.str(from.String()), q.str(label), q.str(to.String()))
+	if err := buildProps(q, props); err != nil {
+		return nil, err
+	}
+	return c.ExecuteContext(ctx, q.String(), q.params(), nil)
+}
+
+// AddEdge takes a from and a to interface and an edge interface, then creates the edge between the two vertex in
+// the graph. The label is the string field tagged `graph:"label"` and the other tagged fields are written as
+// properties like in AddV. Edges have a single value per property.
+func (c *Client) AddEdge(from, to, edge interface{}) ([]*GremlinRespData, error) {
+	return c.AddEdgeContext(context.Background(), from, to, edge)
+}
+
+// AddEdgeContext is the context aware version of AddEdge.
+func (c *Client) AddEdgeContext(ctx context.Context, from, to, edge interface{}) ([]*GremlinRespData, error) {
+	c.verbose("passed interface: %s", lazyDump{edge})
+	if c.pool.isDisposed() {
+		return nil, ErrorConnectionDisposed
+	}
+	d := getValue(edge)
+	if d.Kind() != reflect.Struct {
+		return nil, ErrorEdgeHasNoLabel
+	}
+	sc := codecOf(d.Type())
+	label, ok := sc.labelOf(d)
+	if !ok {
+		return nil, ErrorEdgeHasNoLabel
+	}
+
+	q := c.newScript()
+	fid, err := q.idOf(from)
+	if err != nil {
+		return nil, err
+	}
+	l := q.str(label)
+	tid, err := q.idOf(to)
 	if err != nil {
 		return nil, err
 	}
-	q = q + p
-	return c.Execute(q, nil, nil)
+	q.add("g.V(%s).addE(%s).to(g.V(%s))", fid, l, tid)
+	if err := sc.addProps(q, d); err != nil {
+		return nil, err
+	}
+	return c.ExecuteContext(ctx, q.String(), q.params(), nil)
 }
 
 // DropE takes a label, from UUID and to UUID then drops the edge between the two vertex in the graph
 func (c *Client) DropE(label string, from, to interface{}) ([]*GremlinRespData, error) {
-	if c.conn.isDisposed() {
+	return c.DropEContext(context.Background(), label, from, to)
+}
+
+// DropEContext is the context aware version of DropE.
+func (c *Client) DropEContext(ctx context.Context, label string, from, to interface{}) ([]*GremlinRespData, error) {
+	if c.pool.isDisposed() {
 		return nil, ErrorConnectionDisposed
 	}
-	df := getValue(from)
-	fid := df.FieldByName("Id")
-	if !fid.IsValid() {
-		return nil, ErrorInterfaceHasNoIdField
+	q := c.newScript()
+	fid, err := q.idOf(from)
+	if err != nil {
+		return nil, err
 	}
-
-	dt := getValue(to)
-	tid := dt.FieldByName("Id")
-	if !tid.IsValid() {
-		return nil, ErrorInterfaceHasNoIdField
+	l := q.str(label)
+	tid, err := q.idOf(to)
+	if err != nil {
+		return nil, err
 	}
-
-	q := fmt.Sprintf("g.V('%s').outE('%s').and(inV().is('%s')).drop()", fid.Interface(), label, tid.Interface())
-	return c.Execute(q, nil, nil)
+	q.add("g.V(%s).outE(%s).and(inV().is(%s)).drop()", fid, l, tid)
+	return c.ExecuteContext(ctx, q.String(), q.params(), nil)
 }
 
 // DropEById takes a label, from UUID and to UUID then drops the edge between the two vertex in the graph
 func (c *Client) DropEById(label string, from, to uuid.UUID) ([]*GremlinRespData, error) {
-	if c.conn.isDisposed() {
+	return c.DropEByIdContext(context.Background(), label, from, to)
+}
+
+// DropEByIdContext is the context aware version of DropEById.
+func (c *Client) DropEByIdContext(ctx context.Context, label string, from, to uuid.UUID) ([]*GremlinRespData, error) {
+	if c.pool.isDisposed() {
 		return nil, ErrorConnectionDisposed
 	}
-	q := fmt.Sprintf("g.V('%s').outE('%s').and(inV().is('%s')).drop()", from.String(), label, to.String())
-	return c.Execute(q, nil, nil)
+	q := c.newScript()
+	q.add("g.V(%s).outE(%s).and(inV().is(%s)).drop()", q.str(from.String()), q.str(label), q.str(to.String()))
+	return c.ExecuteContext(ctx, q.String(), q.params(), nil)
 }
 
 // getProprtyValue takes a property map slice and return the value
@@ -683,26 +690,33 @@ func getPropertyValue(prop interface{}) (interface{}, error) {
 	return nil, ErrorCannotCastProperty
 }
 
-// buildProps takes a map of string to interfaces to be used as properties on a edge
-func buildProps(props map[string]interface{}) (string, error) {
-	q := ""
+// buildProps takes a map of string to interfaces and adds them to the script as properties on a edge, the keys
+// are sorted so the same keys always give the same script
+func buildProps(q *script, props map[string]interface{}) error {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
-	for k, v := range props {
+	for _, k := range keys {
+		v := props[k]
+		k = escapeString(k)
 		t := reflect.ValueOf(v).Kind()
 		if t == reflect.String {
-			q = fmt.Sprintf("%s.property('%s', '%s')", q, k, v)
+			q.add(".property('%s', %s)", k, q.str(fmt.Sprintf("%s", v)))
 		} else if t == reflect.Bool || t == reflect.Int || t == reflect.Int8 || t == reflect.Int16 || t == reflect.Int32 || t == reflect.Int64 || t == reflect.Uint || t == reflect.Uint8 || t == reflect.Uint16 || t == reflect.Uint32 || t == reflect.Uint64 || t == reflect.Float32 || t == reflect.Float64 {
-			q = fmt.Sprintf("%s.property('%s', %v)", q, k, v)
+			q.add(".property('%s', %s)", k, q.val(v))
 		} else if t == reflect.Slice {
 			s := reflect.ValueOf(v)
 			for i := 0; i < s.Len(); i++ {
-				q = fmt.Sprintf("%s.property('%s', '%s')", q, k, escapeString(fmt.Sprintf("%s", s.Index(i).Interface())))
+				q.add(".property('%s', %s)", k, q.str(fmt.Sprintf("%s", s.Index(i).Interface())))
 			}
 		} else {
-			return "", ErrorUnsupportedPropertyMap
+			return ErrorUnsupportedPropertyMap
 		}
 	}
-	return q, nil
+	return nil
 }
 
 // getValue returns the underlying reflect.Value
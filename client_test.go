@@ -1,6 +1,7 @@
 package gremgoser
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -84,6 +85,11 @@ func TestNewClient(t *testing.T) {
 	assert.IsType(make(chan error), errs)
 	assert.Equal(u, g.conf.URI)
 	assert.Equal(time.Duration(300000000000), g.conf.Timeout)
+
+	// a failed connect returns its error without a reader waiting
+	g, errs = NewClient(NewClientConfig(""))
+	assert.Nil(g)
+	assert.Equal(ErrorInvalidURI, <-errs)
 }
 
 func TestExecute(t *testing.T) {
@@ -116,6 +122,94 @@ func TestExecute(t *testing.T) {
 	assert.Equal([]*GremlinRespData(nil), resp)
 }
 
+func TestExecuteContext(t *testing.T) {
+	assert := assert.New(t)
+
+	// Create test server with the mock handler.
+	s := httptest.NewServer(http.HandlerFunc(mock))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	// test connecting to the mock server
+	g, errs := NewClient(NewClientConfig(u))
+	assert.IsType(make(chan error), errs)
+	assert.NotNil(g)
+	assert.IsType(&Client{}, g)
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	// test query execution with a live context
+	resp, err := g.ExecuteContext(context.Background(), gremV, nil, nil)
+	assert.Nil(err)
+	assert.Nil(resp)
+
+	// test a query the server never answers
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	resp, err = g.ExecuteContext(ctx, gremNoResp, nil, nil)
+	assert.Equal(context.DeadlineExceeded, err)
+	assert.Nil(resp)
+	pending := 0
+	g.responseNotifier.Range(func(k, v interface{}) bool {
+		pending++
+		return true
+	})
+	assert.Equal(0, pending)
+
+	// test an already canceled context
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = g.DropVContext(ctx, TestPtrStruct{Id: uuid.New()})
+	assert.Equal(context.Canceled, err)
+}
+
+func TestExecuteServerError(t *testing.T) {
+	assert := assert.New(t)
+
+	// Create test server with the mock handler.
+	s := httptest.NewServer(http.HandlerFunc(mock))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	// test connecting to the mock server
+	g, errs := NewClient(NewClientConfig(u))
+	assert.NotNil(g)
+
+	resp, err := g.Execute(gremScriptError, nil, nil)
+	assert.Nil(resp)
+	assert.True(errors.Is(err, Error597ScriptEvaluationError))
+	var gerr *GremlinError
+	assert.True(errors.As(err, &gerr))
+	assert.Equal(597, gerr.Code)
+	assert.Equal("ScriptEvaluationError: unexpected token", gerr.Message)
+
+	// the failed request is cleaned up and the client keeps working
+	pending := 0
+	g.responseNotifier.Range(func(k, v interface{}) bool {
+		pending++
+		return true
+	})
+	assert.Equal(0, pending)
+	resp, err = g.Execute(gremV, nil, nil)
+	assert.Nil(err)
+	assert.Nil(resp)
+
+	// server errors go to the caller, not to the err channel
+	select {
+	case err := <-errs:
+		assert.Fail("unexpected error on err channel", "%s", err)
+	default:
+	}
+}
+
 func TestAddV(t *testing.T) {
 	assert := assert.New(t)
 
@@ -675,6 +769,35 @@ func TestAddEById(t *testing.T) {
 	assert.Equal(_resp, resp)
 }
 
+func TestAddEByIdDisableBindings(t *testing.T) {
+	assert := assert.New(t)
+
+	// Create test server with the mock handler.
+	s := httptest.NewServer(http.HandlerFunc(mock))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	// test connecting to the mock server with inlined values
+	conf := NewClientConfig(u)
+	conf.SetDisableBindings()
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	_tUUID, _ := uuid.Parse("64795211-c4a1-4eac-9e0a-b674ced77461")
+	_t2UUID, _ := uuid.Parse("dafeafc6-63a7-42b2-8ac2-4b85c3e2e37a")
+	resp, err := g.AddEById("relates", _tUUID, _t2UUID)
+	assert.Nil(err)
+	assert.Equal(1, len(resp))
+}
+
 func TestDropEById(t *testing.T) {
 	assert := assert.New(t)
 
@@ -872,7 +995,7 @@ func TestDisposed(t *testing.T) {
 	}(errs)
 
 	// dispose connection
-	err := g.conn.close()
+	err := g.pool.close()
 	assert.Nil(err)
 	q := "g.V()"
 	_, err = g.Execute(q, nil, nil)
@@ -983,6 +1106,53 @@ func TestDisposed(t *testing.T) {
 	assert.Equal(_err, err)
 }
 
+func TestGetAs(t *testing.T) {
+	assert := assert.New(t)
+
+	// Create test server with the mock handler.
+	s := httptest.NewServer(http.HandlerFunc(mock))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	g, errs := NewClient(NewClientConfig(u))
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	ctx := context.Background()
+	_tUUID, _ := uuid.Parse("64795211-c4a1-4eac-9e0a-b674ced77461")
+	q := fmt.Sprintf("g.V('%s')", _tUUID)
+
+	tests, err := GetAs[Test](ctx, g, q, nil)
+	assert.Nil(err)
+	assert.Equal(1, len(tests))
+	assert.Equal(_tUUID, tests[0].Id)
+	assert.Equal("aa", tests[0].A)
+
+	test, err := GetOne[Test](ctx, g, q, nil)
+	assert.Nil(err)
+	assert.Equal(tests[0], test)
+
+	// vertices decode into structs only
+	_, err = GetAs[*Test](ctx, g, q, nil)
+	assert.Equal(errors.New("the passed interface is not a slice of structs"), err)
+
+	// an empty result is not found for GetOne only
+	tests, err = GetAs[Test](ctx, g, gremV, nil)
+	assert.Nil(err)
+	assert.Equal(0, len(tests))
+	test, err = GetOne[Test](ctx, g, gremV, nil)
+	assert.Equal(ErrorNotFound, err)
+	assert.Equal(Test{}, test)
+}
+
 func TestGetPropVal(t *testing.T) {
 	assert := assert.New(t)
 
@@ -1000,14 +1170,36 @@ func TestBuildProps(t *testing.T) {
 	assert := assert.New(t)
 
 	var props map[string]interface{}
-	maps := []byte(`{"foo":"bar","biz":3}`)
+	maps := []byte(`{"foo":"bar","biz":3,"baz":["a","b"]}`)
 	err := json.Unmarshal(maps, &props)
 	assert.Nil(err)
-	p, err := buildProps(props)
+
+	// values are bound
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	q := c.newScript()
+	err = buildProps(q, props)
+	assert.Nil(err)
+	assert.Equal(".property('baz', _p0).property('baz', _p1).property('biz', _p2).property('foo', _p3)", q.String())
+	assert.Equal(map[string]interface{}{"_p0": "a", "_p1": "b", "_p2": float64(3), "_p3": "bar"}, q.params())
+
+	// values are inlined
+	c.conf.SetDisableBindings()
+	q = c.newScript()
+	err = buildProps(q, props)
 	assert.Nil(err)
-	_p := ".property('foo', 'bar').property('biz', 3)"
-	_p2 := ".property('biz', 3).property('foo', 'bar')"
-	assert.True(_p == p || _p2 == p)
+	assert.Equal(".property('baz', 'a').property('baz', 'b').property('biz', 3).property('foo', 'bar')", q.String())
+	assert.Nil(q.params())
+
+	// plain strings are escaped as well
+	q = c.newScript()
+	err = buildProps(q, map[string]interface{}{"foo": "b'ar"})
+	assert.Nil(err)
+	assert.Equal(`.property('foo', 'b\'ar')`, q.String())
+
+	q = c.newScript()
+	err = buildProps(q, map[string]interface{}{"foo": struct{}{}})
+	assert.Equal(ErrorUnsupportedPropertyMap, err)
 }
 
 func TestClientClose(t *testing.T) {
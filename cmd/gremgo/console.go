@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/intwinelabs/gremgoser"
+)
+
+const (
+	prompt     = "gremlin> "
+	morePrompt = "......> "
+)
+
+// console runs queries and prints their results
+type console struct {
+	client  *gremgoser.Client
+	out     io.Writer
+	errOut  io.Writer
+	format  string
+	history *history
+}
+
+// exec runs a query and prints its results, the summary with the request charge goes to the error output so the
+// results can be piped. An interrupt cancels the query instead of ending the console.
+func (c *console) exec(ctx context.Context, query string) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	start := time.Now()
+	stream, err := c.client.ExecuteStream(ctx, query, nil, nil)
+	if err != nil {
+		fmt.Fprintf(c.errOut, "error: %s\n", err)
+		return err
+	}
+	defer stream.Close()
+	var values []interface{}
+	for stream.Next() {
+		values = append(values, stream.Values()...)
+	}
+	result := stream.Result()
+	if err := stream.Err(); err != nil {
+		fmt.Fprintf(c.errOut, "error: %s%s\n", err, chargeOf(result))
+		return err
+	}
+	if err := printValues(c.out, c.format, values); err != nil {
+		fmt.Fprintf(c.errOut, "error: %s\n", err)
+		return err
+	}
+	fmt.Fprintf(c.errOut, "==> %d results in %s%s\n", len(values), time.Since(start).Round(time.Millisecond), chargeOf(result))
+	return nil
+}
+
+// chargeOf returns the request charge Cosmos DB reported for every frame of the request, servers that report no
+// charge get none
+func chargeOf(result gremgoser.Result) string {
+	if result.RequestCharge == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", request charge %.2f RU", result.RequestCharge)
+}
+
+// statements splits the lines of a script into statements, a statement goes on while it is incomplete. Blank
+// lines and comments between statements are skipped.
+type statements struct {
+	lines []string
+}
+
+// add adds a line and returns the statement it completes
+func (s *statements) add(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if len(s.lines) == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "//")) {
+		return "", false
+	}
+	s.lines = append(s.lines, strings.TrimSuffix(strings.TrimRight(line, " \t"), `\`))
+	if strings.HasSuffix(trimmed, `\`) || !complete(strings.Join(s.lines, "\n")) {
+		return "", false
+	}
+	statement := strings.TrimSpace(strings.Join(s.lines, "\n"))
+	s.lines = nil
+	return statement, true
+}
+
+// pending reports whether a statement was started
+func (s *statements) pending() bool {
+	return len(s.lines) > 0
+}
+
+// complete reports whether a query is complete, its brackets are balanced, its strings closed and it does not end
+// with a dot or a comma
+func complete(query string) bool {
+	depth := 0
+	var quote rune
+	escaped := false
+	for _, r := range query {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(' || r == '[' || r == '{':
+			depth++
+		case r == ')' || r == ']' || r == '}':
+			depth--
+		}
+	}
+	trimmed := strings.TrimSpace(query)
+	return depth <= 0 && quote == 0 && !strings.HasSuffix(trimmed, ".") && !strings.HasSuffix(trimmed, ",")
+}
+
+// runFile runs the statements of a script file, stopping at the first that fails
+func (c *console) runFile(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(c.errOut, "error: %s\n", err)
+		return err
+	}
+	defer f.Close()
+	return c.runScript(ctx, f)
+}
+
+func (c *console) runScript(ctx context.Context, r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var st statements
+	for s.Scan() {
+		if query, ok := st.add(s.Text()); ok {
+			if err := c.exec(ctx, query); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		fmt.Fprintf(c.errOut, "error: %s\n", err)
+		return err
+	}
+	if st.pending() {
+		err := errors.New("the script ends within a statement")
+		fmt.Fprintf(c.errOut, "error: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// repl runs the interactive session until the input ends or :quit is entered
+func (c *console) repl(ctx context.Context, in io.Reader) {
+	fmt.Fprintln(c.out, "gremgo console, :help for the commands")
+	s := bufio.NewScanner(in)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var st statements
+	for {
+		if st.pending() {
+			fmt.Fprint(c.out, morePrompt)
+		} else {
+			fmt.Fprint(c.out, prompt)
+		}
+		if !s.Scan() {
+			fmt.Fprintln(c.out)
+			return
+		}
+		line := s.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ":") {
+			if trimmed == ":clear" || trimmed == ":c" {
+				st = statements{}
+				continue
+			}
+			if !st.pending() {
+				if c.command(ctx, trimmed) {
+					return
+				}
+				continue
+			}
+		}
+		if !st.pending() && strings.HasPrefix(trimmed, "!") {
+			query, err := c.history.recall(trimmed)
+			if err != nil {
+				fmt.Fprintf(c.errOut, "error: %s\n", err)
+				continue
+			}
+			fmt.Fprintln(c.out, query)
+			line = query
+		}
+		query, ok := st.add(line)
+		if !ok {
+			continue
+		}
+		c.history.add(query)
+		c.exec(ctx, query)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// command runs a console command and reports whether the session ends
+func (c *console) command(ctx context.Context, line string) bool {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ":quit", ":exit", ":q":
+		return true
+	case ":help", ":h":
+		fmt.Fprint(c.out, `:format table|json|graphson   sets the output format
+:history                      lists the history, !n runs entry n again and !! the last one
+:load <file>                  runs the queries of a script file
+:clear                        discards the statement being entered
+:quit                         ends the session
+Statements go on over several lines while their brackets or strings are open, or a line ends with a dot, a
+comma or a backslash.
+`)
+	case ":format", ":f":
+		if len(fields) != 2 || !validFormat(fields[1]) {
+			fmt.Fprintf(c.errOut, "error: the format is table, json or graphson, it is %s\n", c.format)
+			break
+		}
+		c.format = fields[1]
+	case ":history":
+		for i, query := range c.history.entries {
+			fmt.Fprintf(c.out, "%4d  %s\n", i+1, strings.ReplaceAll(query, "\n", "\n      "))
+		}
+	case ":load", ":l":
+		if len(fields) != 2 {
+			fmt.Fprintln(c.errOut, "error: :load needs a file")
+			break
+		}
+		c.runFile(ctx, fields[1])
+	default:
+		fmt.Fprintf(c.errOut, "error: unknown command %s, :help lists the commands\n", fields[0])
+	}
+	return false
+}
+
+// history keeps the queries of interactive sessions, a query per line of its file in Go quoted form so queries
+// over several lines keep their line breaks
+type history struct {
+	path    string
+	entries []string
+}
+
+const maxHistory = 1000
+
+// loadHistory reads the history file, a missing or unreadable file starts an empty history
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for s.Scan() {
+		if query, err := strconv.Unquote(s.Text()); err == nil {
+			h.entries = append(h.entries, query)
+		}
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	return h
+}
+
+// add adds a query to the history and appends it to the file, repeating the last query adds nothing
+func (h *history) add(query string) {
+	if h == nil || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == query) {
+		return
+	}
+	h.entries = append(h.entries, query)
+	if h.path == "" {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, strconv.Quote(query))
+}
+
+// recall returns the query of a !n or !! reference
+func (h *history) recall(ref string) (string, error) {
+	if h == nil || len(h.entries) == 0 {
+		return "", errors.New("the history is empty")
+	}
+	if ref == "!!" {
+		return h.entries[len(h.entries)-1], nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(ref, "!"))
+	if err != nil || n < 1 || n > len(h.entries) {
+		return "", fmt.Errorf("there is no history entry %s", strings.TrimPrefix(ref, "!"))
+	}
+	return h.entries[n-1], nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplete(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(complete(`g.V().count()`))
+	assert.True(complete(`g.V().has('name', 'a(b')`))
+	assert.True(complete(`g.V().has('name', 'it\'s')`))
+	assert.False(complete(`g.V().has('name',`))
+	assert.False(complete(`g.V().has('name', 'a`))
+	assert.False(complete(`g.V().`))
+	assert.False(complete(`g.V().project('a').by(__.out()`))
+	assert.True(complete("g.V().\n  out()"))
+}
+
+func TestStatements(t *testing.T) {
+	assert := assert.New(t)
+
+	var st statements
+	var got []string
+	for _, line := range []string{"", "// people", "g.V().", "  hasLabel('person')", "g.E() \\", ".count()", "g.V().count()"} {
+		if query, ok := st.add(line); ok {
+			got = append(got, query)
+		}
+	}
+	assert.Equal([]string{"g.V().\n  hasLabel('person')", "g.E() \n.count()", "g.V().count()"}, got)
+	assert.False(st.pending())
+
+	_, ok := st.add("g.V(")
+	assert.False(ok)
+	assert.True(st.pending())
+}
+
+func TestHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "history")
+	h := loadHistory(path)
+	assert.Equal(0, len(h.entries))
+	_, err := h.recall("!!")
+	assert.NotNil(err)
+
+	h.add("g.V().count()")
+	h.add("g.V().count()")
+	h.add("g.V().\n  out()")
+	assert.Equal([]string{"g.V().count()", "g.V().\n  out()"}, h.entries)
+
+	// queries over several lines are kept
+	h = loadHistory(path)
+	assert.Equal([]string{"g.V().count()", "g.V().\n  out()"}, h.entries)
+	query, err := h.recall("!!")
+	assert.Nil(err)
+	assert.Equal("g.V().\n  out()", query)
+	query, err = h.recall("!1")
+	assert.Nil(err)
+	assert.Equal("g.V().count()", query)
+	_, err = h.recall("!3")
+	assert.NotNil(err)
+
+	// unreadable lines are skipped
+	assert.Nil(os.WriteFile(path, []byte("\"g.E()\"\nbroken\n"), 0600))
+	assert.Equal([]string{"g.E()"}, loadHistory(path).entries)
+}
@@ -0,0 +1,106 @@
+// Command gremgo is a Gremlin console for Gremlin Server and Cosmos DB. It runs a single query, a script file or
+// an interactive session with history and multiline input, and prints the results as a table, JSON or GraphSON
+// along with the request charge Cosmos DB reports. The password or key is read from $GREMGO_PASSWORD so it stays
+// out of the process list and the shell history.
+//
+//	GREMGO_PASSWORD=$KEY gremgo -uri wss://account.gremlin.cosmos.azure.com:443/ -user /dbs/db/colls/graph
+//	gremgo -uri ws://localhost:8182/gremlin -e "g.V().count()"
+//	gremgo -uri ws://localhost:8182/gremlin -f fixtures.groovy -o json
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/intwinelabs/gremgoser"
+)
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the console with the command line arguments and returns the exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gremgo", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	uri := fs.String("uri", os.Getenv("GREMGO_URI"), "websocket uri of the server, $GREMGO_URI by default")
+	user := fs.String("user", os.Getenv("GREMGO_USER"), "user name, for Cosmos DB /dbs/<database>/colls/<graph>")
+	password := fs.String("password", os.Getenv("GREMGO_PASSWORD"), "password or Cosmos DB key, discouraged as it shows in the process list and the shell history, set $GREMGO_PASSWORD instead")
+	query := fs.String("e", "", "run a single query and exit")
+	file := fs.String("f", "", "run the queries of a script file and exit")
+	format := fs.String("o", "table", "output format: table, json or graphson")
+	serializer := fs.String("serializer", "graphson2", "serializer: graphson2, graphson3 or graphbinary")
+	timeout := fs.Duration("timeout", 2*time.Minute, "time to wait for the response of a query")
+	history := fs.String("history", defaultHistory(), "file the history of the interactive session is kept in")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !validFormat(*format) {
+		fmt.Fprintf(stderr, "unknown output format %q\n", *format)
+		return 2
+	}
+	if *uri == "" {
+		fmt.Fprintln(stderr, "the -uri of the server is missing")
+		return 2
+	}
+
+	conf := gremgoser.NewClientConfig(*uri)
+	if *user != "" {
+		conf.SetAuthentication(*user, *password)
+	}
+	switch *serializer {
+	case "graphson2":
+		conf.SetSerializer(gremgoser.SerializerGraphSONv2)
+	case "graphson3":
+		conf.SetSerializer(gremgoser.SerializerGraphSONv3)
+	case "graphbinary":
+		conf.SetSerializer(gremgoser.SerializerGraphBinary)
+	default:
+		fmt.Fprintf(stderr, "unknown serializer %q\n", *serializer)
+		return 2
+	}
+	conf.ReadingWait = *timeout
+
+	g, errs := gremgoser.NewClient(conf)
+	if g == nil {
+		fmt.Fprintf(stderr, "cannot connect to %s: %s\n", *uri, <-errs)
+		return 1
+	}
+	defer g.Close()
+	go func() {
+		for err := range errs {
+			fmt.Fprintf(stderr, "connection error: %s\n", err)
+		}
+	}()
+
+	c := &console{client: g, out: stdout, errOut: stderr, format: *format}
+	var err error
+	switch {
+	case *query != "":
+		err = c.exec(ctx, *query)
+	case *file != "":
+		err = c.runFile(ctx, *file)
+	default:
+		c.history = loadHistory(*history)
+		c.repl(ctx, stdin)
+		return 0
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// defaultHistory returns the path of the history file in the home directory
+func defaultHistory() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gremgo_history")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/intwinelabs/gremgoser"
+	"github.com/stretchr/testify/assert"
+)
+
+// cosmosMock answers queries like Cosmos DB, with the request charge in the status attributes. Some answers come in
+// several frames that only hold the charge of the frame.
+func cosmosMock(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	c, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	for {
+		_, message, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		msg := bytes.SplitAfter(message, []byte("!application/vnd.gremlin-v2.0+json"))
+		var req gremgoser.GremlinRequest
+		if err := json.Unmarshal(msg[1], &req); err != nil {
+			return
+		}
+		status, data := `{"code":200,"attributes":{"x-ms-request-charge":2.5,"x-ms-total-request-charge":2.5},"message":""}`, `[]`
+		var frames []string // status and data of the frames before the last one
+		switch strings.TrimSpace(req.Args["gremlin"].(string)) {
+		case "g.V().count()":
+			data = `[2]`
+		case "g.V()":
+			data = `[{"id":"1","label":"person","type":"vertex","properties":{"name":[{"id":"a","value":"ann"}]}}]`
+		case "g.E()":
+			frames = []string{`{"code":206,"attributes":{"x-ms-request-charge":1},"message":""}`, `[{"id":"e","label":"knows","type":"edge","inV":"1","outV":"1"}]`}
+			status = `{"code":200,"attributes":{"x-ms-request-charge":0.5},"message":""}`
+		case "g.V().\n  fail()":
+			frames = []string{`{"code":206,"attributes":{"x-ms-request-charge":1},"message":""}`, `[]`}
+			status = `{"code":597,"attributes":{"x-ms-request-charge":1.25},"message":"no such step"}`
+		}
+		frames = append(frames, status, data)
+		for i := 0; i < len(frames); i += 2 {
+			resp := `{"requestId":"` + req.RequestId.String() + `","status":` + frames[i] + `,"result":{"data":` + frames[i+1] + `,"meta":{}}}`
+			if err := c.WriteMessage(websocket.TextMessage, []byte(resp)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+
+	s := httptest.NewServer(http.HandlerFunc(cosmosMock))
+	defer s.Close()
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+	dir := t.TempDir()
+	t.Setenv("GREMGO_PASSWORD", "key")
+
+	gremgo := func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), append([]string{"-uri", u, "-user", "/dbs/db/colls/graph", "-history", filepath.Join(dir, "history")}, args...), strings.NewReader(stdin), &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	// a single query prints its results and the request charge
+	code, stdout, stderr := gremgo("", "-e", "g.V().count()", "-o", "json")
+	assert.Equal(0, code)
+	assert.Equal("[\n  2\n]\n", stdout)
+	assert.Contains(stderr, "==> 1 results in ")
+	assert.Contains(stderr, ", request charge 2.50 RU")
+
+	// the charge is summed over every frame when the total is not sent
+	code, _, stderr = gremgo("", "-e", "g.E()")
+	assert.Equal(0, code)
+	assert.Contains(stderr, "==> 1 results in ")
+	assert.Contains(stderr, ", request charge 1.50 RU")
+
+	// a script runs its statements until one fails
+	script := filepath.Join(dir, "script.groovy")
+	assert.Nil(os.WriteFile(script, []byte("// people\ng.V()\n\ng.V().\n  fail()\ng.V().count()\n"), 0600))
+	code, stdout, stderr = gremgo("", "-f", script)
+	assert.Equal(1, code)
+	assert.Equal("id  label   name\n--  -----   ----\n1   person  ann\n", stdout)
+	assert.Contains(stderr, "no such step, request charge 2.25 RU")
+	assert.NotContains(stderr, "==> 2 results")
+
+	// the interactive session continues incomplete statements and keeps the history
+	code, stdout, stderr = gremgo("g.V().\n  fail()\n:format graphson\ng.V()\n:history\n!1\n:quit\n")
+	assert.Equal(0, code)
+	assert.Contains(stdout, "gremlin> ......> gremlin> gremlin> ")
+	assert.Contains(stdout, `{"@type":"g:Vertex","@value":{"id":"1","label":"person","properties":{"name":[{"@type":"g:VertexProperty","@value":{"id":"a","label":"name","value":"ann"}}]}}}`)
+	assert.Contains(stdout, "   1  g.V().\n        fail()\n   2  g.V()\n")
+	assert.Equal(2, strings.Count(stderr, "no such step"))
+	assert.Equal([]string{"g.V().\n  fail()", "g.V()", "g.V().\n  fail()"}, loadHistory(filepath.Join(dir, "history")).entries)
+
+	// bad arguments fail before connecting
+	code, _, stderr = gremgo("", "-o", "xml")
+	assert.Equal(2, code)
+	assert.Contains(stderr, `unknown output format "xml"`)
+	var out bytes.Buffer
+	assert.Equal(2, run(context.Background(), []string{"-uri", ""}, nil, &out, &out))
+	assert.Contains(out.String(), "-uri of the server is missing")
+}
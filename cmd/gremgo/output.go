@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/intwinelabs/gremgoser"
+)
+
+// validFormat reports whether results can be printed in a format
+func validFormat(format string) bool {
+	switch format {
+	case "table", "json", "graphson":
+		return true
+	}
+	return false
+}
+
+// printValues prints the values of a response in a format
+func printValues(w io.Writer, format string, values []interface{}) error {
+	switch format {
+	case "json":
+		plain := make([]interface{}, len(values))
+		for i, v := range values {
+			plain[i] = plainValue(v)
+		}
+		b, err := json.MarshalIndent(plain, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case "graphson":
+		for _, v := range values {
+			b, err := gremgoser.MarshalGraphSON(v)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return printTable(w, values)
+}
+
+// plainValue turns the values of a response into values that marshal to plain JSON, vertices and edges become
+// objects with their properties by key
+func plainValue(v interface{}) interface{} {
+	switch val := gremgoser.DecodeElement(v).(type) {
+	case *gremgoser.Vertex:
+		props := make(map[string]interface{}, len(val.Properties))
+		for key, list := range val.Properties {
+			values := make([]interface{}, len(list))
+			for i, p := range list {
+				values[i] = plainValue(p.Value)
+			}
+			if len(values) == 1 {
+				props[key] = values[0]
+			} else {
+				props[key] = values
+			}
+		}
+		return map[string]interface{}{"id": plainValue(val.Id), "label": val.Label, "type": "vertex", "properties": props}
+	case *gremgoser.Edge:
+		props := make(map[string]interface{}, len(val.Properties))
+		for key, p := range val.Properties {
+			props[key] = plainValue(p.Value)
+		}
+		return map[string]interface{}{"id": plainValue(val.Id), "label": val.Label, "type": "edge",
+			"outV": plainValue(val.OutV), "inV": plainValue(val.InV), "properties": props}
+	case *gremgoser.VertexProperty:
+		return plainValue(val.Value)
+	case *gremgoser.Property:
+		return plainValue(val.Value)
+	case *gremgoser.Path:
+		objects := make([]interface{}, len(val.Objects))
+		for i, o := range val.Objects {
+			objects[i] = plainValue(o)
+		}
+		return objects
+	case *gremgoser.Traverser:
+		return plainValue(val.Value)
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = plainValue(item)
+		}
+		return list
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			m[key] = plainValue(item)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			m[cell(plainValue(key))] = plainValue(item)
+		}
+		return m
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case uuid.UUID:
+		return val.String()
+	default:
+		return val
+	}
+}
+
+// elementColumns are the columns of vertices and edges that come before their properties
+var elementColumns = []string{"id", "label", "outV", "inV"}
+
+// printTable prints the values as a table. Vertices and edges get a column for each property, maps one for each
+// key and other values a single value column.
+func printTable(w io.Writer, values []interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+	rows := make([]map[string]interface{}, len(values))
+	seen := map[string]bool{}
+	var other []string
+	for i, v := range values {
+		row, ok := plainValue(v).(map[string]interface{})
+		if !ok {
+			row = map[string]interface{}{"value": plainValue(v)}
+		}
+		if props, ok := row["properties"].(map[string]interface{}); ok && (row["type"] == "vertex" || row["type"] == "edge") {
+			flat := map[string]interface{}{"id": row["id"], "label": row["label"]}
+			if row["type"] == "edge" {
+				flat["outV"], flat["inV"] = row["outV"], row["inV"]
+			}
+			for key, p := range props {
+				if _, ok := flat[key]; !ok {
+					flat[key] = p
+				}
+			}
+			row = flat
+		}
+		rows[i] = row
+		for key := range row {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if !isElementColumn(key) {
+				other = append(other, key)
+			}
+		}
+	}
+	columns := make([]string, 0, len(elementColumns)+len(other))
+	for _, key := range elementColumns {
+		if seen[key] {
+			columns = append(columns, key)
+		}
+	}
+	sort.Strings(other)
+	columns = append(columns, other...)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	rules := make([]string, len(columns))
+	for i, column := range columns {
+		rules[i] = strings.Repeat("-", len(column))
+	}
+	fmt.Fprintln(tw, strings.Join(rules, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			if v, ok := row[column]; ok {
+				cells[i] = cell(v)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func isElementColumn(key string) bool {
+	for _, column := range elementColumns {
+		if key == column {
+			return true
+		}
+	}
+	return false
+}
+
+// cell returns the text of a value in a table cell, lists are joined by commas and maps are written as JSON
+func cell(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.NewReplacer("\n", `\n`, "\t", `\t`).Replace(val)
+	case []interface{}:
+		items := make([]string, len(val))
+		for i, item := range val {
+			items[i] = cell(item)
+		}
+		return strings.Join(items, ", ")
+	case map[string]interface{}:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(b)
+	default:
+		return fmt.Sprint(val)
+	}
+}
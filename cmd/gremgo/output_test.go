@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// cosmosValues returns the values of a GraphSON v2 response of Cosmos DB, vertices and edges are untyped maps
+func cosmosValues(t *testing.T) []interface{} {
+	var values []interface{}
+	decoder := json.NewDecoder(strings.NewReader(`[
+		{"id":"1","label":"person","type":"vertex","properties":{"name":[{"id":"a","value":"ann"}],"tags":[{"id":"b","value":"x"},{"id":"c","value":"y"}]}},
+		{"id":"e","label":"knows","type":"edge","inVLabel":"person","outVLabel":"person","inV":"2","outV":"1","properties":{"weight":0.5}}
+	]`))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestPrintTable(t *testing.T) {
+	assert := assert.New(t)
+
+	var b bytes.Buffer
+	assert.Nil(printValues(&b, "table", cosmosValues(t)))
+	assert.Equal(`id  label   outV  inV  name  tags  weight
+--  -----   ----  ---  ----  ----  ------
+1   person             ann   x, y  
+e   knows   1     2                0.5
+`, b.String())
+
+	// scalars get a value column and maps one for each key
+	b.Reset()
+	assert.Nil(printValues(&b, "table", []interface{}{int64(2), "it\tis"}))
+	assert.Equal("value\n-----\n2\nit\\tis\n", b.String())
+	b.Reset()
+	assert.Nil(printValues(&b, "table", []interface{}{map[string]interface{}{"b": 1, "a": []interface{}{"x"}}}))
+	assert.Equal("a  b\n-  -\nx  1\n", b.String())
+
+	// nothing is printed for no results
+	b.Reset()
+	assert.Nil(printValues(&b, "table", nil))
+	assert.Equal("", b.String())
+}
+
+func TestPrintJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var b bytes.Buffer
+	assert.Nil(printValues(&b, "json", cosmosValues(t)))
+	var got []map[string]interface{}
+	assert.Nil(json.Unmarshal(b.Bytes(), &got))
+	assert.Equal([]map[string]interface{}{
+		{"id": "1", "label": "person", "type": "vertex", "properties": map[string]interface{}{"name": "ann", "tags": []interface{}{"x", "y"}}},
+		{"id": "e", "label": "knows", "type": "edge", "outV": "1", "inV": "2", "properties": map[string]interface{}{"weight": 0.5}},
+	}, got)
+}
+
+func TestPrintGraphSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var b bytes.Buffer
+	assert.Nil(printValues(&b, "graphson", cosmosValues(t)[1:]))
+	assert.Equal(`{"@type":"g:Edge","@value":{"id":"e","inV":"2","inVLabel":"person","label":"knows","outV":"1","outVLabel":"person","properties":{"weight":{"@type":"g:Property","@value":{"key":"weight","value":{"@type":"g:Double","@value":0.5}}}}}}`+"\n", b.String())
+}
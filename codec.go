@@ -0,0 +1,667 @@
+package gremgoser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// fieldMode is how a field is written to the scripts of AddV and UpdateV, it is derived once from the tag options
+type fieldMode int
+
+const (
+	modeUntyped      fieldMode = iota // the tag has no option type, writing the field fails
+	modeUnknown                       // the option type is unknown and the field is not written
+	modeID                            // `graph:"id,id"`
+	modePartitionKey                  // `graph:"name,partitionKey"`
+	modeTime                          // `graph:"name,time"` on time.Time and time.Duration fields
+	modeString                        // `graph:"name,string"`
+	modeValue                         // `graph:"name,bool"` and `graph:"name,number"`
+	modeJSON                          // `graph:"name,struct"` and `graph:"name,[]struct"`
+	modeStrings                       // `graph:"name,[]string"`
+	modeValues                        // `graph:"name,[]bool"` and `graph:"name,[]number"`
+	modeLabel                         // `graph:"label"`, the label of the element
+	modeInV                           // `graph:"inV"`, the id of the incoming vertex of an edge
+	modeOutV                          // `graph:"outV"`, the id of the outgoing vertex of an edge
+)
+
+// fieldCodec holds what the struct mapper needs to know about a tagged field
+type fieldCodec struct {
+	index     int
+	name      string
+	mode      fieldMode
+	kind      reflect.Kind // kind of the field, or of the element of pointer and slice fields
+	isPtr     bool
+	isSlice   bool
+	jsonSlice bool         // a slice stored as a single JSON string, `graph:"name,struct"` on a slice
+	marshal   bool         // the field, or its elements, implement GraphMarshaler
+	unmarshal bool         // the field, or its elements, implement GraphUnmarshaler
+	elem      reflect.Type // type of the field, or of the element of pointer and slice fields
+	format    TimeFormat   // time format of the tag options, 0 when the field uses the one of the client
+}
+
+// structCodec holds the tagged fields of a struct type, it is built once per type and shared by Get, AddV,
+// UpdateV and AddEdge
+type structCodec struct {
+	id     int // index of the id field, -1 when the struct has none
+	label  int // index of the label field, -1 when the struct has none
+	fields []fieldCodec
+}
+
+// codecs caches the codec of every struct type the mapper has seen
+var codecs sync.Map // map[reflect.Type]*structCodec
+
+// codecOf returns the cached codec of a struct type, building it on first use
+func codecOf(t reflect.Type) *structCodec {
+	if sc, ok := codecs.Load(t); ok {
+		return sc.(*structCodec)
+	}
+	sc, _ := codecs.LoadOrStore(t, newStructCodec(t))
+	return sc.(*structCodec)
+}
+
+// newStructCodec reflects the tagged fields of a struct type
+func newStructCodec(t reflect.Type) *structCodec {
+	sc := &structCodec{id: -1, label: -1}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, opts := parseTag(sf.Tag.Get("graph"))
+		if opts.Contains("id") && sc.id < 0 {
+			sc.id = i
+		}
+		if len(name) == 0 && len(opts) == 0 {
+			continue
+		}
+		f := fieldCodec{index: i, name: name, mode: fieldModeOf(name, opts), kind: sf.Type.Kind()}
+		if f.mode == modeLabel && sc.label < 0 {
+			sc.label = i
+		}
+		if f.kind == reflect.Ptr {
+			f.isPtr = true
+			f.kind = sf.Type.Elem().Kind()
+		} else if f.kind == reflect.Slice {
+			f.isSlice = true
+			f.kind = sf.Type.Elem().Kind()
+		}
+		f.jsonSlice = f.isSlice && opts.Contains("struct")
+		f.elem = sf.Type
+		if f.isPtr || f.isSlice {
+			f.elem = f.elem.Elem()
+		}
+		f.marshal = f.elem.Implements(graphMarshalerType) || reflect.PtrTo(f.elem).Implements(graphMarshalerType)
+		f.unmarshal = reflect.PtrTo(f.elem).Implements(graphUnmarshalerType)
+		if opts.Contains("rfc3339") {
+			f.format = TimeFormatRFC3339
+		} else if opts.Contains("millis") {
+			f.format = TimeFormatMillis
+		}
+		sc.fields = append(sc.fields, f)
+	}
+	if sc.id < 0 {
+		if sf, ok := t.FieldByName("Id"); ok && len(sf.Index) == 1 {
+			sc.id = sf.Index[0]
+		}
+	}
+	return sc
+}
+
+// fieldModeOf maps tag options to the way the field is written, tags without options name the label and the
+// vertices of an edge
+func fieldModeOf(name string, opts tagOptions) fieldMode {
+	switch {
+	case len(opts) == 0 && name == "label":
+		return modeLabel
+	case len(opts) == 0 && name == "inV":
+		return modeInV
+	case len(opts) == 0 && name == "outV":
+		return modeOutV
+	case len(opts) == 0:
+		return modeUntyped
+	case opts.Contains("id"):
+		return modeID
+	case opts.Contains("partitionKey"):
+		return modePartitionKey
+	case opts.Contains("time"):
+		return modeTime
+	case opts.Contains("string"):
+		return modeString
+	case opts.Contains("bool") || opts.Contains("number"):
+		return modeValue
+	case opts.Contains("struct") || opts.Contains("[]struct"):
+		return modeJSON
+	case opts.Contains("[]string"):
+		return modeStrings
+	case opts.Contains("[]bool") || opts.Contains("[]number"):
+		return modeValues
+	}
+	return modeUnknown
+}
+
+// value returns the value of the field in the struct d, pointers are followed and nil pointers are not written
+func (fc *fieldCodec) value(d reflect.Value) (interface{}, bool) {
+	f := d.Field(fc.index)
+	if fc.isPtr {
+		if f.IsNil() {
+			return nil, false
+		}
+		f = f.Elem()
+	}
+	return f.Interface(), true
+}
+
+// converter returns how the values of a field are turned into property values when they are not written as they
+// are, nil otherwise
+func (fc *fieldCodec) converter(q *script) func(reflect.Value) (interface{}, error) {
+	if fc.mode == modeID {
+		return nil
+	}
+	if fc.marshal {
+		return marshalGraph
+	}
+	if fc.mode == modeTime {
+		format := fc.format
+		if format == 0 && fc.elem != durationType { // durations only take the unit of their own tag
+			format = q.timeFormat
+		}
+		return func(v reflect.Value) (interface{}, error) {
+			return timeValue(v, format)
+		}
+	}
+	return nil
+}
+
+// propValues returns the property values of a field through a conversion, one per element of a slice field. Nil
+// pointers and nil values are left out.
+func (fc *fieldCodec) propValues(d reflect.Value, convert func(reflect.Value) (interface{}, error)) ([]interface{}, error) {
+	f := d.Field(fc.index)
+	if fc.isPtr {
+		if f.IsNil() {
+			return nil, nil
+		}
+		f = f.Elem()
+	}
+	n := 1
+	if fc.isSlice {
+		n = f.Len()
+	}
+	values := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v := f
+		if fc.isSlice {
+			v = f.Index(i)
+		}
+		value, err := convert(v)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// decodeEach sets a field from the slice of properties the element has for it through a conversion that sets an
+// addressable value, pointer and slice fields are allocated
+func (fc *fieldCodec) decodeEach(f, propSlice reflect.Value, set func(reflect.Value, interface{}) error) error {
+	n := propSlice.Len()
+	if fc.isSlice {
+		s := reflect.MakeSlice(f.Type(), n, n)
+		for i := 0; i < n; i++ {
+			v, err := getPropertyValue(propSlice.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+			if err := set(s.Index(i), v); err != nil {
+				return err
+			}
+		}
+		f.Set(s)
+		return nil
+	}
+	if n == 0 {
+		return nil
+	}
+	v, err := getPropertyValue(propSlice.Index(0).Interface())
+	if err != nil {
+		return err
+	}
+	if fc.isPtr {
+		p := reflect.New(f.Type().Elem())
+		if err := set(p.Elem(), v); err != nil {
+			return err
+		}
+		f.Set(p)
+		return nil
+	}
+	return set(f, v)
+}
+
+// labelOf returns the label of the struct d, it reports false when the struct has no label or an empty one
+func (sc *structCodec) labelOf(d reflect.Value) (string, bool) {
+	if sc.label < 0 {
+		return "", false
+	}
+	f := d.Field(sc.label)
+	if f.Kind() != reflect.String {
+		return "", false
+	}
+	return f.String(), f.Len() > 0
+}
+
+// verticesOf returns the fields of the struct d holding the ids of the outgoing and the incoming vertex of an edge
+func (sc *structCodec) verticesOf(d reflect.Value) (out, in reflect.Value, ok bool) {
+	for _, fc := range sc.fields {
+		switch fc.mode {
+		case modeOutV:
+			out = d.Field(fc.index)
+		case modeInV:
+			in = d.Field(fc.index)
+		}
+	}
+	return out, in, out.IsValid() && in.IsValid()
+}
+
+// element reports whether a field holds the label or the vertices of an edge rather than a property
+func (fc *fieldCodec) element() bool {
+	return fc.mode == modeLabel || fc.mode == modeInV || fc.mode == modeOutV
+}
+
+// addProps writes the properties of the struct d to the script of AddV and AddEdge
+func (sc *structCodec) addProps(q *script, d reflect.Value) error {
+	if len(sc.fields) == 0 {
+		return ErrorInterfaceHasNoIdField
+	}
+	for _, fc := range sc.fields {
+		if fc.element() {
+			continue
+		}
+		if convert := fc.converter(q); convert != nil {
+			values, err := fc.propValues(d, convert)
+			if err != nil {
+				return err
+			}
+			for _, v := range values {
+				q.add(".property('%s', %s)", fc.name, q.prop(v))
+			}
+			continue
+		}
+		val, ok := fc.value(d)
+		if !ok {
+			continue
+		}
+		if fc.index == sc.id && q.idToken {
+			// the id token sets the id of the element whatever the field is tagged as, a zero id is left to the server
+			if d.Field(fc.index).IsZero() {
+				continue
+			}
+			id, err := q.id(val)
+			if err != nil {
+				return err
+			}
+			q.add(".property(id, %s)", id)
+			continue
+		}
+		switch fc.mode {
+		case modeUntyped:
+			return fmt.Errorf("gremgoser: interface field graph tag does not contain a tag option type, field type: %T", val)
+		case modeID:
+			// a zero id is left to the server to assign
+			if d.Field(fc.index).IsZero() {
+				continue
+			}
+			id, err := q.id(val)
+			if err != nil {
+				return err
+			}
+			q.add(".property('%s', %s)", fc.name, id)
+		case modeString, modePartitionKey:
+			q.add(".property('%s', %s)", fc.name, q.str(fmt.Sprintf("%s", val)))
+		case modeValue:
+			q.add(".property('%s', %s)", fc.name, q.val(val))
+		case modeJSON:
+			jsonBytes, err := json.Marshal(val)
+			if err != nil {
+				return err
+			}
+			q.add(".property('%s', %s)", fc.name, q.json(jsonBytes))
+		case modeStrings:
+			s := reflect.ValueOf(val)
+			for i := 0; i < s.Len(); i++ {
+				q.add(".property('%s', %s)", fc.name, q.str(fmt.Sprintf("%s", s.Index(i).Interface())))
+			}
+		case modeValues:
+			s := reflect.ValueOf(val)
+			for i := 0; i < s.Len(); i++ {
+				q.add(".property('%s', %s)", fc.name, q.val(s.Index(i).Interface()))
+			}
+		}
+	}
+	return nil
+}
+
+// updateV writes the properties of the struct d to the script of UpdateV, list properties are replaced
+func (sc *structCodec) updateV(q *script, d reflect.Value) error {
+	tagLength, err := sc.setProps(q, d, true)
+	if err != nil {
+		return err
+	}
+	if tagLength == 0 {
+		return ErrorInterfaceHasNoIdField
+	}
+	return nil
+}
+
+// setProps writes the properties of the struct d to a script of an existing vertex, list properties are replaced.
+// Partition keys are written as has steps when keyed is set and left out otherwise. It returns the number of
+// tagged properties.
+func (sc *structCodec) setProps(q *script, d reflect.Value, keyed bool) (int, error) {
+	tagLength := 0
+	for _, fc := range sc.fields {
+		if fc.index == sc.id || fc.element() {
+			continue
+		}
+		tagLength++
+		if convert := fc.converter(q); convert != nil {
+			values, err := fc.propValues(d, convert)
+			if err != nil {
+				return 0, err
+			}
+			if !fc.isSlice {
+				for _, v := range values {
+					q.add(".property('%s', %s)", fc.name, q.prop(v))
+				}
+				continue
+			}
+			// drop the properties
+			q.add(".sideEffect(properties('%s').drop())", fc.name)
+			for _, v := range values {
+				q.add(".property(list, '%s', %s)", fc.name, q.prop(v))
+			}
+			continue
+		}
+		val, ok := fc.value(d)
+		if !ok {
+			continue
+		}
+		switch fc.mode {
+		case modeUntyped:
+			return 0, fmt.Errorf("gremgoser: interface field graph tag does not contain a tag option type, field type: %T", val)
+		case modePartitionKey:
+			if keyed {
+				q.add(".has('%s', %s)", fc.name, q.str(fmt.Sprintf("%s", val)))
+			}
+		case modeString:
+			q.add(".property('%s', %s)", fc.name, q.str(fmt.Sprintf("%s", val)))
+		case modeValue:
+			q.add(".property('%s', %s)", fc.name, q.val(val))
+		case modeJSON:
+			jsonBytes, err := json.Marshal(val)
+			if err != nil {
+				return 0, err
+			}
+			q.add(".property('%s', %s)", fc.name, q.json(jsonBytes))
+		case modeStrings:
+			// drop the properties
+			q.add(".sideEffect(properties('%s').drop())", fc.name)
+			s := reflect.ValueOf(val)
+			for i := 0; i < s.Len(); i++ {
+				q.add(".property(list, '%s', %s)", fc.name, q.str(fmt.Sprintf("%s", s.Index(i).Interface())))
+			}
+		case modeValues:
+			// drop the properties
+			q.add(".sideEffect(properties('%s').drop())", fc.name)
+			s := reflect.ValueOf(val)
+			for i := 0; i < s.Len(); i++ {
+				q.add(".property(list, '%s', %s)", fc.name, q.val(s.Index(i).Interface()))
+			}
+		}
+	}
+	return tagLength, nil
+}
+
+// upsertV writes the script of UpsertV, the vertex is looked up by its id and partition key and added with them
+// when it does not exist, then its properties are set like in UpdateV
+func (sc *structCodec) upsertV(q *script, label, id string, d reflect.Value) error {
+	var keys []string
+	for _, fc := range sc.fields {
+		if fc.mode != modePartitionKey || fc.converter(q) != nil {
+			continue
+		}
+		if val, ok := fc.value(d); ok {
+			keys = append(keys, fmt.Sprintf("'%s', %s", fc.name, q.str(fmt.Sprintf("%s", val))))
+		}
+	}
+	q.add("g.V(%s)", id)
+	for _, key := range keys {
+		q.add(".has(%s)", key)
+	}
+	q.add(".fold().coalesce(unfold(), addV(%s)", q.str(label))
+	if q.idToken {
+		q.add(".property(id, %s)", id)
+	} else {
+		q.add(".property('id', %s)", id)
+	}
+	for _, key := range keys {
+		q.add(".property(%s)", key)
+	}
+	q.add(")")
+	_, err := sc.setProps(q, d, false)
+	return err
+}
+
+// decode populates the struct v from a vertex or an edge of a response
+func (sc *structCodec) decode(item *GremlinData, v reflect.Value) error {
+	// the id is in the base response map
+	if err := setID(v.Field(sc.id), item.Id); err != nil {
+		return err
+	}
+	edge := item.Type == "edge"
+	for _, fc := range sc.fields {
+		if fc.index == sc.id {
+			continue
+		}
+		f := v.Field(fc.index)
+		if !f.CanSet() {
+			continue
+		}
+		switch fc.mode {
+		case modeLabel:
+			if f.Kind() == reflect.String {
+				f.SetString(item.Label)
+			}
+			continue
+		case modeInV:
+			if err := setID(f, item.InV); err != nil {
+				return err
+			}
+			continue
+		case modeOutV:
+			if err := setID(f, item.OutV); err != nil {
+				return err
+			}
+			continue
+		}
+		// it is a property and we have to looks at the properties map
+		prop, ok := item.Properties[fc.name]
+		if !ok {
+			continue
+		}
+		if edge {
+			prop = edgeProperty(prop)
+		}
+		if fc.unmarshal {
+			if err := fc.decodeEach(f, reflect.ValueOf(prop), unmarshalGraph); err != nil {
+				return err
+			}
+		} else if fc.mode == modeTime {
+			setFormat := func(v reflect.Value, value interface{}) error {
+				return setTime(v, value, fc.format)
+			}
+			if err := fc.decodeEach(f, reflect.ValueOf(prop), setFormat); err != nil {
+				return err
+			}
+		} else if err := fc.decode(f, reflect.ValueOf(prop)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// edgeProperty returns an edge property in the shape of the list of vertex properties, edges have a single value
+// per key that is sent as is or as a property with key and value
+func edgeProperty(prop interface{}) interface{} {
+	if m, ok := prop.(map[string]interface{}); ok {
+		if _, ok := m["value"]; ok {
+			return []interface{}{m}
+		}
+	}
+	return []interface{}{map[string]interface{}{"value": prop}}
+}
+
+// decode sets the field f from the slice of properties the element has for it
+func (fc *fieldCodec) decode(f, propSlice reflect.Value) error {
+	propSliceLen := propSlice.Len()
+	// check the length, if its 1 we set it as a single value otherwise we need to create a slice
+	if propSliceLen == 1 {
+		// get the value of the property we are looking for
+		v, err := getPropertyValue(propSlice.Index(0).Interface())
+		if err != nil {
+			return err
+		}
+		switch fc.kind {
+		case reflect.String: // Set as string
+			vString, ok := v.(string)
+			if ok {
+				if fc.isPtr {
+					f.Set(reflect.ValueOf(&vString))
+				} else {
+					f.SetString(vString)
+				}
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64: // Set as int
+			vNumber, ok := v.(json.Number)
+			if ok {
+				vInt, _ := vNumber.Int64()
+				if !f.OverflowInt(vInt) {
+					if fc.isPtr {
+						f.Set(reflect.ValueOf(&vInt))
+					} else {
+						f.SetInt(vInt)
+					}
+				}
+			}
+		case reflect.Float32, reflect.Float64: // Set as float
+			vNumber, ok := v.(json.Number)
+			if ok {
+				vFloat, _ := vNumber.Float64()
+				if !f.OverflowFloat(vFloat) {
+					if fc.isPtr {
+						f.Set(reflect.ValueOf(&vFloat))
+					} else {
+						f.SetFloat(vFloat)
+					}
+				}
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64: // Set as uint
+			vNumber, ok := v.(json.Number)
+			if ok {
+				vInt, _ := vNumber.Int64()
+				vUint := uint64(vInt)
+				if !f.OverflowUint(vUint) {
+					if fc.isPtr {
+						f.Set(reflect.ValueOf(&vUint))
+					} else {
+						f.SetUint(vUint)
+					}
+				}
+			}
+		case reflect.Bool: // Set as bool
+			vBool, ok := v.(bool)
+			if ok {
+				if fc.isPtr {
+					f.Set(reflect.ValueOf(&vBool))
+				} else {
+					f.SetBool(vBool)
+				}
+			}
+		case reflect.Struct, reflect.Map: // take JSON string and unmarshal into struct or map
+			vString, ok := v.(string)
+			if ok {
+				s := reflect.New(f.Type()).Interface()
+				json.Unmarshal([]byte(vString), s)
+				if fc.isPtr {
+					f.Set(reflect.ValueOf(s))
+				} else {
+					f.Set(reflect.ValueOf(s).Elem())
+				}
+			}
+		}
+		// this is a special case
+		if fc.jsonSlice { // take JSON string and unmarshal into slice
+			vString, ok := v.(string) // the data is stored as a struct string in the graph
+			if ok {
+				sSlice := reflect.SliceOf(f.Type().Elem())
+				s := reflect.New(sSlice)
+				json.Unmarshal([]byte(vString), s.Interface())
+				if fc.isPtr {
+					sInterface := s.Interface()
+					f.Set(reflect.ValueOf(sInterface))
+				} else {
+					f.Set(s.Elem())
+				}
+			}
+		}
+	} else if propSliceLen > 1 { // we need to creates slices for the properties
+		pSlice := reflect.MakeSlice(reflect.SliceOf(f.Type().Elem()), propSliceLen, propSliceLen+1)
+		// now we iterate over the properties
+		for i := 0; i < propSliceLen; i++ {
+			// get the value of the property we are looking for
+			v, err := getPropertyValue(propSlice.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+			switch fc.kind {
+			case reflect.String: // Set as string
+				vString, ok := v.(string)
+				if ok {
+					pSlice.Index(i).SetString(vString)
+				}
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64: // Set as int
+				vNumber, ok := v.(json.Number)
+				if ok {
+					vInt, _ := vNumber.Int64()
+					if !pSlice.Index(i).OverflowInt(vInt) {
+						pSlice.Index(i).SetInt(vInt)
+					}
+				}
+			case reflect.Float32, reflect.Float64: // Set as float
+				vNumber, ok := v.(json.Number)
+				if ok {
+					vFloat, _ := vNumber.Float64()
+					if !pSlice.Index(i).OverflowFloat(vFloat) {
+						pSlice.Index(i).SetFloat(vFloat)
+					}
+				}
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64: // Set as uint
+				vNumber, ok := v.(json.Number)
+				if ok {
+					vInt, _ := vNumber.Int64()
+					vUint := uint64(vInt)
+					if !pSlice.Index(i).OverflowUint(vUint) {
+						pSlice.Index(i).SetUint(vUint)
+					}
+				}
+			case reflect.Bool: // Set as bool
+				vBool, ok := v.(bool)
+				if ok {
+					pSlice.Index(i).SetBool(vBool)
+				}
+			}
+		}
+		// set the field to the created slice
+		f.Set(pSlice)
+	}
+	return nil
+}
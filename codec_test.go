@@ -0,0 +1,226 @@
+package gremgoser
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructCodec(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := newStructCodec(reflect.TypeOf(struct {
+		Name    string            `graph:"name,string"`
+		Skipped int               // no graph tag
+		Key     NodeID            `graph:"id,id"`
+		Tenant  string            `graph:"tenant,partitionKey"`
+		Age     *int              `graph:"age,number"`
+		Tags    []string          `graph:"tags,[]string"`
+		Scores  []float64         `graph:"scores,[]number"`
+		Meta    map[string]string `graph:"meta,struct"`
+		Nested  []Test2           `graph:"nested,struct"`
+		Raw     string            `graph:"raw"`
+		Other   string            `graph:"other,unknown"`
+	}{}))
+	assert.Equal(2, sc.id)
+	assert.Equal([]fieldCodec{
+		{index: 0, name: "name", mode: modeString, kind: reflect.String, elem: reflect.TypeOf("")},
+		{index: 2, name: "id", mode: modeID, kind: reflect.Int64, elem: reflect.TypeOf(NodeID(0))},
+		{index: 3, name: "tenant", mode: modePartitionKey, kind: reflect.String, elem: reflect.TypeOf("")},
+		{index: 4, name: "age", mode: modeValue, kind: reflect.Int, isPtr: true, elem: reflect.TypeOf(0)},
+		{index: 5, name: "tags", mode: modeStrings, kind: reflect.String, isSlice: true, elem: reflect.TypeOf("")},
+		{index: 6, name: "scores", mode: modeValues, kind: reflect.Float64, isSlice: true, elem: reflect.TypeOf(0.0)},
+		{index: 7, name: "meta", mode: modeJSON, kind: reflect.Map, elem: reflect.TypeOf(map[string]string{})},
+		{index: 8, name: "nested", mode: modeJSON, kind: reflect.Struct, isSlice: true, jsonSlice: true, elem: reflect.TypeOf(Test2{})},
+		{index: 9, name: "raw", mode: modeUntyped, kind: reflect.String, elem: reflect.TypeOf("")},
+		{index: 10, name: "other", mode: modeUnknown, kind: reflect.String, elem: reflect.TypeOf("")},
+	}, sc.fields)
+
+	// the id falls back to the field named Id
+	assert.Equal(0, newStructCodec(reflect.TypeOf(Test2{})).id)
+	assert.Equal(-1, newStructCodec(reflect.TypeOf(struct{ A string }{})).id)
+
+	// the codec is built once per type and shared by concurrent callers
+	typ := reflect.TypeOf(Test{})
+	codecs := make([]*structCodec, 8)
+	var wg sync.WaitGroup
+	for i := range codecs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codecs[i] = codecOf(typ)
+		}(i)
+	}
+	wg.Wait()
+	for _, sc := range codecs {
+		assert.True(sc == codecOf(typ))
+	}
+}
+
+func TestStructCodecScripts(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	c.conf.SetDisableBindings()
+
+	name := "ann"
+	p := struct {
+		Id   uuid.UUID `graph:"id,string"`
+		Name *string   `graph:"name,string"`
+		Tags []string  `graph:"tags,[]string"`
+		Pk   string    `graph:"pk,partitionKey"`
+		Raw  string    `graph:"raw,unknown"`
+	}{Id: uuid.MustParse("64795211-c4a1-4eac-9e0a-b674ced77461"), Name: &name, Tags: []string{"a", "b"}, Pk: "p"}
+	d := reflect.ValueOf(p)
+	sc := codecOf(d.Type())
+
+	q := c.newScript()
+	assert.Nil(sc.addProps(q, d))
+	assert.Equal(".property('id', '64795211-c4a1-4eac-9e0a-b674ced77461').property('name', 'ann').property('tags', 'a').property('tags', 'b').property('pk', 'p')", q.String())
+
+	// the id token sets the id itself on servers like TinkerGraph
+	q = c.newScript()
+	q.idToken = true
+	assert.Nil(sc.addProps(q, d))
+	assert.Equal(".property(id, '64795211-c4a1-4eac-9e0a-b674ced77461').property('name', 'ann').property('tags', 'a').property('tags', 'b').property('pk', 'p')", q.String())
+
+	q = c.newScript()
+	assert.Nil(sc.updateV(q, d))
+	assert.Equal(".property('name', 'ann').sideEffect(properties('tags').drop()).property(list, 'tags', 'a').property(list, 'tags', 'b').has('pk', 'p')", q.String())
+
+	// updating a struct with a tagged id only fails
+	idOnly := struct {
+		Id uuid.UUID `graph:"id,string"`
+	}{}
+	assert.Equal(ErrorInterfaceHasNoIdField, codecOf(reflect.TypeOf(idOnly)).updateV(c.newScript(), reflect.ValueOf(idOnly)))
+
+	// nil pointers are not written
+	p.Name = nil
+	q = c.newScript()
+	assert.Nil(sc.updateV(q, reflect.ValueOf(p)))
+	assert.Equal(".sideEffect(properties('tags').drop()).property(list, 'tags', 'a').property(list, 'tags', 'b').has('pk', 'p')", q.String())
+
+	// untagged options fail
+	untyped := struct {
+		Id uuid.UUID `graph:"id,string"`
+		A  string    `graph:"a"`
+	}{}
+	assert.NotNil(codecOf(reflect.TypeOf(untyped)).addProps(c.newScript(), reflect.ValueOf(untyped)))
+	assert.NotNil(codecOf(reflect.TypeOf(untyped)).updateV(c.newScript(), reflect.ValueOf(untyped)))
+}
+
+// benchmarkRows returns n copies of the vertex of the get response decoded like Get does
+func benchmarkRows(b *testing.B, n int) []*GremlinData {
+	var resp GremlinResponse
+	if err := json.Unmarshal([]byte(getResp), &resp); err != nil {
+		b.Fatal(err)
+	}
+	obj, err := json.Marshal(resp.Result.Data[0])
+	if err != nil {
+		b.Fatal(err)
+	}
+	rows := make([]*GremlinData, n)
+	for i := range rows {
+		decoder := json.NewDecoder(bytes.NewReader(obj))
+		decoder.UseNumber()
+		if err := decoder.Decode(&rows[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return rows
+}
+
+// BenchmarkDecode decodes rows with the cached codec like Get does
+func BenchmarkDecode(b *testing.B) {
+	rows := benchmarkRows(b, 1000)
+	typ := reflect.TypeOf(Test{})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := reflect.MakeSlice(reflect.SliceOf(typ), len(rows), len(rows))
+		sc := codecOf(typ)
+		for j, row := range rows {
+			if err := sc.decode(row, s.Index(j)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkDecodeUncached reflects the fields and parses the tags for every row, like Get did before the codec
+// was cached
+func BenchmarkDecodeUncached(b *testing.B) {
+	rows := benchmarkRows(b, 1000)
+	typ := reflect.TypeOf(Test{})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := reflect.MakeSlice(reflect.SliceOf(typ), len(rows), len(rows))
+		for j, row := range rows {
+			if err := newStructCodec(typ).decode(row, s.Index(j)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkTest returns the vertex of the AddV and UpdateV tests
+func benchmarkTest() Test {
+	return Test{
+		Id: uuid.MustParse("64795211-c4a1-4eac-9e0a-b674ced77461"),
+		A:  "aa", B: 10, C: 20, D: 30, E: 40, F: 50, G: 0.06, H: 0.07, I: 80, J: 90, K: 100, L: 110, M: 120, N: true,
+		AA: []string{"aa", "aa"}, BB: []int{10, 10}, FF: []int64{50, 50}, HH: []float64{0.07, 0.07},
+		Z: Test2{A: "aa", B: 10},
+	}
+}
+
+// BenchmarkAddVScript builds the script of AddV with the cached codec
+func BenchmarkAddVScript(b *testing.B) {
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	d := reflect.ValueOf(benchmarkTest())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q := c.newScript()
+		if err := codecOf(d.Type()).addProps(q, d); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkAddVScriptUncached reflects the fields and parses the tags for every call
+func BenchmarkAddVScriptUncached(b *testing.B) {
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	d := reflect.ValueOf(benchmarkTest())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q := c.newScript()
+		if err := newStructCodec(d.Type()).addProps(q, d); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUpdateVScript builds the script of UpdateV with the cached codec
+func BenchmarkUpdateVScript(b *testing.B) {
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	d := reflect.ValueOf(benchmarkTest())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q := c.newScript()
+		if err := codecOf(d.Type()).updateV(q, d); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
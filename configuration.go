@@ -10,11 +10,19 @@ import (
 // NewClientConfig returns a default client config
 func NewClientConfig(uri string) *ClientConfig {
 	return &ClientConfig{
-		URI:          uri,
-		Timeout:      300 * time.Second,
-		PingInterval: 60 * time.Second,
-		WritingWait:  120 * time.Second,
-		ReadingWait:  120 * time.Second,
+		URI:                 uri,
+		Timeout:             300 * time.Second,
+		PingInterval:        60 * time.Second,
+		WritingWait:         120 * time.Second,
+		ReadingWait:         120 * time.Second,
+		PoolSize:            1,
+		MinIdle:             1,
+		ReconnectBackoff:    100 * time.Millisecond,
+		ReconnectMaxBackoff: 30 * time.Second,
+		Serializer:          SerializerGraphSONv2,
+		TimeFormat:          TimeFormatMillis,
+		BatchSize:           100,
+		BatchConcurrency:    4,
 	}
 }
 
@@ -54,6 +62,75 @@ func (conf *ClientConfig) SetReadingWait(seconds int) {
 	conf.ReadingWait = time.Duration(seconds) * time.Second
 }
 
+// SetPoolSize sets the maximum number of websocket connections the client keeps open
+func (conf *ClientConfig) SetPoolSize(size int) {
+	conf.PoolSize = size
+}
+
+// SetMinIdle sets the number of websocket connections that are dialed upfront and kept open
+func (conf *ClientConfig) SetMinIdle(minIdle int) {
+	conf.MinIdle = minIdle
+}
+
+// SetMaxInFlight sets the maximum number of requests awaiting a response on a single connection, 0 means unlimited
+func (conf *ClientConfig) SetMaxInFlight(maxInFlight int) {
+	conf.MaxInFlight = maxInFlight
+}
+
+// SetReconnectBackoff sets the initial and the maximum delay between reconnect attempts
+func (conf *ClientConfig) SetReconnectBackoff(min, max time.Duration) {
+	conf.ReconnectBackoff = min
+	conf.ReconnectMaxBackoff = max
+}
+
+// SetReconnectAttempts sets how many consecutive reconnect attempts are made before giving up, 0 retries forever
+func (conf *ClientConfig) SetReconnectAttempts(attempts int) {
+	conf.ReconnectAttempts = attempts
+}
+
+// SetReplayIdempotent enables replaying read-only requests that were in flight on a lost connection
+func (conf *ClientConfig) SetReplayIdempotent() {
+	conf.ReplayIdempotent = true
+}
+
+// SetDisableBindings makes the struct helpers like AddV inline values into the script instead of sending them
+// as bindings, for servers that restrict bindings
+func (conf *ClientConfig) SetDisableBindings() {
+	conf.DisableBindings = true
+}
+
+// SetIdToken makes the struct helpers like AddV and UpsertV, and Import, set the ids with the id token, T.id, like
+// TinkerGraph and JanusGraph require. The ids are written as the 'id' property otherwise, like Cosmos DB expects.
+func (conf *ClientConfig) SetIdToken() {
+	conf.IdToken = true
+}
+
+// SetSerializer sets the format requests and responses are exchanged in
+func (conf *ClientConfig) SetSerializer(serializer Serializer) {
+	conf.Serializer = serializer
+}
+
+// SetTimeFormat sets how time.Time fields tagged with the time option are written, a field can override it with
+// the millis or rfc3339 option like `graph:"createdAt,time,rfc3339"`
+func (conf *ClientConfig) SetTimeFormat(format TimeFormat) {
+	conf.TimeFormat = format
+}
+
+// SetBatchSize sets how many elements AddVBatch and AddEBatch send in a single traversal
+func (conf *ClientConfig) SetBatchSize(size int) {
+	conf.BatchSize = size
+}
+
+// SetBatchConcurrency sets how many traversals of AddVBatch and AddEBatch are in flight at the same time
+func (conf *ClientConfig) SetBatchConcurrency(concurrency int) {
+	conf.BatchConcurrency = concurrency
+}
+
+// SetStateCallback sets the callback that receives connection state transitions
+func (conf *ClientConfig) SetStateCallback(fn func(ConnectionState)) {
+	conf.OnStateChange = fn
+}
+
 // SetLogger sets the default logger
 func (conf *ClientConfig) SetLogger(logger *logger.Logger) {
 	conf.Logger = logger
@@ -92,3 +92,79 @@ func TestSetLogger(t *testing.T) {
 	conf.SetLogger(log)
 	assert.Equal(log, conf.Logger)
 }
+
+func TestSetPool(t *testing.T) {
+	assert := assert.New(t)
+
+	u := "ws://127.0.0.1"
+	conf := NewClientConfig(u)
+	assert.Equal(1, conf.PoolSize)
+	assert.Equal(1, conf.MinIdle)
+	conf.SetPoolSize(4)
+	conf.SetMinIdle(2)
+	conf.SetMaxInFlight(8)
+	assert.Equal(4, conf.PoolSize)
+	assert.Equal(2, conf.MinIdle)
+	assert.Equal(8, conf.MaxInFlight)
+}
+
+func TestSetReconnect(t *testing.T) {
+	assert := assert.New(t)
+
+	u := "ws://127.0.0.1"
+	conf := NewClientConfig(u)
+	conf.SetReconnectBackoff(time.Second, time.Minute)
+	conf.SetReconnectAttempts(5)
+	conf.SetReplayIdempotent()
+	assert.Equal(time.Second, conf.ReconnectBackoff)
+	assert.Equal(time.Minute, conf.ReconnectMaxBackoff)
+	assert.Equal(5, conf.ReconnectAttempts)
+	assert.True(conf.ReplayIdempotent)
+
+	var state ConnectionState
+	conf.SetStateCallback(func(s ConnectionState) { state = s })
+	conf.OnStateChange(StateReconnecting)
+	assert.Equal(StateReconnecting, state)
+	assert.Equal("reconnecting", state.String())
+}
+
+func TestSetDisableBindings(t *testing.T) {
+	assert := assert.New(t)
+
+	u := "ws://127.0.0.1"
+	conf := NewClientConfig(u)
+	assert.False(conf.DisableBindings)
+	conf.SetDisableBindings()
+	assert.True(conf.DisableBindings)
+}
+
+func TestSetSerializer(t *testing.T) {
+	assert := assert.New(t)
+
+	u := "ws://127.0.0.1"
+	conf := NewClientConfig(u)
+	assert.Equal(SerializerGraphSONv2, conf.Serializer)
+	conf.SetSerializer(SerializerGraphSONv3)
+	assert.Equal(SerializerGraphSONv3, conf.Serializer)
+}
+
+func TestSetTimeFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	conf := NewClientConfig("")
+	assert.Equal(TimeFormatMillis, conf.TimeFormat)
+	conf.SetTimeFormat(TimeFormatRFC3339)
+	assert.Equal(TimeFormatRFC3339, conf.TimeFormat)
+}
+
+func TestSetBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	conf := NewClientConfig("")
+	assert.Equal(100, conf.BatchSize)
+	assert.Equal(4, conf.BatchConcurrency)
+	conf.SetBatchSize(500)
+	conf.SetBatchConcurrency(8)
+	assert.Equal(500, conf.BatchSize)
+	assert.Equal(8, conf.BatchConcurrency)
+}
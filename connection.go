@@ -16,6 +16,7 @@ type dialer interface {
 	read() ([]byte, error)
 	close() error
 	ping(errs chan error)
+	setHandling(handling bool)
 }
 
 func (ws *Ws) connect() error {
@@ -43,7 +44,10 @@ func (ws *Ws) connect() error {
 	}
 
 	if err == nil {
+		ws.Lock()
 		ws.connected = true
+		ws.lastPong = time.Now()
+		ws.Unlock()
 		ws.conn.SetPongHandler(ws.pongHandler)
 	}
 
@@ -54,16 +58,21 @@ func (ws *Ws) pongHandler(appData string) error {
 	ws.conn.SetReadDeadline(time.Now().Add(ws.pingInterval + 10))
 	ws.Lock()
 	ws.connected = true
+	ws.lastPong = time.Now()
 	ws.Unlock()
 	ws.verbosef("received pong message from server")
 	return nil
 }
 
 func (ws *Ws) isConnected() bool {
+	ws.RLock()
+	defer ws.RUnlock()
 	return ws.connected
 }
 
 func (ws *Ws) isDisposed() bool {
+	ws.RLock()
+	defer ws.RUnlock()
 	return ws.disposed
 }
 
@@ -71,6 +80,8 @@ func (ws *Ws) write(msg []byte) error {
 	if ws.conn == nil {
 		return ErrorWSConnectionNil
 	}
+	ws.writeMutex.Lock()
+	defer ws.writeMutex.Unlock()
 	wwt := time.Now().Add(ws.writingWait)
 	ws.verbosef("waiting to write until: %s, msg: %s", wwt.Format("Mon Jan 2 15:04:05 -0700 MST 2006"), msg)
 	ws.conn.SetWriteDeadline(wwt)
@@ -99,10 +110,19 @@ func (ws *Ws) close() error {
 	if ws.conn == nil {
 		return ErrorWSConnectionNil
 	}
+	ws.Lock()
+	if ws.disposed { // the connection was already closed
+		ws.Unlock()
+		return nil
+	}
+	ws.disposed = true
+	ws.connected = false
+	ws.Unlock()
+	ws.writeMutex.Lock()
+	defer ws.writeMutex.Unlock()
 	defer func() {
 		close(ws.quit)
 		ws.conn.Close()
-		ws.disposed = true
 	}()
 
 	err := ws.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")) //Cleanly close the connection with the server
@@ -110,21 +130,37 @@ func (ws *Ws) close() error {
 }
 
 func (ws *Ws) ping(errs chan error) {
-	var isConnected bool
 	ticker := time.NewTicker(ws.pingInterval)
 	defer ticker.Stop()
+	var pingSent time.Time
 	for {
-		if ws.conn == nil {
-			errs <- ErrorWSConnectionNil
-		}
 		select {
 		case <-ticker.C:
-			isConnected = true
+			if ws.conn == nil {
+				ws.reportPingError(errs, ErrorWSConnectionNil)
+				return
+			}
+			ws.RLock()
+			lastPong, handling := ws.lastPong, ws.handling
+			ws.RUnlock()
+			if handling { // pongs are not read while a message is handled, e.g. a stream waiting on its consumer
+				pingSent = time.Time{}
+				continue
+			}
+			if !pingSent.IsZero() && lastPong.Before(pingSent) { // the server did not answer the last ping
+				ws.Lock()
+				ws.connected = false
+				ws.Unlock()
+				ws.reportPingError(errs, ErrorMissedPong)
+				return
+			}
+			isConnected := true
 			err := ws.conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(ws.writingWait))
 			if err != nil {
-				errs <- err
+				ws.reportPingError(errs, err)
 				isConnected = false
 			}
+			pingSent = time.Now()
 			ws.verbosef("sending ping message to server")
 			ws.Lock()
 			ws.connected = isConnected
@@ -135,41 +171,57 @@ func (ws *Ws) ping(errs chan error) {
 	}
 }
 
-// writeWorker works on a loop and dispatches messages as soon as it receives them
-func (c *Client) writeWorker(errs chan error, quit chan struct{}) {
+// setHandling marks whether the reader is busy handling a message, the pong check is suspended meanwhile
+func (ws *Ws) setHandling(handling bool) {
+	ws.Lock()
+	ws.handling = handling
+	ws.Unlock()
+}
+
+// reportPingError sends the error unless the connection is closed in the meantime
+func (ws *Ws) reportPingError(errs chan error, err error) {
+	select {
+	case errs <- err:
+	case <-ws.quit:
+	}
+}
+
+// writeWorker works on a loop and dispatches messages routed to the connection as soon as it receives them
+func (c *Client) writeWorker(pc *poolConn) {
 	for {
 		select {
-		case msg := <-c.requests:
-			err := c.conn.write(msg)
+		case msg := <-pc.requests:
+			err := pc.conn.write(msg)
 			if err != nil {
-				errs <- err
-				c.Errored = true
-				break
+				c.connectionErrored(pc, err)
+				return
 			}
-		case <-quit:
+		case <-pc.quit:
 			return
 		}
 	}
 }
 
-// readWorker works on a loop and sorts messages as soon as it receives them
-func (c *Client) readWorker(errs chan error, quit chan struct{}) {
+// readWorker works on a loop and sorts messages of the connection as soon as it receives them
+func (c *Client) readWorker(pc *poolConn) {
 	for {
-		msg, err := c.conn.read()
+		msg, err := pc.conn.read()
 		if err != nil {
-			errs <- err
-			c.Errored = true
-			break
+			c.connectionErrored(pc, err)
+			return
 		}
 		if msg != nil {
+			c.pool.alive()
+			pc.conn.setHandling(true)
 			err := c.handleResponse(msg)
+			pc.conn.setHandling(false)
 			if err != nil {
-				errs <- err
+				c.reportError(err)
 			}
 			c.verbose("message handled: %s", msg)
 		}
 		select {
-		case <-quit:
+		case <-pc.quit:
 			return
 		default:
 			continue
@@ -177,6 +229,74 @@ func (c *Client) readWorker(errs chan error, quit chan struct{}) {
 	}
 }
 
+// connectionErrored stops routing requests to a failed connection, starts its recovery and reports the error.
+// Errors of connections that were already stopped, for example by closing the client, are not reported.
+func (c *Client) connectionErrored(pc *poolConn, err error) {
+	c.pool.mu.Lock()
+	if pc.errored {
+		c.pool.mu.Unlock()
+		return
+	}
+	pc.stop()
+	c.Errored = true
+	c.pool.mu.Unlock()
+	c.debug("connection errored: %s", err)
+	c.pool.retire(pc)
+	c.reportError(err)
+}
+
+// reportError sends an error on the channel returned by NewClient without waiting, errors are dropped while the
+// channel is full so connections never block on a caller that stopped reading it
+func (c *Client) reportError(err error) {
+	select {
+	case c.errs <- err:
+	default:
+		c.debug("dropping error, the error channel is full: %s", err)
+	}
+}
+
+// pingWorker retires the connection as soon as its pings fail or the server stops answering them
+func (c *Client) pingWorker(pc *poolConn, errs chan error) {
+	select {
+	case err := <-errs:
+		c.connectionErrored(pc, err)
+	case <-pc.quit:
+	}
+}
+
+// newWs returns a websocket dialer configured from the client config
+func newWs(conf *ClientConfig) *Ws {
+	ws := &Ws{
+		debug:     conf.Debug,
+		verbose:   conf.Verbose,
+		uri:       conf.URI,
+		connected: false,
+		quit:      make(chan struct{}),
+		logger:    conf.Logger,
+	}
+	if conf.Timeout != 0 {
+		ws.timeout = conf.Timeout
+	} else {
+		ws.timeout = 300 * time.Second
+	}
+	if conf.PingInterval != 0 {
+		ws.pingInterval = conf.PingInterval
+	} else {
+		ws.pingInterval = 60 * time.Second
+	}
+	if conf.WritingWait != 0 {
+		ws.writingWait = conf.WritingWait
+	} else {
+		ws.writingWait = 120 * time.Second
+	}
+	if conf.ReadingWait != 0 {
+		ws.readingWait = conf.ReadingWait
+	} else {
+		ws.readingWait = 120 * time.Second
+	}
+	return ws
+}
+
 // debugf prints to the configured logger if debug is enabled
 func (ws *Ws) debugf(frmt string, i ...interface{}) {
 	if ws.debug && ws.logger != nil {
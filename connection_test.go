@@ -7,7 +7,9 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -41,6 +43,10 @@ var gremForceAuth = `g.V('__FORCE____AUTH__')`
 
 var gremV = `g.V()`
 
+var gremNoResp = `g.V().sideEffect(__NO____RESP__)`
+
+var gremScriptError = `g.V().hasLabel(__SCRIPT____ERROR__)`
+
 var gremGet = `g.V('64795211-c4a1-4eac-9e0a-b674ced77461')`
 
 var gremV1 = `g.addV('test').property('id', '64795211-c4a1-4eac-9e0a-b674ced77461').property('a', 'aa').property('b', 10).property('c', 20).property('d', 30).property('e', 40).property('f', 50).property('g', 0.06).property('h', 0.07).property('i', 80).property('j', 90).property('k', 100).property('l', 110).property('m', 120).property('n', true).property('aa', 'aa').property('aa', 'aa').property('bb', 10).property('bb', 10).property('cc', 20).property('cc', 20).property('dd', 30).property('dd', 30).property('ee', 40).property('ee', 40).property('ff', 50).property('ff', 50).property('gg', 0.06).property('gg', 0.06).property('hh', 0.07).property('hh', 0.07).property('ii', 80).property('ii', 80).property('jj', 90).property('jj', 90).property('kk', 100).property('kk', 100).property('ll', 110).property('ll', 110).property('mm', 120).property('mm', 120).property('nn', true).property('nn', true).property('x', 130).property('xx', 140).property('xx', 140).property('z', '{"Id":"64795211-c4a1-4eac-9e0a-b674ced77461","A":"aa","B":10}').property('zz', '[{"Id":"64795211-c4a1-4eac-9e0a-b674ced77461","A":"aa","B":10},{"Id":"64795211-c4a1-4eac-9e0a-b674ced77461","A":"aa","B":10}]')`
@@ -109,6 +115,78 @@ func pong(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// bindingName matches the names the struct helpers bind values to
+var bindingName = regexp.MustCompile(`_p[0-9]+`)
+
+// inlineBindings replaces the binding names of a script with their values, so the mock answers bound scripts
+// like their inlined equivalent
+func inlineBindings(gremlin string, bindings map[string]interface{}) string {
+	return bindingName.ReplaceAllStringFunc(gremlin, func(name string) string {
+		v, ok := bindings[name]
+		if !ok {
+			return name
+		}
+		if s, ok := v.(string); ok {
+			return "'" + s + "'"
+		}
+		return fmt.Sprintf("%v", v)
+	})
+}
+
+// recordingMock answers every script with the status code and data of its answer function and records the
+// scripts with their bindings inlined
+type recordingMock struct {
+	answer  func(gremlin string) (code int, data []interface{})
+	mu      sync.Mutex
+	scripts []string
+}
+
+func (m *recordingMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	for {
+		mt, message, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req GremlinRequest
+		if err := json.Unmarshal(bytes.TrimPrefix(message, []byte("!application/vnd.gremlin-v2.0+json")), &req); err != nil {
+			return
+		}
+		gremlin, _ := req.Args["gremlin"].(string)
+		if bindings, ok := req.Args["bindings"].(map[string]interface{}); ok {
+			gremlin = inlineBindings(gremlin, bindings)
+		}
+		m.mu.Lock()
+		m.scripts = append(m.scripts, gremlin)
+		m.mu.Unlock()
+		code, data := m.answer(gremlin)
+		if data == nil {
+			data = []interface{}{}
+		}
+		resp, _ := json.Marshal(map[string]interface{}{
+			"requestId": req.RequestId,
+			"status":    map[string]interface{}{"code": code, "message": "", "attributes": map[string]interface{}{}},
+			"result":    map[string]interface{}{"data": data, "meta": map[string]interface{}{}},
+		})
+		if err := c.WriteMessage(mt, resp); err != nil {
+			return
+		}
+	}
+}
+
+// take returns the recorded scripts and forgets them
+func (m *recordingMock) take() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	scripts := m.scripts
+	m.scripts = nil
+	return scripts
+}
+
 func mock(w http.ResponseWriter, r *http.Request) {
 	c, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -129,6 +207,9 @@ func mock(w http.ResponseWriter, r *http.Request) {
 				break
 			}
 			gremlin := req.Args["gremlin"]
+			if bindings, ok := req.Args["bindings"].(map[string]interface{}); ok {
+				gremlin = inlineBindings(gremlin.(string), bindings)
+			}
 			fmt.Printf("------>   Mock Server Request: %s\n", gremlin)
 			switch gremlin {
 			case string(gremForceAuth): // FORCE AUTH
@@ -161,6 +242,23 @@ func mock(w http.ResponseWriter, r *http.Request) {
 				if err != nil {
 					break
 				}
+			case string(gremNoResp): // never answer the query
+				continue
+			case string(gremScriptError): // answer the query with a script evaluation error
+				var resp GremlinResponse
+				err := json.Unmarshal(dummyScriptErrorResponse, &resp)
+				if err != nil {
+					break
+				}
+				resp.RequestId = req.RequestId
+				respMessage, err := json.Marshal(resp)
+				if err != nil {
+					break
+				}
+				err = c.WriteMessage(mt, respMessage)
+				if err != nil {
+					break
+				}
 			case string(gremE): // query add edge
 				var resp GremlinResponse
 				err := json.Unmarshal([]byte(addEResp), &resp)
@@ -355,7 +453,7 @@ func TestWsConnection(t *testing.T) {
 
 	// test connecting to the mock server
 	g, _ := NewClient(NewClientConfig(u))
-	ws := g.conn.(*Ws)
+	ws := g.pool.conns[0].conn.(*Ws)
 
 	// test to see if connectend
 	connected := ws.isConnected()
@@ -365,13 +463,14 @@ func TestWsConnection(t *testing.T) {
 	disposed := ws.isDisposed()
 	assert.False(disposed)
 
-	// test ping
-	ws.pingInterval = time.Duration(10) * time.Millisecond
+	// test ping on a connection the pool did not start, the pool already pings its own
+	ws = &Ws{uri: u, pingInterval: time.Duration(10) * time.Millisecond, writingWait: time.Second, quit: make(chan struct{})}
+	assert.Nil(ws.connect())
 	errs := make(chan error)
 	go ws.ping(errs)
 	go func() {
 		time.Sleep(time.Duration(15) * time.Millisecond)
-		close(ws.quit)
+		ws.close()
 		close(errs)
 	}()
 	err := <-errs
@@ -380,7 +479,7 @@ func TestWsConnection(t *testing.T) {
 
 	// test close
 	g2, _ := NewClient(NewClientConfig(u))
-	ws2 := g2.conn.(*Ws)
+	ws2 := g2.pool.conns[0].conn.(*Ws)
 	err = ws2.close()
 	assert.Nil(err)
 }
@@ -0,0 +1,106 @@
+package gremgoser
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Knows struct {
+	Id     int64   `graph:"id,id"`
+	Label  string  `graph:"label"`
+	InV    NodeID  `graph:"inV"`
+	OutV   NodeID  `graph:"outV"`
+	Weight float64 `graph:"weight,number"`
+	Since  int     `graph:"since,number"`
+}
+
+func TestEdgeCodec(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := codecOf(reflect.TypeOf(Knows{}))
+	assert.Equal(0, sc.id)
+	assert.Equal(1, sc.label)
+	assert.Equal(modeInV, sc.fields[2].mode)
+	assert.Equal(modeOutV, sc.fields[3].mode)
+
+	label, ok := sc.labelOf(reflect.ValueOf(Knows{Label: "knows"}))
+	assert.True(ok)
+	assert.Equal("knows", label)
+	_, ok = codecOf(reflect.TypeOf(Test2{})).labelOf(reflect.ValueOf(Test2{}))
+	assert.False(ok)
+
+	// the label and the vertices are no properties
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	c.conf.SetDisableBindings()
+	q := c.newScript()
+	assert.Nil(sc.updateV(q, reflect.ValueOf(Knows{Label: "knows", InV: 1, OutV: 2, Weight: 0.5})))
+	assert.Equal(".property('weight', 0.5).property('since', 0)", q.String())
+
+	// edge properties are single values, sent as is or as a property with key and value
+	assert.Equal([]interface{}{map[string]interface{}{"value": 1}}, edgeProperty(1))
+	prop := map[string]interface{}{"key": "since", "value": 1}
+	assert.Equal([]interface{}{prop}, edgeProperty(prop))
+}
+
+func TestAddEdge(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newIDMock()
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	g, errs := NewClient(NewClientConfig(u))
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	ann, bob := &IntPerson{Key: 42, Name: "ann"}, &IntPerson{Key: 43, Name: "bob"}
+	_, err := g.AddEdge(ann, bob, &Knows{Label: "knows", Weight: 0.5, Since: 2010})
+	assert.Nil(err)
+	_, err = g.AddEdge(ann, bob, Knows{Id: 7, Label: "it's"})
+	assert.Nil(err)
+
+	m.mu.Lock()
+	assert.Equal([]string{
+		"g.V(42).addE('knows').to(g.V(43)).property('weight', 0.5).property('since', 2010)",
+		"g.V(42).addE('it's').to(g.V(43)).property('id', 7).property('weight', 0).property('since', 0)",
+	}, m.scripts)
+	m.mu.Unlock()
+
+	// the edge needs a label and vertices with ids
+	_, err = g.AddEdge(ann, bob, &Test2{})
+	assert.Equal(ErrorEdgeHasNoLabel, err)
+	_, err = g.AddEdge(ann, bob, "knows")
+	assert.Equal(ErrorEdgeHasNoLabel, err)
+	_, err = g.AddEdge(ann, struct{ A string }{}, &Knows{Label: "knows"})
+	assert.Equal(ErrorInterfaceHasNoIdField, err)
+
+	// edges are decoded with their label, vertices and properties
+	edges, err := GetAs[Knows](context.Background(), g, "g.E().hasLabel('knows')", nil)
+	assert.Nil(err)
+	assert.Equal([]Knows{{Id: 7, Label: "knows", InV: 43, OutV: 42, Weight: 0.5, Since: 2010}}, edges)
+
+	// vertices are decoded with their label
+	var people []struct {
+		Key   NodeID `graph:"id,id"`
+		Label string `graph:"label"`
+		Name  string `graph:"name,string"`
+	}
+	assert.Nil(g.Get("g.V().hasLabel('person')", nil, &people))
+	assert.Equal("person", people[0].Label)
+	assert.Equal("ann", people[0].Name)
+}
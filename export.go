@@ -0,0 +1,353 @@
+package gremgoser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExportFormat is the file format of Export and Import
+type ExportFormat int
+
+const (
+	ExportGraphSON ExportFormat = iota + 1 // GraphSON 3 lines, a typed g:Vertex or g:Edge per line
+	ExportGraphML                          // GraphML with the labels in the labelV and labelE keys, like TinkerPop writes it
+)
+
+// ExportOptions select the part of the graph Export writes
+type ExportOptions struct {
+	Format         ExportFormat // GraphSON lines by default
+	VertexLabels   []string     // only vertices with one of these labels and the edges between them, all when empty
+	EdgeLabels     []string     // only edges with one of these labels, all when empty
+	PartitionKey   string       // property holding the partition key
+	PartitionValue interface{}  // only elements in this partition when PartitionKey is set
+	PageSize       int          // number of elements fetched per request, 1000 by default
+}
+
+// ImportOptions configure Import
+type ImportOptions struct {
+	Format ExportFormat // GraphSON lines by default
+	// IdToken sets the ids with the id token, T.id, like servers such as TinkerGraph require. The ids are written
+	// as the 'id' property otherwise, like Cosmos DB expects them. ClientConfig.IdToken sets it for every import.
+	IdToken bool
+}
+
+// ElementCounts are the number of vertices and edges written by Export or Import
+type ElementCounts struct {
+	Vertices int
+	Edges    int
+}
+
+// Export writes the vertices and then the edges of the graph, or of the part selected by the options, to w. The
+// elements are read page by page in id order, elements added or removed during the export may or may not be in it.
+func (c *Client) Export(ctx context.Context, w io.Writer, opts ExportOptions) (*ElementCounts, error) {
+	if c.pool.isDisposed() {
+		return nil, ErrorConnectionDisposed
+	}
+	var ew elementWriter
+	switch opts.Format {
+	case 0, ExportGraphSON:
+		ew = newGraphSONWriter(w)
+	case ExportGraphML:
+		gw, err := newGraphMLWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		ew = gw
+	default:
+		return nil, fmt.Errorf("gremgoser: unknown export format %d", opts.Format)
+	}
+	defer ew.release()
+	counts := &ElementCounts{}
+	err := c.exportPages(ctx, opts, false, func(v interface{}) error {
+		vertex, ok := v.(*Vertex)
+		if !ok {
+			return fmt.Errorf("gremgoser: exported vertex is not a vertex: %v", v)
+		}
+		counts.Vertices++
+		return ew.vertex(vertex)
+	})
+	if err != nil {
+		return counts, err
+	}
+	err = c.exportPages(ctx, opts, true, func(v interface{}) error {
+		edge, ok := v.(*Edge)
+		if !ok {
+			return fmt.Errorf("gremgoser: exported edge is not an edge: %v", v)
+		}
+		counts.Edges++
+		return ew.edge(edge)
+	})
+	if err != nil {
+		return counts, err
+	}
+	return counts, ew.close()
+}
+
+// exportPages streams the pages of the vertices or the edges selected by the options to fn. The pages are ordered
+// by id and each starts after the last id of the one before, so they neither skip nor repeat elements and every
+// page is an index lookup however far the export got.
+func (c *Client) exportPages(ctx context.Context, opts ExportOptions, edges bool, fn func(interface{}) error) error {
+	size := opts.PageSize
+	if size <= 0 {
+		size = 1000
+	}
+	var last interface{}
+	for {
+		q := c.newScript()
+		q.add("g")
+		if edges {
+			q.add(".E()")
+			if len(opts.EdgeLabels) > 0 {
+				q.add(".hasLabel(%s)", q.strs(opts.EdgeLabels))
+			}
+			if len(opts.VertexLabels) > 0 {
+				labels := q.strs(opts.VertexLabels)
+				q.add(".where(outV().hasLabel(%s)).where(inV().hasLabel(%s))", labels, labels)
+			}
+		} else {
+			q.add(".V()")
+			if len(opts.VertexLabels) > 0 {
+				q.add(".hasLabel(%s)", q.strs(opts.VertexLabels))
+			}
+		}
+		if opts.PartitionKey != "" {
+			q.add(".has(%s, %s)", q.str(opts.PartitionKey), q.prop(opts.PartitionValue))
+		}
+		if last != nil {
+			cursor, err := q.id(last)
+			if err != nil {
+				return err
+			}
+			q.add(".has(id, gt(%s))", cursor)
+		}
+		q.add(".order().by(id).limit(%s)", q.val(size))
+
+		stream, err := c.ExecuteStream(ctx, q.String(), q.params(), nil)
+		if err != nil {
+			return err
+		}
+		n := 0
+		for stream.Next() {
+			for _, v := range stream.Values() {
+				n++
+				element := DecodeElement(v)
+				switch e := element.(type) {
+				case *Vertex:
+					last = exportID(e.Id)
+				case *Edge:
+					last = exportID(e.Id)
+				}
+				if err := fn(element); err != nil {
+					stream.Close()
+					return err
+				}
+			}
+		}
+		stream.Close()
+		if err := stream.Err(); err != nil {
+			return err
+		}
+		if n < size {
+			return nil
+		}
+	}
+}
+
+// exportID returns the id of an exported element as the script writes it, numeric ids of untyped GraphSON stay
+// numbers
+func exportID(id interface{}) interface{} {
+	if n, ok := id.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+	}
+	return id
+}
+
+// elementWriter writes the elements of an export
+type elementWriter interface {
+	vertex(v *Vertex) error
+	edge(e *Edge) error
+	close() error
+	release() // release frees what the writer holds, whether the export succeeded or not
+}
+
+// graphSONWriter writes an element per line as GraphSON 3
+type graphSONWriter struct {
+	enc *json.Encoder
+}
+
+func newGraphSONWriter(w io.Writer) *graphSONWriter {
+	return &graphSONWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *graphSONWriter) vertex(v *Vertex) error {
+	return w.enc.Encode(graphSONv3.encode(v))
+}
+
+func (w *graphSONWriter) edge(e *Edge) error {
+	return w.enc.Encode(graphSONv3.encode(e))
+}
+
+func (w *graphSONWriter) close() error {
+	return nil
+}
+
+func (w *graphSONWriter) release() {}
+
+// Import adds the vertices and edges written by Export to the graph. The elements keep their ids, edges are added
+// between the vertices with the ids they were exported with, so their vertices must come first.
+func (c *Client) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ElementCounts, error) {
+	if c.pool.isDisposed() {
+		return nil, ErrorConnectionDisposed
+	}
+	counts := &ElementCounts{}
+	add := func(n int, v interface{}) error {
+		var err error
+		switch e := v.(type) {
+		case *Vertex:
+			err = c.importVertex(ctx, e, opts)
+			counts.Vertices++
+		case *Edge:
+			err = c.importEdge(ctx, e, opts)
+			counts.Edges++
+		default:
+			err = fmt.Errorf("gremgoser: not a vertex or an edge: %v", v)
+		}
+		if err != nil {
+			return fmt.Errorf("gremgoser: import of element %d: %w", n, err)
+		}
+		return nil
+	}
+	switch opts.Format {
+	case 0, ExportGraphSON:
+		return counts, readGraphSONLines(r, add)
+	case ExportGraphML:
+		return counts, readGraphML(r, add)
+	}
+	return nil, fmt.Errorf("gremgoser: unknown import format %d", opts.Format)
+}
+
+// readGraphSONLines decodes a GraphSON element per line
+func readGraphSONLines(r io.Reader, fn func(int, interface{}) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for n := 1; s.Scan(); n++ {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var raw interface{}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			return fmt.Errorf("gremgoser: import line %d: %w", n, err)
+		}
+		v, err := decodeGraphSON(raw)
+		if err != nil {
+			return fmt.Errorf("gremgoser: import line %d: %w", n, err)
+		}
+		if err := fn(n, v); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// importID writes the id of an imported element
+func (q *script) importID(id interface{}, opts ImportOptions) error {
+	if id == nil {
+		return nil
+	}
+	v, err := q.id(id)
+	if err != nil {
+		return err
+	}
+	if opts.IdToken || q.idToken {
+		q.add(".property(id, %s)", v)
+	} else {
+		q.add(".property('id', %s)", v)
+	}
+	return nil
+}
+
+// importValue returns the binding name or the literal of an imported property value, dates are written in the
+// time format of the client and uuids as strings
+func (q *script) importValue(v interface{}) string {
+	switch val := v.(type) {
+	case time.Time:
+		t, _ := timeValue(reflect.ValueOf(val), q.timeFormat)
+		return q.prop(t)
+	case uuid.UUID:
+		return q.str(val.String())
+	}
+	return q.prop(v)
+}
+
+func (c *Client) importVertex(ctx context.Context, v *Vertex, opts ImportOptions) error {
+	q := c.newScript()
+	q.add("g.addV(%s)", q.str(v.Label))
+	if err := q.importID(v.Id, opts); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(v.Properties) {
+		// properties with several values are lists, servers would keep only the last value of a single property
+		cardinality := ""
+		if len(v.Properties[key]) > 1 {
+			cardinality = "list, "
+		}
+		for _, p := range v.Properties[key] {
+			q.add(".property(%s%s, %s", cardinality, q.str(key), q.importValue(p.Value))
+			for _, meta := range sortedKeys(p.Properties) {
+				q.add(", %s, %s", q.str(meta), q.importValue(p.Properties[meta]))
+			}
+			q.add(")")
+		}
+	}
+	_, err := c.ExecuteContext(ctx, q.String(), q.params(), nil)
+	return err
+}
+
+func (c *Client) importEdge(ctx context.Context, e *Edge, opts ImportOptions) error {
+	q := c.newScript()
+	out, err := q.id(e.OutV)
+	if err != nil {
+		return err
+	}
+	label := q.str(e.Label)
+	in, err := q.id(e.InV)
+	if err != nil {
+		return err
+	}
+	q.add("g.V(%s).addE(%s).to(g.V(%s))", out, label, in)
+	if err := q.importID(e.Id, opts); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(e.Properties) {
+		q.add(".property(%s, %s)", q.str(key), q.importValue(e.Properties[key].Value))
+	}
+	resp, err := c.ExecuteContext(ctx, q.String(), q.params(), nil)
+	if err == nil && len(resp) == 0 {
+		return errors.New("gremgoser: the vertices of the edge do not exist")
+	}
+	return err
+}
+
+// strs returns a comma separated list of the binding names or the literals of strings
+func (q *script) strs(values []string) string {
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = q.str(v)
+	}
+	return strings.Join(names, ", ")
+}
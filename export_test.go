@@ -0,0 +1,231 @@
+package gremgoser
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pageCursor and pageLimit match the id the page starts after and the size of the page
+var (
+	pageCursor = regexp.MustCompile(`\.has\(id, gt\('([^']*)'\)\)`)
+	pageLimit  = regexp.MustCompile(`\.order\(\)\.by\(id\)\.limit\((\d+)\)$`)
+)
+
+// exportVertices and exportEdges are in id order like the pages of the export
+var exportVertices = []interface{}{
+	map[string]interface{}{"id": "acme", "label": "company", "type": "vertex", "properties": map[string]interface{}{
+		"score": []interface{}{map[string]interface{}{"id": "p6", "value": 0.5}},
+	}},
+	map[string]interface{}{"id": "ann", "label": "person", "type": "vertex", "properties": map[string]interface{}{
+		"age":  []interface{}{map[string]interface{}{"id": "p1", "value": 30}},
+		"name": []interface{}{map[string]interface{}{"id": "p2", "value": "Ann & Co"}},
+		"tags": []interface{}{map[string]interface{}{"id": "p3", "value": "a"}, map[string]interface{}{"id": "p4", "value": "b"}},
+	}},
+	map[string]interface{}{"id": "bob", "label": "person", "type": "vertex", "properties": map[string]interface{}{
+		"active": []interface{}{map[string]interface{}{"id": "p5", "value": true}},
+	}},
+}
+
+var exportEdges = []interface{}{
+	map[string]interface{}{"id": "e1", "label": "knows", "type": "edge", "inV": "bob", "outV": "ann", "inVLabel": "person", "outVLabel": "person", "properties": map[string]interface{}{"weight": 0.5}},
+	map[string]interface{}{"id": "e2", "label": "works", "type": "edge", "inV": "acme", "outV": "bob", "inVLabel": "company", "outVLabel": "person"},
+}
+
+// newExportMock serves pages of a small graph in the untyped form of Cosmos DB
+func newExportMock() *recordingMock {
+	return &recordingMock{answer: func(gremlin string) (int, []interface{}) {
+		data := []interface{}{}
+		var elements []interface{}
+		switch {
+		case strings.HasPrefix(gremlin, "g.V()"):
+			elements = exportVertices
+		case strings.HasPrefix(gremlin, "g.E()"):
+			elements = exportEdges
+		case strings.Contains(gremlin, "g.V('nobody')"):
+		default:
+			data = append(data, map[string]interface{}{})
+		}
+		if match := pageLimit.FindStringSubmatch(gremlin); match != nil {
+			limit, _ := strconv.Atoi(match[1])
+			sorted := append([]interface{}(nil), elements...)
+			sort.Slice(sorted, func(i, j int) bool {
+				return sorted[i].(map[string]interface{})["id"].(string) < sorted[j].(map[string]interface{})["id"].(string)
+			})
+			after := ""
+			if cursor := pageCursor.FindStringSubmatch(gremlin); cursor != nil {
+				after = cursor[1]
+			}
+			for _, e := range sorted {
+				if len(data) < limit && e.(map[string]interface{})["id"].(string) > after {
+					data = append(data, e)
+				}
+			}
+		}
+		return 200, data
+	}}
+}
+
+func newExportClient(t *testing.T) (*Client, *recordingMock, func()) {
+	m := newExportMock()
+	s := httptest.NewServer(m)
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	g, errs := NewClient(NewClientConfig(u))
+	assert.NotNil(t, g)
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(t, err)
+	}(errs)
+	return g, m, func() {
+		g.Close()
+		s.Close()
+	}
+}
+
+// imported are the scripts importing the exported graph
+var imported = []string{
+	"g.addV('company').property('id', 'acme').property('score', 0.5)",
+	"g.addV('person').property('id', 'ann').property('age', 30).property('name', 'Ann & Co').property(list, 'tags', 'a').property(list, 'tags', 'b')",
+	"g.addV('person').property('id', 'bob').property('active', true)",
+	"g.V('ann').addE('knows').to(g.V('bob')).property('id', 'e1').property('weight', 0.5)",
+	"g.V('bob').addE('works').to(g.V('acme')).property('id', 'e2')",
+}
+
+func TestExportGraphSON(t *testing.T) {
+	assert := assert.New(t)
+
+	g, m, done := newExportClient(t)
+	defer done()
+
+	var buf bytes.Buffer
+	counts, err := g.Export(context.Background(), &buf, ExportOptions{PageSize: 2})
+	assert.Nil(err)
+	assert.Equal(&ElementCounts{Vertices: 3, Edges: 2}, counts)
+	assert.Equal([]string{
+		"g.V().order().by(id).limit(2)",
+		"g.V().has(id, gt('ann')).order().by(id).limit(2)",
+		"g.E().order().by(id).limit(2)",
+		"g.E().has(id, gt('e2')).order().by(id).limit(2)",
+	}, m.take())
+
+	// an element per line, typed so the values keep their type
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(5, len(lines))
+	assert.Equal(`{"@type":"g:Vertex","@value":{"id":"bob","label":"person","properties":{"active":[{"@type":"g:VertexProperty","@value":{"id":"p5","label":"active","value":true}}]}}}`, lines[2])
+	assert.Equal(`{"@type":"g:Edge","@value":{"id":"e1","inV":"bob","inVLabel":"person","label":"knows","outV":"ann","outVLabel":"person","properties":{"weight":{"@type":"g:Property","@value":{"key":"weight","value":{"@type":"g:Double","@value":0.5}}}}}}`, lines[3])
+
+	counts, err = g.Import(context.Background(), &buf, ImportOptions{})
+	assert.Nil(err)
+	assert.Equal(&ElementCounts{Vertices: 3, Edges: 2}, counts)
+	assert.Equal(imported, m.take())
+
+	// ids are set with the id token for servers like TinkerGraph
+	_, err = g.Import(context.Background(), strings.NewReader(lines[2]), ImportOptions{IdToken: true})
+	assert.Nil(err)
+	assert.Equal([]string{"g.addV('person').property(id, 'bob').property('active', true)"}, m.take())
+
+	// edges between missing vertices fail with the element they are in
+	_, err = g.Import(context.Background(), strings.NewReader(`{"@type":"g:Edge","@value":{"id":"e3","label":"knows","inV":"bob","outV":"nobody"}}`), ImportOptions{})
+	assert.NotNil(err)
+	assert.True(strings.Contains(err.Error(), "element 1"))
+	_, err = g.Import(context.Background(), strings.NewReader("\n{"), ImportOptions{})
+	assert.NotNil(err)
+	assert.True(strings.Contains(err.Error(), "line 2"))
+}
+
+func TestExportFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	g, m, done := newExportClient(t)
+	defer done()
+
+	_, err := g.Export(context.Background(), &bytes.Buffer{}, ExportOptions{
+		VertexLabels:   []string{"person", "company"},
+		EdgeLabels:     []string{"knows"},
+		PartitionKey:   "tenant",
+		PartitionValue: "t1",
+	})
+	assert.Nil(err)
+	assert.Equal([]string{
+		"g.V().hasLabel('person', 'company').has('tenant', 't1').order().by(id).limit(1000)",
+		"g.E().hasLabel('knows').where(outV().hasLabel('person', 'company')).where(inV().hasLabel('person', 'company')).has('tenant', 't1').order().by(id).limit(1000)",
+	}, m.take())
+
+	_, err = g.Export(context.Background(), &bytes.Buffer{}, ExportOptions{Format: 3})
+	assert.NotNil(err)
+}
+
+func TestExportGraphML(t *testing.T) {
+	assert := assert.New(t)
+
+	g, m, done := newExportClient(t)
+	defer done()
+
+	// the elements are spooled to a temporary file that is removed afterwards
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	var buf bytes.Buffer
+	_, err := g.Export(context.Background(), &buf, ExportOptions{Format: ExportGraphML})
+	assert.Nil(err)
+	m.take()
+	spooled, err := os.ReadDir(tmp)
+	assert.Nil(err)
+	assert.Equal(0, len(spooled))
+	assert.Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+<key id="labelV" for="node" attr.name="labelV" attr.type="string"/>
+<key id="score" for="node" attr.name="score" attr.type="double"/>
+<key id="age" for="node" attr.name="age" attr.type="long"/>
+<key id="name" for="node" attr.name="name" attr.type="string"/>
+<key id="tags" for="node" attr.name="tags" attr.type="string"/>
+<key id="active" for="node" attr.name="active" attr.type="boolean"/>
+<key id="labelE" for="edge" attr.name="labelE" attr.type="string"/>
+<key id="weight" for="edge" attr.name="weight" attr.type="double"/>
+<graph id="G" edgedefault="directed">
+<node id="acme"><data key="labelV">company</data><data key="score">0.5</data></node>
+<node id="ann"><data key="labelV">person</data><data key="age">30</data><data key="name">Ann &amp; Co</data><data key="tags">a</data><data key="tags">b</data></node>
+<node id="bob"><data key="labelV">person</data><data key="active">true</data></node>
+<edge id="e1" source="ann" target="bob"><data key="labelE">knows</data><data key="weight">0.5</data></edge>
+<edge id="e2" source="bob" target="acme"><data key="labelE">works</data></edge>
+</graph>
+</graphml>
+`, buf.String())
+
+	counts, err := g.Import(context.Background(), &buf, ImportOptions{Format: ExportGraphML})
+	assert.Nil(err)
+	assert.Equal(&ElementCounts{Vertices: 3, Edges: 2}, counts)
+	assert.Equal(imported, m.take())
+
+	// node and edge keys with the same name get their own ids
+	w, err := newGraphMLWriter(&bytes.Buffer{})
+	assert.Nil(err)
+	defer w.release()
+	assert.Equal("name", w.key("node", "name", "a"))
+	assert.Equal("edge.name", w.key("edge", "name", int32(1)))
+	assert.Equal("int", w.keys[1].Type)
+
+	// keys with values of different types are widened to string
+	assert.Equal("age", w.key("node", "age", int64(30)))
+	assert.Equal("age", w.key("node", "age", int64(31)))
+	assert.Equal("long", w.keys[2].Type)
+	assert.Equal("age", w.key("node", "age", "unknown"))
+	assert.Equal("string", w.keys[2].Type)
+	assert.Equal("age", w.key("node", "age", int64(32)))
+	assert.Equal("string", w.keys[2].Type)
+	assert.Equal(int32(1), parseGraphML("int", "1"))
+	assert.Equal("x", parseGraphML("long", "x"))
+}
@@ -1,10 +1,16 @@
 module github.com/intwinelabs/gremgoser
 
+go 1.27.1
+
 require (
 	github.com/davecgh/go-spew v1.1.1
 	github.com/google/uuid v1.1.1
 	github.com/gorilla/websocket v1.4.0
 	github.com/intwinelabs/logger v0.0.0-20190213011727-75270f66be17
-	github.com/stretchr/objx v0.2.0 // indirect
 	github.com/stretchr/testify v1.3.0
 )
+
+require (
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/objx v0.2.0 // indirect
+)
@@ -0,0 +1,1074 @@
+package gremgoser
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/intwinelabs/gremgoser/traversal"
+)
+
+// graphBinary is the GraphBinary 1.0 serializer. Responses are decoded into the same Go values as GraphSON, Data
+// holds the objects of the result in the untyped GraphSON shape the struct helpers read.
+type graphBinary struct{}
+
+var graphBinaryV1 = &graphBinary{}
+
+// graphBinaryVersion starts every GraphBinary 1.0 request and response
+const graphBinaryVersion = 0x81
+
+// GraphBinary type codes
+const (
+	gbInt            byte = 0x01
+	gbLong           byte = 0x02
+	gbString         byte = 0x03
+	gbDate           byte = 0x04
+	gbTimestamp      byte = 0x05
+	gbClass          byte = 0x06
+	gbDouble         byte = 0x07
+	gbFloat          byte = 0x08
+	gbList           byte = 0x09
+	gbMap            byte = 0x0a
+	gbSet            byte = 0x0b
+	gbUUID           byte = 0x0c
+	gbEdge           byte = 0x0d
+	gbPath           byte = 0x0e
+	gbProperty       byte = 0x0f
+	gbVertex         byte = 0x11
+	gbVertexProperty byte = 0x12
+	gbBarrier        byte = 0x13
+	gbBytecode       byte = 0x15
+	gbCardinality    byte = 0x16
+	gbColumn         byte = 0x17
+	gbDirection      byte = 0x18
+	gbOperator       byte = 0x19
+	gbOrder          byte = 0x1a
+	gbPick           byte = 0x1b
+	gbPop            byte = 0x1c
+	gbP              byte = 0x1e
+	gbScope          byte = 0x1f
+	gbT              byte = 0x20
+	gbTraverser      byte = 0x21
+	gbBigDecimal     byte = 0x22
+	gbBigInteger     byte = 0x23
+	gbByte           byte = 0x24
+	gbByteBuffer     byte = 0x25
+	gbShort          byte = 0x26
+	gbBoolean        byte = 0x27
+	gbTextP          byte = 0x28
+	gbBulkSet        byte = 0x2a
+	gbDuration       byte = 0x81
+	gbNull           byte = 0xfe // gbNull is the type of an unspecified null
+)
+
+// GraphBinary value flags
+const (
+	gbValue    byte = 0x00
+	gbNullFlag byte = 0x01
+)
+
+// graphBinaryEnums are the type codes of the Gremlin enums
+var graphBinaryEnums = map[string]byte{
+	"Barrier":     gbBarrier,
+	"Cardinality": gbCardinality,
+	"Column":      gbColumn,
+	"Direction":   gbDirection,
+	"Operator":    gbOperator,
+	"Order":       gbOrder,
+	"Pick":        gbPick,
+	"Pop":         gbPop,
+	"Scope":       gbScope,
+	"T":           gbT,
+}
+
+// errGraphBinaryShort is returned when a GraphBinary message ends in the middle of a value
+var errGraphBinaryShort = errors.New("gremgoser: graphbinary message is too short")
+
+// errGraphBinaryBulk is returned when a bulk set expands to more than graphBinaryMaxBulk items
+var errGraphBinaryBulk = errors.New("gremgoser: graphbinary bulk set has too many items")
+
+// graphBinaryMaxBulk is the number of items a bulk set may expand to, the bulks are counts sent by the server and a
+// few bytes could ask for any number of items otherwise
+const graphBinaryMaxBulk = 1 << 20
+
+func (s *graphBinary) serializeRequest(req *GremlinRequest) ([]byte, error) {
+	w := &graphBinaryWriter{}
+	w.byte(byte(len(SerializerGraphBinary)))
+	w.bytes([]byte(SerializerGraphBinary))
+	w.byte(graphBinaryVersion)
+	w.uuid(req.RequestId)
+	w.string(req.Op)
+	w.string(req.Processor)
+	args := make(map[interface{}]interface{}, len(req.Args))
+	for k, v := range req.Args {
+		args[k] = v
+	}
+	if err := w.mapValue(args); err != nil {
+		return nil, err
+	}
+	return w.buf, nil
+}
+
+func (s *graphBinary) deserializeResponse(msg []byte) (*GremlinResponse, error) {
+	resp := &GremlinResponse{}
+	r := &graphBinaryReader{buf: msg}
+	version, err := r.byte()
+	if err != nil {
+		return resp, err
+	}
+	if version != graphBinaryVersion {
+		return resp, fmt.Errorf("gremgoser: unsupported graphbinary version 0x%x", version)
+	}
+	if flag, err := r.byte(); err != nil {
+		return resp, err
+	} else if flag&gbNullFlag == 0 {
+		if resp.RequestId, err = r.uuid(); err != nil {
+			return resp, err
+		}
+	}
+	code, err := r.int32()
+	if err != nil {
+		return resp, err
+	}
+	resp.Status.Code = int(code)
+	if flag, err := r.byte(); err != nil {
+		return resp, err
+	} else if flag&gbNullFlag == 0 {
+		if resp.Status.Message, err = r.string(); err != nil {
+			return resp, err
+		}
+	}
+	attrs, err := r.valueOf(gbMap)
+	if err != nil {
+		return resp, err
+	}
+	if err = setStatusAttributes(&resp.Status.Attributes, attrs); err != nil {
+		return resp, err
+	}
+	if resp.Result.Meta, err = r.valueOf(gbMap); err != nil {
+		return resp, err
+	}
+	data, err := r.value()
+	if err != nil {
+		return resp, err
+	}
+	setResultData(&resp.Result, data)
+
+	err = responseDetectError(resp.Status.Code)
+	if err != nil {
+		return resp, newGremlinError(resp, err)
+	}
+	return resp, nil
+}
+
+// setStatusAttributes fills the status attributes from a decoded map through their JSON decoding
+func setStatusAttributes(a *GremlinStatusAttributes, attrs interface{}) error {
+	m, ok := attrs.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	j, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return a.UnmarshalJSON(j)
+}
+
+// setResultData sets the values of a decoded result and the objects among them as Data
+func setResultData(r *GremlinResult, data interface{}) {
+	var items []interface{}
+	switch d := data.(type) {
+	case nil:
+		return
+	case []interface{}:
+		items = d
+	default:
+		items = []interface{}{d}
+	}
+	r.Values = expandTraversers(items)
+	for _, item := range r.Values {
+		r.appendData(item)
+	}
+}
+
+// untypedGraphSON renders decoded graph elements the way untyped GraphSON sends them, so Data looks the same
+// whatever the serializer is
+func untypedGraphSON(v interface{}) interface{} {
+	switch val := v.(type) {
+	case *Vertex:
+		props := make(map[string]interface{}, len(val.Properties))
+		for key, vps := range val.Properties {
+			list := make([]interface{}, len(vps))
+			for i, vp := range vps {
+				list[i] = untypedGraphSON(vp)
+			}
+			props[key] = list
+		}
+		return map[string]interface{}{"id": val.Id, "label": val.Label, "type": "vertex", "properties": props}
+	case *VertexProperty:
+		vp := map[string]interface{}{"id": val.Id, "label": val.Label, "value": untypedGraphSON(val.Value)}
+		if len(val.Properties) > 0 {
+			vp["properties"] = untypedGraphSON(val.Properties)
+		}
+		return vp
+	case *Edge:
+		props := make(map[string]interface{}, len(val.Properties))
+		for key, p := range val.Properties {
+			props[key] = untypedGraphSON(p.Value)
+		}
+		return map[string]interface{}{
+			"id":         val.Id,
+			"label":      val.Label,
+			"type":       "edge",
+			"inV":        val.InV,
+			"inVLabel":   val.InVLabel,
+			"outV":       val.OutV,
+			"outVLabel":  val.OutVLabel,
+			"properties": props,
+		}
+	case *Property:
+		return map[string]interface{}{"key": val.Key, "value": untypedGraphSON(val.Value)}
+	case *Path:
+		return map[string]interface{}{"labels": val.Labels, "objects": untypedGraphSON(val.Objects)}
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = untypedGraphSON(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = untypedGraphSON(item)
+		}
+		return list
+	}
+	return v
+}
+
+// graphBinaryWriter appends GraphBinary values to a buffer
+type graphBinaryWriter struct {
+	buf []byte
+}
+
+func (w *graphBinaryWriter) byte(b byte) {
+	w.buf = append(w.buf, b)
+}
+
+func (w *graphBinaryWriter) bytes(b []byte) {
+	w.buf = append(w.buf, b...)
+}
+
+func (w *graphBinaryWriter) int32(i int32) {
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(i))
+}
+
+func (w *graphBinaryWriter) int64(i int64) {
+	w.buf = binary.BigEndian.AppendUint64(w.buf, uint64(i))
+}
+
+func (w *graphBinaryWriter) string(s string) {
+	w.int32(int32(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *graphBinaryWriter) uuid(id uuid.UUID) {
+	w.buf = append(w.buf, id[:]...)
+}
+
+// typed writes the type code and value flag of a fully qualified value
+func (w *graphBinaryWriter) typed(code byte) {
+	w.buf = append(w.buf, code, gbValue)
+}
+
+func (w *graphBinaryWriter) null() {
+	w.buf = append(w.buf, gbNull, gbNullFlag)
+}
+
+// value writes a Go value as fully qualified GraphBinary value
+func (w *graphBinaryWriter) value(v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		w.null()
+	case bool:
+		w.typed(gbBoolean)
+		if val {
+			w.byte(1)
+		} else {
+			w.byte(0)
+		}
+	case string:
+		w.typed(gbString)
+		w.string(val)
+	case int8:
+		w.typed(gbByte)
+		w.byte(byte(val))
+	case int16:
+		w.typed(gbShort)
+		w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(val))
+	case int32:
+		w.typed(gbInt)
+		w.int32(val)
+	case uint8:
+		w.typed(gbInt)
+		w.int32(int32(val))
+	case uint16:
+		w.typed(gbInt)
+		w.int32(int32(val))
+	case int:
+		w.typed(gbLong)
+		w.int64(int64(val))
+	case int64:
+		w.typed(gbLong)
+		w.int64(val)
+	case uint:
+		return w.value(uint64(val))
+	case uint32:
+		w.typed(gbLong)
+		w.int64(int64(val))
+	case uint64:
+		if val > math.MaxInt64 {
+			return w.value(new(big.Int).SetUint64(val))
+		}
+		w.typed(gbLong)
+		w.int64(int64(val))
+	case float32:
+		w.typed(gbFloat)
+		w.buf = binary.BigEndian.AppendUint32(w.buf, math.Float32bits(val))
+	case float64:
+		w.typed(gbDouble)
+		w.int64(int64(math.Float64bits(val)))
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return w.value(i)
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return err
+		}
+		return w.value(f)
+	case *big.Int:
+		if val == nil {
+			w.null()
+			return nil
+		}
+		w.typed(gbBigInteger)
+		w.bigInt(val)
+	case uuid.UUID:
+		w.typed(gbUUID)
+		w.uuid(val)
+	case time.Time:
+		w.typed(gbDate)
+		w.int64(val.UnixNano() / int64(time.Millisecond))
+	case time.Duration:
+		w.typed(gbDuration)
+		w.int64(int64(val / time.Second))
+		w.int32(int32(val % time.Second))
+	case []byte:
+		w.typed(gbByteBuffer)
+		w.int32(int32(len(val)))
+		w.bytes(val)
+	case *Vertex:
+		w.typed(gbVertex)
+		if err := w.value(val.Id); err != nil {
+			return err
+		}
+		w.string(val.Label)
+		var props []interface{}
+		for _, key := range sortedKeys(val.Properties) {
+			for _, vp := range val.Properties[key] {
+				props = append(props, vp)
+			}
+		}
+		return w.elementProperties(props)
+	case *Edge:
+		w.typed(gbEdge)
+		if err := w.value(val.Id); err != nil {
+			return err
+		}
+		w.string(val.Label)
+		if err := w.value(val.InV); err != nil {
+			return err
+		}
+		w.string(val.InVLabel)
+		if err := w.value(val.OutV); err != nil {
+			return err
+		}
+		w.string(val.OutVLabel)
+		w.null() // parent
+		var props []interface{}
+		for _, key := range sortedKeys(val.Properties) {
+			props = append(props, val.Properties[key])
+		}
+		return w.elementProperties(props)
+	case *VertexProperty:
+		w.typed(gbVertexProperty)
+		if err := w.value(val.Id); err != nil {
+			return err
+		}
+		w.string(val.Label)
+		if err := w.value(val.Value); err != nil {
+			return err
+		}
+		w.null() // parent
+		var props []interface{}
+		for _, key := range sortedKeys(val.Properties) {
+			props = append(props, &Property{Key: key, Value: val.Properties[key]})
+		}
+		return w.elementProperties(props)
+	case *Property:
+		w.typed(gbProperty)
+		w.string(val.Key)
+		if err := w.value(val.Value); err != nil {
+			return err
+		}
+		w.null() // parent
+	case *Path:
+		labels := make([]interface{}, len(val.Labels))
+		for i, l := range val.Labels {
+			labels[i] = l
+		}
+		w.typed(gbPath)
+		w.typed(gbList)
+		if err := w.listValue(labels); err != nil {
+			return err
+		}
+		return w.value(val.Objects)
+	case *Traverser:
+		w.typed(gbTraverser)
+		w.int64(val.Bulk)
+		return w.value(val.Value)
+	case *traversal.Bytecode:
+		w.typed(gbBytecode)
+		w.int32(int32(len(val.Steps)))
+		for _, step := range val.Steps {
+			w.string(step.Name)
+			if err := w.listValue(step.Args); err != nil {
+				return err
+			}
+		}
+		w.int32(0) // sources
+	case traversal.P:
+		name, text := val.Predicate()
+		if text {
+			w.typed(gbTextP)
+		} else {
+			w.typed(gbP)
+		}
+		w.string(name)
+		return w.listValue(val.Values)
+	case traversal.Token:
+		enum, name := val.Enum()
+		code, ok := graphBinaryEnums[enum]
+		if !ok {
+			return fmt.Errorf("gremgoser: graphbinary has no enum %s", enum)
+		}
+		w.typed(code)
+		return w.value(name)
+	case map[string]interface{}:
+		if val == nil {
+			w.null()
+			return nil
+		}
+		m := make(map[interface{}]interface{}, len(val))
+		for k, item := range val {
+			m[k] = item
+		}
+		w.typed(gbMap)
+		return w.mapValue(m)
+	case []interface{}:
+		w.typed(gbList)
+		return w.listValue(val)
+	default:
+		return w.reflectValue(v)
+	}
+	return nil
+}
+
+// reflectValue writes pointers, slices and maps of other types
+func (w *graphBinaryWriter) reflectValue(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			w.null()
+			return nil
+		}
+		return w.value(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			w.null()
+			return nil
+		}
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = rv.Index(i).Interface()
+		}
+		w.typed(gbList)
+		return w.listValue(list)
+	case reflect.Map:
+		if rv.IsNil() {
+			w.null()
+			return nil
+		}
+		m := make(map[interface{}]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().Interface()] = iter.Value().Interface()
+		}
+		w.typed(gbMap)
+		return w.mapValue(m)
+	}
+	return fmt.Errorf("gremgoser: graphbinary cannot serialize %T", v)
+}
+
+// elementProperties writes the properties of an element as list, null when there are none
+func (w *graphBinaryWriter) elementProperties(props []interface{}) error {
+	if len(props) == 0 {
+		w.null()
+		return nil
+	}
+	w.typed(gbList)
+	return w.listValue(props)
+}
+
+// sortedKeys returns the keys of a string keyed map in order
+func sortedKeys(m interface{}) []string {
+	rv := reflect.ValueOf(m)
+	keys := make([]string, 0, rv.Len())
+	for _, k := range rv.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (w *graphBinaryWriter) listValue(list []interface{}) error {
+	w.int32(int32(len(list)))
+	for _, item := range list {
+		if err := w.value(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapValue writes the entries of a map sorted by key, which keeps the encoding stable
+func (w *graphBinaryWriter) mapValue(m map[interface{}]interface{}) error {
+	keys := make([]interface{}, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+	w.int32(int32(len(keys)))
+	for _, k := range keys {
+		if err := w.value(k); err != nil {
+			return err
+		}
+		if err := w.value(m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bigInt writes the two's complement bytes of an integer
+func (w *graphBinaryWriter) bigInt(i *big.Int) {
+	var b []byte
+	switch i.Sign() {
+	case 0:
+		b = []byte{0}
+	case 1:
+		b = i.Bytes()
+		if b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+	default:
+		// -i in two's complement is 2^n - |i| for the smallest n keeping the sign bit set
+		n := uint(i.BitLen()/8+1) * 8
+		b = new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), n), i).Bytes()
+	}
+	w.int32(int32(len(b)))
+	w.bytes(b)
+}
+
+// graphBinaryReader reads GraphBinary values from a message
+type graphBinaryReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *graphBinaryReader) take(n int) ([]byte, error) {
+	if n < 0 || len(r.buf)-r.pos < n {
+		return nil, errGraphBinaryShort
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *graphBinaryReader) byte() (byte, error) {
+	b, err := r.take(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *graphBinaryReader) int16() (int16, error) {
+	b, err := r.take(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *graphBinaryReader) int32() (int32, error) {
+	b, err := r.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *graphBinaryReader) int64() (int64, error) {
+	b, err := r.take(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *graphBinaryReader) string() (string, error) {
+	n, err := r.int32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.take(int(n))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (r *graphBinaryReader) uuid() (uuid.UUID, error) {
+	b, err := r.take(16)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.FromBytes(b)
+}
+
+func (r *graphBinaryReader) bigInt() (*big.Int, error) {
+	n, err := r.int32()
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.take(int(n))
+	if err != nil {
+		return nil, err
+	}
+	i := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		i.Sub(i, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	return i, nil
+}
+
+// value reads a fully qualified value
+func (r *graphBinaryReader) value() (interface{}, error) {
+	code, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	flag, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if flag&gbNullFlag != 0 {
+		return nil, nil
+	}
+	return r.valueOf(code)
+}
+
+// label reads a fully qualified string, the way enums and element labels are sent
+func (r *graphBinaryReader) label() (string, error) {
+	v, err := r.value()
+	if err != nil {
+		return "", err
+	}
+	s, _ := v.(string)
+	return s, nil
+}
+
+// valueOf reads the value of a type without type code and flag
+func (r *graphBinaryReader) valueOf(code byte) (interface{}, error) {
+	switch code {
+	case gbInt:
+		return r.int32()
+	case gbLong:
+		return r.int64()
+	case gbString, gbClass:
+		return r.string()
+	case gbDate, gbTimestamp:
+		ms, err := r.int64()
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(0, ms*int64(time.Millisecond)).UTC(), nil
+	case gbDouble:
+		i, err := r.int64()
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(uint64(i)), nil
+	case gbFloat:
+		i, err := r.int32()
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(uint32(i)), nil
+	case gbList, gbSet:
+		return r.list()
+	case gbMap:
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]interface{}, 0, n)
+		values := make([]interface{}, 0, n)
+		stringKeys := true
+		for i := 0; i < n; i++ {
+			key, err := r.value()
+			if err != nil {
+				return nil, err
+			}
+			value, err := r.value()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := key.(string); !ok {
+				stringKeys = false
+			}
+			keys = append(keys, key)
+			values = append(values, value)
+		}
+		return pairsMap(keys, values, stringKeys), nil
+	case gbUUID:
+		return r.uuid()
+	case gbEdge:
+		return r.edge()
+	case gbPath:
+		return r.path()
+	case gbProperty:
+		return r.property()
+	case gbVertex:
+		return r.vertex()
+	case gbVertexProperty:
+		return r.vertexProperty()
+	case gbBarrier, gbCardinality, gbColumn, gbDirection, gbOperator, gbOrder, gbPick, gbPop, gbScope, gbT:
+		return r.label()
+	case gbBytecode:
+		return r.bytecode()
+	case gbP, gbTextP:
+		name, err := r.string()
+		if err != nil {
+			return nil, err
+		}
+		values, err := r.list()
+		if err != nil {
+			return nil, err
+		}
+		if code == gbTextP {
+			name = "TextP." + name
+		}
+		return traversal.P{Operator: name, Values: values}, nil
+	case gbTraverser:
+		bulk, err := r.int64()
+		if err != nil {
+			return nil, err
+		}
+		value, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		return &Traverser{Bulk: bulk, Value: value}, nil
+	case gbBigDecimal:
+		scale, err := r.int32()
+		if err != nil {
+			return nil, err
+		}
+		unscaled, err := r.bigInt()
+		if err != nil {
+			return nil, err
+		}
+		d := new(big.Rat).SetInt(unscaled)
+		exp := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs32(scale))), nil))
+		if scale > 0 {
+			return json.Number(d.Quo(d, exp).FloatString(int(scale))), nil
+		}
+		return json.Number(d.Mul(d, exp).FloatString(0)), nil
+	case gbBigInteger:
+		i, err := r.bigInt()
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(i.String()), nil
+	case gbByte:
+		b, err := r.byte()
+		return int8(b), err
+	case gbByteBuffer:
+		n, err := r.int32()
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.take(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case gbShort:
+		return r.int16()
+	case gbBoolean:
+		b, err := r.byte()
+		return b != 0, err
+	case gbBulkSet:
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for i := 0; i < n; i++ {
+			item, err := r.value()
+			if err != nil {
+				return nil, err
+			}
+			bulk, err := r.int64()
+			if err != nil {
+				return nil, err
+			}
+			if bulk < 0 || bulk > int64(graphBinaryMaxBulk-len(list)) {
+				return nil, errGraphBinaryBulk
+			}
+			for j := int64(0); j < bulk; j++ {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	case gbDuration:
+		seconds, err := r.int64()
+		if err != nil {
+			return nil, err
+		}
+		nanos, err := r.int32()
+		if err != nil {
+			return nil, err
+		}
+		return time.Duration(seconds)*time.Second + time.Duration(nanos), nil
+	case gbNull:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("gremgoser: unsupported graphbinary type 0x%x", code)
+}
+
+// count reads the number of items of a list, set or map. Every item takes at least one byte, so a count beyond the
+// rest of the message is rejected before anything is allocated for it.
+func (r *graphBinaryReader) count() (int, error) {
+	n, err := r.int32()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || int(n) > len(r.buf)-r.pos {
+		return 0, errGraphBinaryShort
+	}
+	return int(n), nil
+}
+
+func (r *graphBinaryReader) list() ([]interface{}, error) {
+	n, err := r.count()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]interface{}, n)
+	for i := range list {
+		if list[i], err = r.value(); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// bytecode reads the steps of bytecode, source instructions are skipped
+func (r *graphBinaryReader) bytecode() (*traversal.Bytecode, error) {
+	b := &traversal.Bytecode{}
+	for part := 0; part < 2; part++ {
+		n, err := r.int32()
+		if err != nil {
+			return nil, err
+		}
+		for i := int32(0); i < n; i++ {
+			name, err := r.string()
+			if err != nil {
+				return nil, err
+			}
+			args, err := r.list()
+			if err != nil {
+				return nil, err
+			}
+			if part == 0 {
+				b.Steps = append(b.Steps, traversal.Step{Name: name, Args: args})
+			}
+		}
+	}
+	return b, nil
+}
+
+func (r *graphBinaryReader) vertex() (*Vertex, error) {
+	id, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	label, err := r.string()
+	if err != nil {
+		return nil, err
+	}
+	props, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	v := &Vertex{Id: id, Label: label}
+	if list, ok := props.([]interface{}); ok {
+		v.Properties = make(map[string][]*VertexProperty)
+		for _, item := range list {
+			if vp, ok := item.(*VertexProperty); ok {
+				v.Properties[vp.Label] = append(v.Properties[vp.Label], vp)
+			}
+		}
+	}
+	return v, nil
+}
+
+func (r *graphBinaryReader) vertexProperty() (*VertexProperty, error) {
+	id, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	label, err := r.string()
+	if err != nil {
+		return nil, err
+	}
+	value, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = r.value(); err != nil { // parent
+		return nil, err
+	}
+	props, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	vp := &VertexProperty{Id: id, Label: label, Value: value}
+	if list, ok := props.([]interface{}); ok {
+		vp.Properties = make(map[string]interface{}, len(list))
+		for _, item := range list {
+			if p, ok := item.(*Property); ok {
+				vp.Properties[p.Key] = p.Value
+			}
+		}
+	}
+	return vp, nil
+}
+
+func (r *graphBinaryReader) edge() (*Edge, error) {
+	e := &Edge{}
+	var err error
+	if e.Id, err = r.value(); err != nil {
+		return nil, err
+	}
+	if e.Label, err = r.string(); err != nil {
+		return nil, err
+	}
+	if e.InV, err = r.value(); err != nil {
+		return nil, err
+	}
+	if e.InVLabel, err = r.string(); err != nil {
+		return nil, err
+	}
+	if e.OutV, err = r.value(); err != nil {
+		return nil, err
+	}
+	if e.OutVLabel, err = r.string(); err != nil {
+		return nil, err
+	}
+	if _, err = r.value(); err != nil { // parent
+		return nil, err
+	}
+	props, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	if list, ok := props.([]interface{}); ok {
+		e.Properties = make(map[string]*Property, len(list))
+		for _, item := range list {
+			if p, ok := item.(*Property); ok {
+				e.Properties[p.Key] = p
+			}
+		}
+	}
+	return e, nil
+}
+
+func (r *graphBinaryReader) property() (*Property, error) {
+	key, err := r.string()
+	if err != nil {
+		return nil, err
+	}
+	value, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = r.value(); err != nil { // parent
+		return nil, err
+	}
+	return &Property{Key: key, Value: value}, nil
+}
+
+func (r *graphBinaryReader) path() (*Path, error) {
+	labels, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	p := &Path{}
+	p.Objects, _ = objects.([]interface{})
+	sets, _ := labels.([]interface{})
+	p.Labels = make([][]string, len(sets))
+	for i, set := range sets {
+		items, _ := set.([]interface{})
+		p.Labels[i] = make([]string, 0, len(items))
+		for _, item := range items {
+			if s, ok := item.(string); ok {
+				p.Labels[i] = append(p.Labels[i], s)
+			}
+		}
+	}
+	return p, nil
+}
+
+func abs32(i int32) int32 {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,281 @@
+package gremgoser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/intwinelabs/gremgoser/traversal"
+	"github.com/stretchr/testify/assert"
+)
+
+// graphBinaryResponse encodes a response the way Gremlin Server does
+func graphBinaryResponse(id uuid.UUID, code int32, message string, attrs map[string]interface{}, data interface{}) []byte {
+	w := &graphBinaryWriter{}
+	w.byte(graphBinaryVersion)
+	w.byte(gbValue)
+	w.uuid(id)
+	w.int32(code)
+	w.byte(gbValue)
+	w.string(message)
+	m := make(map[interface{}]interface{}, len(attrs))
+	for k, v := range attrs {
+		m[k] = v
+	}
+	w.mapValue(m)
+	w.mapValue(nil)
+	w.value(data)
+	return w.buf
+}
+
+// graphBinaryMock answers g.V() with a vertex and a count, and every other query with a script error
+func graphBinaryMock(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			mt, message, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			mimeType := append([]byte{byte(len(SerializerGraphBinary))}, SerializerGraphBinary...)
+			if !bytes.HasPrefix(message, mimeType) {
+				t.Errorf("unexpected mime type: %q", message)
+				return
+			}
+			req := &graphBinaryReader{buf: message[len(mimeType):]}
+			req.byte() // version
+			id, _ := req.uuid()
+			req.string() // op
+			req.string() // processor
+			args, err := req.valueOf(gbMap)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var resp []byte
+			switch args.(map[string]interface{})["gremlin"] {
+			case "g.V()":
+				resp = graphBinaryResponse(id, 200, "", map[string]interface{}{"x-ms-request-charge": 2.5}, []interface{}{
+					&Vertex{
+						Id:    uuid.MustParse("64795211-c4a1-4eac-9e0a-b674ced77461"),
+						Label: "test",
+						Properties: map[string][]*VertexProperty{
+							"a": {{Id: uuid.MustParse("4f9a2f8b-3b3a-4028-a1e0-fa55e0dd1543"), Label: "a", Value: "aa"}},
+							"b": {{Id: uuid.MustParse("96f7cacd-01fd-469e-a14c-5178903a39b6"), Label: "b", Value: int32(10)}},
+						},
+					},
+					int64(1),
+				})
+			default:
+				resp = graphBinaryResponse(id, 597, "script error", nil, nil)
+			}
+			if err := c.WriteMessage(mt, resp); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestGraphBinaryValues(t *testing.T) {
+	assert := assert.New(t)
+
+	id := uuid.MustParse("41d2e28a-20a4-4ab0-b379-d810dede3786")
+	date := time.Date(2019, 2, 13, 1, 17, 27, 0, time.UTC)
+	for _, tc := range []struct {
+		in  interface{}
+		out interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{"marko", "marko"},
+		{int8(-3), int8(-3)},
+		{int16(300), int16(300)},
+		{int32(-70000), int32(-70000)},
+		{uint8(200), int32(200)},
+		{7, int64(7)},
+		{json.Number("8"), int64(8)},
+		{float32(0.5), float32(0.5)},
+		{0.25, 0.25},
+		{id, id},
+		{date, date},
+		{90 * time.Second, 90 * time.Second},
+		{[]byte{1, 2}, []byte{1, 2}},
+		{big.NewInt(0), json.Number("0")},
+		{big.NewInt(128), json.Number("128")},
+		{big.NewInt(-128), json.Number("-128")},
+		{new(big.Int).Lsh(big.NewInt(-1), 70), json.Number("-1180591620717411303424")},
+		{uint64(1 << 63), json.Number("9223372036854775808")},
+		{[]string{"a", "b"}, []interface{}{"a", "b"}},
+		{map[string]interface{}{"a": 1}, map[string]interface{}{"a": int64(1)}},
+		{map[int]string{1: "a"}, map[interface{}]interface{}{int64(1): "a"}},
+		{&Traverser{Bulk: 3, Value: "a"}, &Traverser{Bulk: 3, Value: "a"}},
+		{
+			&Edge{Id: int64(1), Label: "knows", InV: "a", InVLabel: "person", OutV: "b", OutVLabel: "person", Properties: map[string]*Property{"since": {Key: "since", Value: int32(2009)}}},
+			&Edge{Id: int64(1), Label: "knows", InV: "a", InVLabel: "person", OutV: "b", OutVLabel: "person", Properties: map[string]*Property{"since": {Key: "since", Value: int32(2009)}}},
+		},
+		{
+			&Vertex{Id: "a", Label: "person", Properties: map[string][]*VertexProperty{"name": {{Id: int64(1), Label: "name", Value: "marko", Properties: map[string]interface{}{"since": int32(1997)}}}}},
+			&Vertex{Id: "a", Label: "person", Properties: map[string][]*VertexProperty{"name": {{Id: int64(1), Label: "name", Value: "marko", Properties: map[string]interface{}{"since": int32(1997)}}}}},
+		},
+		{traversal.TId, "id"},
+		{traversal.Decr, "decr"},
+		{traversal.Within(1, "a"), traversal.Within(int64(1), "a")},
+		{traversal.TextContains("a"), traversal.TextContains("a")},
+		{
+			traversal.G().V().Where(traversal.Anon().Out("knows")).Bytecode(),
+			&traversal.Bytecode{Steps: []traversal.Step{{Name: "V", Args: []interface{}{}}, {Name: "where", Args: []interface{}{&traversal.Bytecode{Steps: []traversal.Step{{Name: "out", Args: []interface{}{"knows"}}}}}}}},
+		},
+		{
+			&Path{Labels: [][]string{{"a"}, {}}, Objects: []interface{}{int32(1), "lop"}},
+			&Path{Labels: [][]string{{"a"}, {}}, Objects: []interface{}{int32(1), "lop"}},
+		},
+	} {
+		w := &graphBinaryWriter{}
+		assert.Nil(w.value(tc.in))
+		r := &graphBinaryReader{buf: w.buf}
+		out, err := r.value()
+		assert.Nil(err)
+		assert.Equal(tc.out, out, "%#v", tc.in)
+		assert.Equal(len(w.buf), r.pos)
+	}
+
+	// a bulk set is expanded
+	r := &graphBinaryReader{buf: []byte{gbBulkSet, gbValue, 0, 0, 0, 1, gbString, gbValue, 0, 0, 0, 1, 'a', 0, 0, 0, 0, 0, 0, 0, 2}}
+	out, err := r.value()
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a", "a"}, out)
+
+	// enums are sent as strings
+	r = &graphBinaryReader{buf: []byte{gbT, gbValue, gbString, gbValue, 0, 0, 0, 2, 'i', 'd'}}
+	out, err = r.value()
+	assert.Nil(err)
+	assert.Equal("id", out)
+
+	// errors
+	assert.NotNil((&graphBinaryWriter{}).value(struct{}{}))
+	_, err = (&graphBinaryReader{buf: []byte{gbString, gbValue, 0, 0, 0, 5, 'a'}}).value()
+	assert.Equal(errGraphBinaryShort, err)
+	_, err = (&graphBinaryReader{buf: []byte{0x7f, gbValue}}).value()
+	assert.NotNil(err)
+
+	// counts and bulks are checked before anything is allocated for them
+	for _, code := range []byte{gbList, gbSet, gbMap, gbBulkSet} {
+		for _, n := range [][]byte{{0xff, 0xff, 0xff, 0xff}, {0x7f, 0xff, 0xff, 0xff}} {
+			_, err = (&graphBinaryReader{buf: append([]byte{code, gbValue}, n...)}).value()
+			assert.Equal(errGraphBinaryShort, err, "0x%x % x", code, n)
+		}
+	}
+	for _, bulk := range [][]byte{{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, {0, 0, 0, 0, 0x7f, 0xff, 0xff, 0xff}} {
+		_, err = (&graphBinaryReader{buf: append([]byte{gbBulkSet, gbValue, 0, 0, 0, 1, gbString, gbValue, 0, 0, 0, 1, 'a'}, bulk...)}).value()
+		assert.Equal(errGraphBinaryBulk, err, "% x", bulk)
+	}
+}
+
+func TestGraphBinaryRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	req := prepareRequest("g.V(x)", map[string]interface{}{"x": 1}, nil)
+	msg, err := graphBinaryV1.serializeRequest(req)
+	assert.Nil(err)
+	assert.True(bytes.HasPrefix(msg, []byte("\x20application/vnd.graphbinary-v1.0\x81")))
+
+	r := &graphBinaryReader{buf: msg[len(SerializerGraphBinary)+2:]}
+	id, err := r.uuid()
+	assert.Nil(err)
+	assert.Equal(req.RequestId, id)
+	op, _ := r.string()
+	assert.Equal("eval", op)
+	processor, _ := r.string()
+	assert.Equal("", processor)
+	args, err := r.valueOf(gbMap)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{
+		"bindings":   map[string]interface{}{"x": int64(1)},
+		"gremlin":    "g.V(x)",
+		"language":   "gremlin-groovy",
+		"rebindings": nil,
+	}, args)
+	assert.Equal(len(r.buf), r.pos)
+}
+
+func TestGraphBinaryResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	id := uuid.New()
+	resp, err := graphBinaryV1.deserializeResponse(graphBinaryResponse(id, 206, "", map[string]interface{}{"x-ms-request-charge": 1.5}, []interface{}{
+		&Edge{Id: "e", Label: "knows", InV: "a", OutV: "b", Properties: map[string]*Property{"since": {Key: "since", Value: int32(2009)}}},
+		"a",
+		nil,
+	}))
+	assert.Nil(err)
+	assert.Equal(id, resp.RequestId)
+	assert.Equal(206, resp.Status.Code)
+	assert.Equal(float32(1.5), resp.Status.Attributes.XMsRequestCharge)
+	assert.Equal(3, len(resp.Result.Values))
+	assert.Equal(2, len(resp.Result.Data))
+	assert.Equal("edge", (*resp.Result.Data[0])["type"])
+	assert.Equal(map[string]interface{}{"since": int32(2009)}, (*resp.Result.Data[0])["properties"])
+	assert.Nil(resp.Result.Data[1])
+
+	resp, err = graphBinaryV1.deserializeResponse(graphBinaryResponse(id, 597, "script error", nil, nil))
+	assert.True(errors.Is(err, Error597ScriptEvaluationError))
+	assert.Equal("script error", resp.Status.Message)
+
+	_, err = graphBinaryV1.deserializeResponse([]byte{0x01})
+	assert.NotNil(err)
+	_, err = graphBinaryV1.deserializeResponse([]byte{graphBinaryVersion, gbValue})
+	assert.Equal(errGraphBinaryShort, err)
+}
+
+func TestExecuteGraphBinary(t *testing.T) {
+	assert := assert.New(t)
+
+	s := httptest.NewServer(graphBinaryMock(t))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conf := NewClientConfig(u)
+	conf.SetSerializer(SerializerGraphBinary)
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	data, err := g.Execute("g.V()", nil, nil)
+	assert.Nil(err)
+	assert.Equal(1, len(data))
+	assert.Equal("vertex", (*data[0])["type"])
+
+	values, err := g.ExecuteValues("g.V()", nil, nil)
+	assert.Nil(err)
+	assert.Equal(2, len(values))
+	assert.Equal("aa", values[0].(*Vertex).Properties["a"][0].Value)
+	assert.Equal(int64(1), values[1])
+
+	// the struct helpers read the same data as with GraphSON
+	var tests []Test2
+	err = g.Get("g.V()", nil, &tests)
+	assert.Nil(err)
+	assert.Equal([]Test2{{Id: uuid.MustParse("64795211-c4a1-4eac-9e0a-b674ced77461"), A: "aa", B: 10}}, tests)
+
+	_, err = g.Execute("g.E()", nil, nil)
+	assert.True(errors.Is(err, Error597ScriptEvaluationError))
+}
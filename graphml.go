@@ -0,0 +1,264 @@
+package gremgoser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// graphMLWriter writes GraphML. The keys of the properties have to be declared before the elements, so the
+// elements are spooled to a temporary file and copied after the key declarations once the export is done. Only
+// the keys are held in memory.
+type graphMLWriter struct {
+	w    io.Writer
+	file *os.File
+	body *bufio.Writer
+	keys []graphMLKey
+	ids  map[string]int // index of the keys by their for and name
+}
+
+// graphMLKey is the declaration of a property key
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+func newGraphMLWriter(w io.Writer) (*graphMLWriter, error) {
+	f, err := os.CreateTemp("", "gremgoser-graphml-*")
+	if err != nil {
+		return nil, err
+	}
+	return &graphMLWriter{w: w, file: f, body: bufio.NewWriter(f), ids: make(map[string]int)}, nil
+}
+
+// key returns the id of the key of a property, declaring it with the type of the value on first use. A key whose
+// values turn out to have different types is widened to string, which holds any of them, the keys are only
+// written once the export is done. Node and edge keys with the same name get different ids.
+func (w *graphMLWriter) key(elem, name string, value interface{}) string {
+	if i, ok := w.ids[elem+"."+name]; ok {
+		if w.keys[i].Type != graphMLType(value) {
+			w.keys[i].Type = "string"
+		}
+		return w.keys[i].ID
+	}
+	id := name
+	for _, k := range w.keys {
+		if k.ID == id {
+			id = elem + "." + name
+			break
+		}
+	}
+	w.ids[elem+"."+name] = len(w.keys)
+	w.keys = append(w.keys, graphMLKey{ID: id, For: elem, Name: name, Type: graphMLType(value)})
+	return id
+}
+
+// graphMLType returns the GraphML type of a property value, values of other types are written as strings
+func graphMLType(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return "boolean"
+	case int8, int16, int32:
+		return "int"
+	case int, int64, uint8, uint16, uint32:
+		return "long"
+	case float32:
+		return "float"
+	case float64:
+		return "double"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "long"
+		}
+		return "double"
+	}
+	return "string"
+}
+
+// graphMLValue returns the text of a property value
+func graphMLValue(value interface{}) string {
+	if t, ok := value.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+func (w *graphMLWriter) data(elem, name string, value interface{}) {
+	fmt.Fprintf(w.body, `<data key="%s">`, escapeXML(w.key(elem, name, value)))
+	xml.EscapeText(w.body, []byte(graphMLValue(value)))
+	w.body.WriteString("</data>")
+}
+
+func (w *graphMLWriter) vertex(v *Vertex) error {
+	fmt.Fprintf(w.body, `<node id="%s">`, escapeXML(graphMLValue(v.Id)))
+	w.data("node", "labelV", v.Label)
+	for _, key := range sortedKeys(v.Properties) {
+		// GraphML has a single value per key, list properties repeat the key
+		for _, p := range v.Properties[key] {
+			w.data("node", key, p.Value)
+		}
+	}
+	w.body.WriteString("</node>\n")
+	return nil
+}
+
+func (w *graphMLWriter) edge(e *Edge) error {
+	fmt.Fprintf(w.body, `<edge id="%s" source="%s" target="%s">`, escapeXML(graphMLValue(e.Id)), escapeXML(graphMLValue(e.OutV)), escapeXML(graphMLValue(e.InV)))
+	w.data("edge", "labelE", e.Label)
+	for _, key := range sortedKeys(e.Properties) {
+		w.data("edge", key, e.Properties[key].Value)
+	}
+	w.body.WriteString("</edge>\n")
+	return nil
+}
+
+func (w *graphMLWriter) close() error {
+	if err := w.body.Flush(); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	var head bytes.Buffer
+	head.WriteString(xml.Header)
+	head.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	for _, k := range w.keys {
+		fmt.Fprintf(&head, `<key id="%s" for="%s" attr.name="%s" attr.type="%s"/>`+"\n", escapeXML(k.ID), k.For, escapeXML(k.Name), k.Type)
+	}
+	head.WriteString(`<graph id="G" edgedefault="directed">` + "\n")
+	if _, err := head.WriteTo(w.w); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w.w, w.file); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.w, "</graph>\n</graphml>\n")
+	return err
+}
+
+// release removes the temporary file
+func (w *graphMLWriter) release() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+func escapeXML(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// graphMLElement is a node or an edge of a GraphML file
+type graphMLElement struct {
+	ID     string `xml:"id,attr"`
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+	Data   []struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"data"`
+}
+
+// readGraphML decodes the nodes and edges of a GraphML file as *Vertex and *Edge, the labels are read from the
+// labelV and labelE keys and the property values are parsed by the types of their keys. Ids are strings.
+func readGraphML(r io.Reader, fn func(int, interface{}) error) error {
+	decoder := xml.NewDecoder(r)
+	keys := make(map[string]graphMLKey)
+	n := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "key":
+			var k graphMLKey
+			if err := decoder.DecodeElement(&k, &start); err != nil {
+				return err
+			}
+			keys[k.ID] = k
+		case "node", "edge":
+			var e graphMLElement
+			if err := decoder.DecodeElement(&e, &start); err != nil {
+				return err
+			}
+			n++
+			var elem interface{}
+			if start.Name.Local == "node" {
+				v := &Vertex{Id: e.ID, Label: "vertex", Properties: make(map[string][]*VertexProperty)}
+				for _, d := range e.Data {
+					k := graphMLKeyOf(keys, d.Key)
+					if k.Name == "labelV" {
+						v.Label = d.Value
+						continue
+					}
+					v.Properties[k.Name] = append(v.Properties[k.Name], &VertexProperty{Label: k.Name, Value: parseGraphML(k.Type, d.Value)})
+				}
+				elem = v
+			} else {
+				edge := &Edge{Id: e.ID, Label: "edge", OutV: e.Source, InV: e.Target, Properties: make(map[string]*Property)}
+				for _, d := range e.Data {
+					k := graphMLKeyOf(keys, d.Key)
+					if k.Name == "labelE" {
+						edge.Label = d.Value
+						continue
+					}
+					edge.Properties[k.Name] = &Property{Key: k.Name, Value: parseGraphML(k.Type, d.Value)}
+				}
+				elem = edge
+			}
+			if err := fn(n, elem); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// graphMLKeyOf returns the declaration of a key, undeclared keys are string properties named by their id
+func graphMLKeyOf(keys map[string]graphMLKey, id string) graphMLKey {
+	if k, ok := keys[id]; ok {
+		return k
+	}
+	return graphMLKey{ID: id, Name: id, Type: "string"}
+}
+
+// parseGraphML parses the text of a value by the type of its key, text that does not parse stays a string
+func parseGraphML(typ, text string) interface{} {
+	switch typ {
+	case "boolean":
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+	case "int":
+		if i, err := strconv.ParseInt(text, 10, 32); err == nil {
+			return int32(i)
+		}
+	case "long":
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(text, 32); err == nil {
+			return float32(f)
+		}
+	case "double":
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+	return text
+}
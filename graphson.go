@@ -0,0 +1,535 @@
+package gremgoser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/intwinelabs/gremgoser/traversal"
+)
+
+// decodeGraphSON turns the typed values of GraphSON v2 and v3, like {"@type":"g:Int64","@value":5}, into Go
+// values. Untyped values are returned as they are, numbers stay json.Number. Maps whose keys are all strings
+// become map[string]interface{}, other GraphSON v3 maps become map[interface{}]interface{}.
+func decodeGraphSON(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if typ, value, ok := graphSONType(val); ok {
+			return decodeGraphSONType(typ, value)
+		}
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			decoded, err := decodeGraphSON(item)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = decoded
+		}
+		return m, nil
+	case []interface{}:
+		return decodeGraphSONList(val)
+	default:
+		return v, nil
+	}
+}
+
+// graphSONType returns the type and value of a typed GraphSON value
+func graphSONType(m map[string]interface{}) (string, interface{}, bool) {
+	if len(m) != 2 {
+		return "", nil, false
+	}
+	typ, ok := m["@type"].(string)
+	if !ok {
+		return "", nil, false
+	}
+	value, ok := m["@value"]
+	return typ, value, ok
+}
+
+func decodeGraphSONList(items []interface{}) ([]interface{}, error) {
+	list := make([]interface{}, len(items))
+	for i, item := range items {
+		decoded, err := decodeGraphSON(item)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = decoded
+	}
+	return list, nil
+}
+
+func decodeGraphSONType(typ string, value interface{}) (interface{}, error) {
+	switch typ {
+	case "g:Int32":
+		n, err := graphSONInt(value, 32)
+		return int32(n), err
+	case "g:Int64":
+		return graphSONInt(value, 64)
+	case "gx:Int16":
+		n, err := graphSONInt(value, 16)
+		return int16(n), err
+	case "gx:Byte":
+		n, err := graphSONInt(value, 8)
+		return int8(n), err
+	case "g:Float":
+		f, err := graphSONFloat(value)
+		return float32(f), err
+	case "g:Double":
+		return graphSONFloat(value)
+	case "gx:BigInteger", "gx:BigDecimal":
+		return json.Number(fmt.Sprintf("%v", value)), nil
+	case "g:Date", "g:Timestamp":
+		ms, err := graphSONInt(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(0, ms*int64(time.Millisecond)).UTC(), nil
+	case "g:UUID":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("gremgoser: graphson %s is not a string: %v", typ, value)
+		}
+		return uuid.Parse(s)
+	case "g:T", "g:Direction", "g:Class", "g:Cardinality", "g:Column", "g:Order", "g:Pop", "g:Scope":
+		return value, nil
+	case "g:List", "g:Set":
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("gremgoser: graphson %s is not a list: %v", typ, value)
+		}
+		return decodeGraphSONList(items)
+	case "g:BulkSet": // value and bulk alternate
+		items, ok := value.([]interface{})
+		if !ok || len(items)%2 != 0 {
+			return nil, fmt.Errorf("gremgoser: graphson %s is not a list of pairs: %v", typ, value)
+		}
+		list := []interface{}{}
+		for i := 0; i < len(items); i += 2 {
+			item, err := decodeGraphSON(items[i])
+			if err != nil {
+				return nil, err
+			}
+			count, err := decodeGraphSON(items[i+1])
+			if err != nil {
+				return nil, err
+			}
+			bulk, err := graphSONInt(count, 64)
+			if err != nil {
+				return nil, err
+			}
+			for j := int64(0); j < bulk; j++ {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	case "g:Map": // keys and values alternate
+		items, ok := value.([]interface{})
+		if !ok || len(items)%2 != 0 {
+			return nil, fmt.Errorf("gremgoser: graphson %s is not a list of pairs: %v", typ, value)
+		}
+		return decodeGraphSONMap(items)
+	case "g:Traverser":
+		m, err := graphSONObject(typ, value)
+		if err != nil {
+			return nil, err
+		}
+		bulk, err := graphSONInt(m["bulk"], 64)
+		if err != nil {
+			return nil, err
+		}
+		return &Traverser{Bulk: bulk, Value: m["value"]}, nil
+	case "g:Vertex":
+		m, err := graphSONObject(typ, value)
+		if err != nil {
+			return nil, err
+		}
+		return graphSONVertex(m), nil
+	case "g:Edge":
+		m, err := graphSONObject(typ, value)
+		if err != nil {
+			return nil, err
+		}
+		return graphSONEdge(m), nil
+	case "g:VertexProperty":
+		m, err := graphSONObject(typ, value)
+		if err != nil {
+			return nil, err
+		}
+		return graphSONVertexProperty(m), nil
+	case "g:Property":
+		m, err := graphSONObject(typ, value)
+		if err != nil {
+			return nil, err
+		}
+		key, _ := m["key"].(string)
+		return &Property{Key: key, Value: m["value"]}, nil
+	case "g:Path":
+		m, err := graphSONObject(typ, value)
+		if err != nil {
+			return nil, err
+		}
+		path := &Path{}
+		labels, _ := m["labels"].([]interface{})
+		for _, l := range labels {
+			set, _ := l.([]interface{})
+			stepLabels := make([]string, 0, len(set))
+			for _, label := range set {
+				if s, ok := label.(string); ok {
+					stepLabels = append(stepLabels, s)
+				}
+			}
+			path.Labels = append(path.Labels, stepLabels)
+		}
+		path.Objects, _ = m["objects"].([]interface{})
+		return path, nil
+	default: // unknown types keep their decoded value
+		return decodeGraphSON(value)
+	}
+}
+
+// graphSONObject decodes the value of an element type like g:Vertex, which is a JSON object
+func graphSONObject(typ string, value interface{}) (map[string]interface{}, error) {
+	decoded, err := decodeGraphSON(value)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := decoded.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("gremgoser: graphson %s is not an object: %v", typ, value)
+	}
+	return m, nil
+}
+
+func decodeGraphSONMap(items []interface{}) (interface{}, error) {
+	keys := make([]interface{}, 0, len(items)/2)
+	values := make([]interface{}, 0, len(items)/2)
+	stringKeys := true
+	for i := 0; i < len(items); i += 2 {
+		key, err := decodeGraphSON(items[i])
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeGraphSON(items[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := key.(string); !ok {
+			stringKeys = false
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return pairsMap(keys, values, stringKeys), nil
+}
+
+// pairsMap builds the map of decoded keys and values, a map[string]interface{} when all keys are strings
+func pairsMap(keys, values []interface{}, stringKeys bool) interface{} {
+	if stringKeys {
+		m := make(map[string]interface{}, len(keys))
+		for i, key := range keys {
+			m[key.(string)] = values[i]
+		}
+		return m
+	}
+	m := make(map[interface{}]interface{}, len(keys))
+	for i, key := range keys {
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			key = fmt.Sprintf("%v", key) // lists and maps cannot be map keys in Go
+		}
+		m[key] = values[i]
+	}
+	return m
+}
+
+func graphSONVertex(m map[string]interface{}) *Vertex {
+	v := &Vertex{Id: m["id"]}
+	v.Label, _ = m["label"].(string)
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		v.Properties = make(map[string][]*VertexProperty, len(props))
+		for key, list := range props {
+			items, _ := list.([]interface{})
+			for _, item := range items {
+				switch p := item.(type) {
+				case *VertexProperty:
+					v.Properties[key] = append(v.Properties[key], p)
+				case map[string]interface{}: // untyped vertex properties
+					v.Properties[key] = append(v.Properties[key], graphSONVertexProperty(p))
+				}
+			}
+		}
+	}
+	return v
+}
+
+func graphSONVertexProperty(m map[string]interface{}) *VertexProperty {
+	p := &VertexProperty{Id: m["id"], Value: m["value"]}
+	p.Label, _ = m["label"].(string)
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		p.Properties = make(map[string]interface{}, len(props))
+		for key, value := range props {
+			if prop, ok := value.(*Property); ok {
+				value = prop.Value
+			}
+			p.Properties[key] = value
+		}
+	}
+	return p
+}
+
+func graphSONEdge(m map[string]interface{}) *Edge {
+	e := &Edge{Id: m["id"], InV: m["inV"], OutV: m["outV"]}
+	e.Label, _ = m["label"].(string)
+	e.InVLabel, _ = m["inVLabel"].(string)
+	e.OutVLabel, _ = m["outVLabel"].(string)
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		e.Properties = make(map[string]*Property, len(props))
+		for key, value := range props {
+			if prop, ok := value.(*Property); ok {
+				e.Properties[key] = prop
+			} else {
+				e.Properties[key] = &Property{Key: key, Value: value}
+			}
+		}
+	}
+	return e
+}
+
+func graphSONInt(value interface{}, bits int) (int64, error) {
+	switch n := value.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil {
+			return 0, err
+		}
+		if bits < 64 && (i < -1<<(bits-1) || i > 1<<(bits-1)-1) {
+			return 0, fmt.Errorf("gremgoser: graphson value %d overflows int%d", i, bits)
+		}
+		return i, nil
+	case float64:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case int32:
+		return int64(n), nil
+	}
+	return 0, fmt.Errorf("gremgoser: graphson value is not an integer: %v", value)
+}
+
+func graphSONFloat(value interface{}) (float64, error) {
+	switch n := value.(type) {
+	case json.Number:
+		return n.Float64()
+	case float64:
+		return n, nil
+	case string: // special values are sent as strings
+		switch n {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+	}
+	return 0, fmt.Errorf("gremgoser: graphson value is not a float: %v", value)
+}
+
+// MarshalGraphSON returns the GraphSON 3 encoding of a result value, like the values of ExecuteValues. Untyped
+// vertices and edges are encoded as typed ones.
+func MarshalGraphSON(v interface{}) ([]byte, error) {
+	return json.Marshal(graphSONv3.encode(DecodeElement(v)))
+}
+
+// DecodeElement returns untyped vertices and edges, like GraphSON v2 responses of Cosmos DB hold them, as *Vertex
+// and *Edge. Other values are returned as they are.
+func DecodeElement(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	switch m["type"] {
+	case "vertex":
+		return graphSONVertex(m)
+	case "edge":
+		return graphSONEdge(m)
+	}
+	return v
+}
+
+// encode turns Go values into typed GraphSON values. GraphSON v2 has no list and map types, those stay JSON arrays
+// and objects.
+func (s *graphSON) encode(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil, string, bool:
+		return val
+	case int8, int16, int32:
+		return typedGraphSON("g:Int32", val)
+	case int:
+		return typedGraphSON("g:Int64", val)
+	case int64:
+		return typedGraphSON("g:Int64", val)
+	case uint8, uint16:
+		return typedGraphSON("g:Int32", val)
+	case uint, uint32, uint64:
+		return typedGraphSON("g:Int64", val)
+	case float32:
+		return typedGraphSON("g:Float", val)
+	case float64:
+		return typedGraphSON("g:Double", val)
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return typedGraphSON("g:Int64", i)
+		}
+		return typedGraphSON("g:Double", val)
+	case uuid.UUID:
+		return typedGraphSON("g:UUID", val.String())
+	case time.Time:
+		return typedGraphSON("g:Date", val.UnixNano()/int64(time.Millisecond))
+	case *Vertex:
+		props := make(map[string]interface{}, len(val.Properties))
+		for key, list := range val.Properties {
+			items := make([]interface{}, len(list))
+			for i, p := range list {
+				if p.Label == "" { // untyped vertex properties have no label
+					labeled := *p
+					labeled.Label = key
+					p = &labeled
+				}
+				items[i] = s.encode(p)
+			}
+			props[key] = items
+		}
+		return typedGraphSON("g:Vertex", map[string]interface{}{"id": s.encode(val.Id), "label": val.Label, "properties": props})
+	case *VertexProperty:
+		value := map[string]interface{}{"id": s.encode(val.Id), "label": val.Label, "value": s.encode(val.Value)}
+		if len(val.Properties) > 0 {
+			meta := make(map[string]interface{}, len(val.Properties))
+			for key, m := range val.Properties {
+				meta[key] = s.encode(m)
+			}
+			value["properties"] = meta
+		}
+		return typedGraphSON("g:VertexProperty", value)
+	case *Edge:
+		props := make(map[string]interface{}, len(val.Properties))
+		for key, p := range val.Properties {
+			props[key] = s.encode(p)
+		}
+		return typedGraphSON("g:Edge", map[string]interface{}{
+			"id":         s.encode(val.Id),
+			"label":      val.Label,
+			"inV":        s.encode(val.InV),
+			"inVLabel":   val.InVLabel,
+			"outV":       s.encode(val.OutV),
+			"outVLabel":  val.OutVLabel,
+			"properties": props,
+		})
+	case *Property:
+		return typedGraphSON("g:Property", map[string]interface{}{"key": val.Key, "value": s.encode(val.Value)})
+	case *traversal.Bytecode:
+		steps := make([]interface{}, len(val.Steps))
+		for i, step := range val.Steps {
+			instruction := []interface{}{step.Name}
+			for _, arg := range step.Args {
+				instruction = append(instruction, s.encode(arg))
+			}
+			steps[i] = instruction
+		}
+		return typedGraphSON("g:Bytecode", map[string]interface{}{"step": steps})
+	case traversal.P:
+		name, text := val.Predicate()
+		typ := "g:P"
+		if text {
+			typ = "g:TextP"
+		}
+		var value interface{}
+		switch name {
+		case "within", "without", "between", "inside", "outside", "and", "or":
+			value = s.encode(val.Values)
+		default:
+			if len(val.Values) > 0 {
+				value = s.encode(val.Values[0])
+			}
+		}
+		return typedGraphSON(typ, map[string]interface{}{"predicate": name, "value": value})
+	case traversal.Token:
+		enum, name := val.Enum()
+		return typedGraphSON("g:"+enum, name)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // keeps the encoding stable
+		if !s.typed {
+			m := make(map[string]interface{}, len(val))
+			for _, k := range keys {
+				m[k] = s.encode(val[k])
+			}
+			return m
+		}
+		pairs := make([]interface{}, 0, 2*len(val))
+		for _, k := range keys {
+			pairs = append(pairs, k, s.encode(val[k]))
+		}
+		return typedGraphSON("g:Map", pairs)
+	case map[string]string:
+		m := make(map[string]interface{}, len(val))
+		for k, str := range val {
+			m[k] = str
+		}
+		return s.encode(m)
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = s.encode(item)
+		}
+		return s.list(list)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return s.encode(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = s.encode(rv.Index(i).Interface())
+		}
+		return s.list(list)
+	case reflect.Map:
+		if !s.typed {
+			m := make(map[string]interface{}, rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				m[fmt.Sprint(iter.Key().Interface())] = s.encode(iter.Value().Interface())
+			}
+			return m
+		}
+		pairs := make([]interface{}, 0, 2*rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			pairs = append(pairs, s.encode(iter.Key().Interface()), s.encode(iter.Value().Interface()))
+		}
+		return typedGraphSON("g:Map", pairs)
+	}
+	return v
+}
+
+// list returns a g:List in GraphSON v3 and the plain list in GraphSON v2
+func (s *graphSON) list(list []interface{}) interface{} {
+	if !s.typed {
+		return list
+	}
+	return typedGraphSON("g:List", list)
+}
+
+func typedGraphSON(typ string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{"@type": typ, "@value": value}
+}
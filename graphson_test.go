@@ -0,0 +1,140 @@
+package gremgoser
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeTestGraphSON decodes a GraphSON document like the response decoder does
+func decodeTestGraphSON(t *testing.T, doc string) interface{} {
+	var raw interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(doc)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		t.Fatal(err)
+	}
+	v, err := decodeGraphSON(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+var testGraphSONVertex = `{"@type":"g:Vertex","@value":{"id":{"@type":"g:Int64","@value":1},"label":"person","properties":{"name":[{"@type":"g:VertexProperty","@value":{"id":{"@type":"g:Int64","@value":0},"value":"marko","label":"name"}}],"location":[{"@type":"g:VertexProperty","@value":{"id":{"@type":"g:Int64","@value":6},"value":"san diego","label":"location","properties":{"startTime":{"@type":"g:Property","@value":{"key":"startTime","value":{"@type":"g:Int32","@value":1997}}}}}}]}}}`
+
+var testGraphSONEdge = `{"@type":"g:Edge","@value":{"id":{"@type":"g:Int32","@value":13},"label":"develops","inVLabel":"software","outVLabel":"person","inV":{"@type":"g:Int32","@value":10},"outV":{"@type":"g:Int32","@value":1},"properties":{"since":{"@type":"g:Property","@value":{"key":"since","value":{"@type":"g:Int32","@value":2009}}}}}}`
+
+var testGraphSONPath = `{"@type":"g:Path","@value":{"labels":{"@type":"g:List","@value":[{"@type":"g:Set","@value":["a"]},{"@type":"g:Set","@value":[]}]},"objects":{"@type":"g:List","@value":[{"@type":"g:Int32","@value":1},"lop"]}}}`
+
+func TestDecodeGraphSONScalars(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(int32(5), decodeTestGraphSON(t, `{"@type":"g:Int32","@value":5}`))
+	assert.Equal(int64(5), decodeTestGraphSON(t, `{"@type":"g:Int64","@value":5}`))
+	assert.Equal(int16(5), decodeTestGraphSON(t, `{"@type":"gx:Int16","@value":5}`))
+	assert.Equal(float32(0.5), decodeTestGraphSON(t, `{"@type":"g:Float","@value":0.5}`))
+	assert.Equal(0.25, decodeTestGraphSON(t, `{"@type":"g:Double","@value":0.25}`))
+	assert.Equal(json.Number("12345678901234567890"), decodeTestGraphSON(t, `{"@type":"gx:BigInteger","@value":12345678901234567890}`))
+	assert.Equal(time.Date(2019, 2, 13, 1, 17, 27, 0, time.UTC), decodeTestGraphSON(t, `{"@type":"g:Date","@value":1550020647000}`))
+	assert.Equal(uuid.MustParse("41d2e28a-20a4-4ab0-b379-d810dede3786"), decodeTestGraphSON(t, `{"@type":"g:UUID","@value":"41d2e28a-20a4-4ab0-b379-d810dede3786"}`))
+	assert.Equal("id", decodeTestGraphSON(t, `{"@type":"g:T","@value":"id"}`))
+
+	// untyped values are kept
+	assert.Equal(json.Number("5"), decodeTestGraphSON(t, `5`))
+	assert.Equal(map[string]interface{}{"a": "b", "n": int64(1)}, decodeTestGraphSON(t, `{"a":"b","n":{"@type":"g:Int64","@value":1}}`))
+
+	// unknown types keep their value
+	assert.Equal("x", decodeTestGraphSON(t, `{"@type":"janusgraph:Foo","@value":"x"}`))
+
+	// errors
+	_, err := decodeGraphSON(map[string]interface{}{"@type": "g:Int32", "@value": json.Number("5000000000")})
+	assert.NotNil(err)
+	_, err = decodeGraphSON(map[string]interface{}{"@type": "g:UUID", "@value": "nope"})
+	assert.NotNil(err)
+}
+
+func TestDecodeGraphSONCollections(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]interface{}{int32(1), "a"}, decodeTestGraphSON(t, `{"@type":"g:List","@value":[{"@type":"g:Int32","@value":1},"a"]}`))
+	assert.Equal([]interface{}{"a", "b"}, decodeTestGraphSON(t, `{"@type":"g:Set","@value":["a","b"]}`))
+	assert.Equal([]interface{}{"a", "a", "b"}, decodeTestGraphSON(t, `{"@type":"g:BulkSet","@value":["a",{"@type":"g:Int64","@value":2},"b",{"@type":"g:Int64","@value":1}]}`))
+	assert.Equal(map[string]interface{}{"name": []interface{}{"marko"}, "age": int32(29)}, decodeTestGraphSON(t, `{"@type":"g:Map","@value":["name",{"@type":"g:List","@value":["marko"]},"age",{"@type":"g:Int32","@value":29}]}`))
+	assert.Equal(map[interface{}]interface{}{int32(1): "a", "b": int64(2)}, decodeTestGraphSON(t, `{"@type":"g:Map","@value":[{"@type":"g:Int32","@value":1},"a","b",{"@type":"g:Int64","@value":2}]}`))
+	assert.Equal(&Traverser{Bulk: 2, Value: "a"}, decodeTestGraphSON(t, `{"@type":"g:Traverser","@value":{"bulk":{"@type":"g:Int64","@value":2},"value":"a"}}`))
+}
+
+func TestDecodeGraphSONElements(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(&Vertex{
+		Id:    int64(1),
+		Label: "person",
+		Properties: map[string][]*VertexProperty{
+			"name":     {{Id: int64(0), Label: "name", Value: "marko"}},
+			"location": {{Id: int64(6), Label: "location", Value: "san diego", Properties: map[string]interface{}{"startTime": int32(1997)}}},
+		},
+	}, decodeTestGraphSON(t, testGraphSONVertex))
+
+	assert.Equal(&Edge{
+		Id:         int32(13),
+		Label:      "develops",
+		InV:        int32(10),
+		InVLabel:   "software",
+		OutV:       int32(1),
+		OutVLabel:  "person",
+		Properties: map[string]*Property{"since": {Key: "since", Value: int32(2009)}},
+	}, decodeTestGraphSON(t, testGraphSONEdge))
+
+	assert.Equal(&Path{
+		Labels:  [][]string{{"a"}, {}},
+		Objects: []interface{}{int32(1), "lop"},
+	}, decodeTestGraphSON(t, testGraphSONPath))
+
+	// vertices without types keep their properties
+	v := decodeTestGraphSON(t, `{"@type":"g:Vertex","@value":{"id":"a","label":"person","properties":{"name":[{"id":"b","value":"marko"}]}}}`)
+	assert.Equal("marko", v.(*Vertex).Properties["name"][0].Value)
+}
+
+func TestEncodeGraphSON(t *testing.T) {
+	assert := assert.New(t)
+
+	id := uuid.MustParse("41d2e28a-20a4-4ab0-b379-d810dede3786")
+	encoded, err := json.Marshal(graphSONv3.encode(map[string]interface{}{
+		"s":  "a",
+		"b":  true,
+		"i":  1,
+		"i3": int32(2),
+		"f":  0.5,
+		"id": id,
+		"t":  time.Date(2019, 2, 13, 1, 17, 27, 0, time.UTC),
+		"l":  []string{"x"},
+		"n":  nil,
+	}))
+	assert.Nil(err)
+	assert.Equal(`{"@type":"g:Map","@value":["b",true,"f",{"@type":"g:Double","@value":0.5},"i",{"@type":"g:Int64","@value":1},"i3",{"@type":"g:Int32","@value":2},"id",{"@type":"g:UUID","@value":"41d2e28a-20a4-4ab0-b379-d810dede3786"},"l",{"@type":"g:List","@value":["x"]},"n",null,"s","a","t",{"@type":"g:Date","@value":1550020647000}]}`, string(encoded))
+}
+
+func TestMarshalGraphSON(t *testing.T) {
+	assert := assert.New(t)
+
+	// elements decode to what they were encoded from
+	for _, typed := range []string{testGraphSONVertex, testGraphSONEdge} {
+		v := decodeTestGraphSON(t, typed)
+		b, err := MarshalGraphSON(v)
+		assert.Nil(err)
+		assert.Equal(v, decodeTestGraphSON(t, string(b)))
+	}
+
+	// untyped vertices are encoded as typed ones
+	b, err := MarshalGraphSON(map[string]interface{}{"id": "a", "label": "person", "type": "vertex", "properties": map[string]interface{}{
+		"name": []interface{}{map[string]interface{}{"id": "b", "value": "marko"}},
+	}})
+	assert.Nil(err)
+	assert.Equal(`{"@type":"g:Vertex","@value":{"id":"a","label":"person","properties":{"name":[{"@type":"g:VertexProperty","@value":{"id":"b","label":"name","value":"marko"}}]}}}`, string(b))
+}
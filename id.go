@@ -0,0 +1,97 @@
+package gremgoser
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// idFieldIndex returns the index of the id field of a struct type, the field tagged with the id option like
+// `graph:"id,id"` or else the field named Id, and -1 when there is none. Ids may be a uuid.UUID, a string, an
+// integer, or a custom type based on them or implementing encoding.TextMarshaler and encoding.TextUnmarshaler.
+func idFieldIndex(t reflect.Type) int {
+	return codecOf(t).id
+}
+
+// idField returns the id field of a struct value
+func idField(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	i := idFieldIndex(v.Type())
+	if i < 0 {
+		return reflect.Value{}, false
+	}
+	return v.Field(i), true
+}
+
+// idValue returns the string or integer an id is sent to the server as
+func idValue(v interface{}) (interface{}, error) {
+	switch id := v.(type) {
+	case uuid.UUID:
+		return id.String(), nil
+	case encoding.TextMarshaler:
+		text, err := id.MarshalText()
+		return string(text), err
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	case reflect.Ptr, reflect.Interface:
+		if !rv.IsNil() {
+			return idValue(rv.Elem().Interface())
+		}
+	}
+	return nil, ErrorUnsupportedIdType
+}
+
+// setID sets an id field from the id of a response, a string, a json.Number or a typed GraphSON value
+func setID(f reflect.Value, id interface{}) error {
+	id, err := decodeGraphSON(id)
+	if err != nil || id == nil {
+		return err
+	}
+	if f.Kind() == reflect.Ptr {
+		p := reflect.New(f.Type().Elem())
+		if err := setID(p.Elem(), id); err != nil {
+			return err
+		}
+		f.Set(p)
+		return nil
+	}
+	text := fmt.Sprint(id)
+	if f.CanAddr() {
+		if u, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(text))
+		}
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil || f.OverflowInt(n) {
+			return fmt.Errorf("gremgoser: id %s does not fit %s", text, f.Type())
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, 64)
+		if err != nil || f.OverflowUint(n) {
+			return fmt.Errorf("gremgoser: id %s does not fit %s", text, f.Type())
+		}
+		f.SetUint(n)
+	case reflect.Interface:
+		f.Set(reflect.ValueOf(id))
+	default:
+		return ErrorUnsupportedIdType
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package gremgoser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type NodeID int64
+
+// UserID has a String method for logging, it is still sent as a number
+type UserID int64
+
+func (id UserID) String() string {
+	return "user-" + strconv.FormatInt(int64(id), 10)
+}
+
+type SKU struct {
+	code string
+}
+
+func (s SKU) MarshalText() ([]byte, error) {
+	return []byte("sku-" + s.code), nil
+}
+
+func (s *SKU) UnmarshalText(text []byte) error {
+	if !bytes.HasPrefix(text, []byte("sku-")) {
+		return errors.New("not a sku")
+	}
+	s.code = string(text[4:])
+	return nil
+}
+
+type IntPerson struct {
+	Key  NodeID `graph:"id,id"`
+	Name string `graph:"name,string"`
+}
+
+type StringPerson struct {
+	Id   string `graph:"id,id"`
+	Name string `graph:"name,string"`
+}
+
+type Product struct {
+	SKU  SKU    `graph:"id,id"`
+	Name string `graph:"name,string"`
+}
+
+func TestIdFieldIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0, idFieldIndex(reflect.TypeOf(Test2{})))
+	assert.Equal(0, idFieldIndex(reflect.TypeOf(IntPerson{})))
+	assert.Equal(0, idFieldIndex(reflect.TypeOf(Product{})))
+	assert.Equal(1, idFieldIndex(reflect.TypeOf(struct {
+		Id  string
+		Key int64 `graph:"key,id"`
+	}{})))
+	assert.Equal(-1, idFieldIndex(reflect.TypeOf(struct{ A string }{})))
+
+	_, ok := idField(reflect.ValueOf(7))
+	assert.False(ok)
+}
+
+func TestScriptId(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	u := uuid.MustParse("64795211-c4a1-4eac-9e0a-b674ced77461")
+
+	// ids are bound as string or number
+	q := c.newScript()
+	for _, id := range []interface{}{u, "a", NodeID(42), uint8(7), SKU{"1"}, &u, UserID(9)} {
+		s, err := q.id(id)
+		assert.Nil(err)
+		q.add("g.V(%s)", s)
+	}
+	assert.Equal("g.V(_p0)g.V(_p1)g.V(_p2)g.V(_p3)g.V(_p4)g.V(_p5)g.V(_p6)", q.String())
+	assert.Equal(map[string]interface{}{"_p0": u.String(), "_p1": "a", "_p2": int64(42), "_p3": uint64(7), "_p4": "sku-1", "_p5": u.String(), "_p6": int64(9)}, q.params())
+
+	// and decoded back the same way
+	var user UserID
+	assert.Nil(setID(reflect.ValueOf(&user).Elem(), json.Number("9")))
+	assert.Equal(UserID(9), user)
+
+	// ids are inlined as literals
+	c.conf.SetDisableBindings()
+	q = c.newScript()
+	for _, id := range []interface{}{"it's", NodeID(42)} {
+		s, err := q.id(id)
+		assert.Nil(err)
+		q.add("g.V(%s)", s)
+	}
+	assert.Equal(`g.V('it\'s')g.V(42)`, q.String())
+
+	// struct ids
+	s, err := q.idOf(&IntPerson{Key: 3})
+	assert.Nil(err)
+	assert.Equal("3", s)
+	_, err = q.idOf(struct{ A string }{})
+	assert.Equal(ErrorInterfaceHasNoIdField, err)
+	_, err = q.id(1.5)
+	assert.Equal(ErrorUnsupportedIdType, err)
+	_, err = q.id((*NodeID)(nil))
+	assert.Equal(ErrorUnsupportedIdType, err)
+}
+
+func TestSetID(t *testing.T) {
+	assert := assert.New(t)
+
+	var p struct {
+		U   uuid.UUID
+		S   string
+		N   NodeID
+		I8  int8
+		U32 uint32
+		P   *int64
+		SKU SKU
+		Any interface{}
+		F   float64
+	}
+	v := reflect.ValueOf(&p).Elem()
+
+	assert.Nil(setID(v.Field(0), "64795211-c4a1-4eac-9e0a-b674ced77461"))
+	assert.Equal(uuid.MustParse("64795211-c4a1-4eac-9e0a-b674ced77461"), p.U)
+	assert.Nil(setID(v.Field(1), json.Number("12")))
+	assert.Equal("12", p.S)
+	assert.Nil(setID(v.Field(2), json.Number("9007199254740993")))
+	assert.Equal(NodeID(9007199254740993), p.N)
+	assert.Nil(setID(v.Field(2), map[string]interface{}{"@type": "g:Int64", "@value": json.Number("5")}))
+	assert.Equal(NodeID(5), p.N)
+	assert.Nil(setID(v.Field(3), "-3"))
+	assert.Equal(int8(-3), p.I8)
+	assert.Nil(setID(v.Field(4), int64(4)))
+	assert.Equal(uint32(4), p.U32)
+	assert.Nil(setID(v.Field(5), json.Number("6")))
+	assert.Equal(int64(6), *p.P)
+	assert.Nil(setID(v.Field(6), "sku-7"))
+	assert.Equal(SKU{"7"}, p.SKU)
+	assert.Nil(setID(v.Field(7), "x"))
+	assert.Equal("x", p.Any)
+
+	// a missing id leaves the field untouched
+	assert.Nil(setID(v.Field(1), nil))
+	assert.Equal("12", p.S)
+
+	assert.NotNil(setID(v.Field(3), json.Number("300")))
+	assert.NotNil(setID(v.Field(4), "a"))
+	assert.NotNil(setID(v.Field(6), "7"))
+	assert.Equal(ErrorUnsupportedIdType, setID(v.Field(8), json.Number("1")))
+}
+
+// newIDMock answers every vertex, upsert and edge query with an element having numeric ids
+func newIDMock() *recordingMock {
+	return &recordingMock{answer: func(gremlin string) (int, []interface{}) {
+		var data []interface{}
+		if strings.HasPrefix(gremlin, "g.V().") || strings.Contains(gremlin, ".fold().coalesce(") {
+			data = append(data, map[string]interface{}{
+				"id":    42,
+				"label": "person",
+				"type":  "vertex",
+				"properties": map[string]interface{}{
+					"name": []interface{}{map[string]interface{}{"id": 1, "value": "ann"}},
+				},
+			})
+		}
+		if strings.HasPrefix(gremlin, "g.E().") {
+			data = append(data, map[string]interface{}{
+				"id":         7,
+				"label":      "knows",
+				"type":       "edge",
+				"inVLabel":   "person",
+				"outVLabel":  "person",
+				"inV":        43,
+				"outV":       42,
+				"properties": map[string]interface{}{"weight": 0.5, "since": 2010},
+			})
+		}
+		return 200, data
+	}}
+}
+
+func TestCustomIds(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newIDMock()
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	g, errs := NewClient(NewClientConfig(u))
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	ann, bob := &IntPerson{Key: 42, Name: "ann"}, &IntPerson{Key: 43, Name: "bob"}
+	_, err := g.AddV("person", ann)
+	assert.Nil(err)
+	_, err = g.AddV("person", &IntPerson{Name: "new"})
+	assert.Nil(err)
+	_, err = g.AddV("person", &StringPerson{Id: "ann", Name: "ann"})
+	assert.Nil(err)
+	_, err = g.UpdateV(ann)
+	assert.Nil(err)
+	_, err = g.AddE("knows", ann, bob)
+	assert.Nil(err)
+	_, err = g.DropE("knows", &Product{SKU: SKU{"1"}}, &StringPerson{Id: "ann"})
+	assert.Nil(err)
+	_, err = g.DropV(bob)
+	assert.Nil(err)
+
+	m.mu.Lock()
+	assert.Equal([]string{
+		"g.addV('person').property('id', 42).property('name', 'ann')",
+		"g.addV('person').property('name', 'new')",
+		"g.addV('person').property('id', 'ann').property('name', 'ann')",
+		"g.V(42).property('name', 'ann')",
+		"g.V(42).addE('knows').to(g.V(43))",
+		"g.V('sku-1').outE('knows').and(inV().is('ann')).drop()",
+		"g.V(43).drop()",
+	}, m.scripts)
+	m.mu.Unlock()
+
+	// numeric ids are decoded into the id field
+	people, err := GetAs[IntPerson](context.Background(), g, "g.V().hasLabel('person')", nil)
+	assert.Nil(err)
+	assert.Equal([]IntPerson{{Key: 42, Name: "ann"}}, people)
+
+	var named []StringPerson
+	assert.Nil(g.Get("g.V().hasLabel('person')", nil, &named))
+	assert.Equal([]StringPerson{{Id: "42", Name: "ann"}}, named)
+
+	var products []Product
+	assert.NotNil(g.Get("g.V().hasLabel('product')", nil, &products))
+}
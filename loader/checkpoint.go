@@ -0,0 +1,33 @@
+package loader
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readCheckpoint returns the offset saved in a checkpoint file, 0 when there is none
+func readCheckpoint(path string) (int, error) {
+	if path == "" {
+		return 0, nil
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+// writeCheckpoint saves an offset to a checkpoint file, the file is replaced so it is never left half written
+func writeCheckpoint(path string, offset int) error {
+	if path == "" {
+		return nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(offset)+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,344 @@
+// Package loader bulk loads vertices and edges from CSV and JSON Lines files into a graph through a gremgoser
+// client. Records are mapped to elements by the column names of the file, sent concurrently, and the progress is
+// checkpointed so an interrupted load resumes where it stopped. Records that fail are written to an error report
+// and do not stop the load.
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/intwinelabs/gremgoser"
+)
+
+// Format is the format of a file
+type Format int
+
+const (
+	FormatCSV   Format = iota + 1 // comma separated values with a header
+	FormatJSONL                   // JSON Lines, an object per line
+)
+
+// Executor runs Gremlin scripts, it is implemented by *gremgoser.Client
+type Executor interface {
+	ExecuteContext(ctx context.Context, query string, bindings, rebindings map[string]interface{}) ([]*gremgoser.GremlinRespData, error)
+}
+
+// Options configure a load
+type Options struct {
+	Format          Format  // format of the file, detected from the extension by LoadFile when 0
+	Edges           bool    // the file holds edges rather than vertices
+	Mapping         Mapping // mapping of the columns to the elements
+	Concurrency     int     // number of elements in flight at the same time, 8 by default
+	Checkpoint      string  // file the offset of the load is saved to and resumed from, none when empty
+	CheckpointEvery int     // number of records between two saves of the checkpoint, 1000 by default
+	ErrorReport     string  // file the failed records are appended to as JSON lines, none when empty
+	// IdToken sets the ids with the id token, T.id, like servers such as TinkerGraph require. The ids are written
+	// as the 'id' property otherwise, like Cosmos DB expects them. It is the IdToken of gremgoser.ImportOptions.
+	IdToken bool
+}
+
+// Stats are the counts of a load
+type Stats struct {
+	Offset  int // number of records processed, including the ones skipped on resume
+	Skipped int // records of the file skipped because the checkpoint was past them
+	Loaded  int // elements added
+	Failed  int // records that failed
+}
+
+// Failure is a line of the error report
+type Failure struct {
+	Offset int    `json:"offset"` // offset of the record, the first record after the header is 1
+	Error  string `json:"error"`
+}
+
+// Loader loads files into a graph
+type Loader struct {
+	exec Executor
+	opts Options
+}
+
+// New returns a loader sending the elements through exec
+func New(exec Executor, opts Options) *Loader {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 8
+	}
+	if opts.CheckpointEvery <= 0 {
+		opts.CheckpointEvery = 1000
+	}
+	return &Loader{exec: exec, opts: opts}
+}
+
+// LoadFile loads a CSV or JSON Lines file, files ending in .csv are CSV and all others JSON Lines unless
+// Options.Format is set
+func (l *Loader) LoadFile(ctx context.Context, path string) (*Stats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	format := l.opts.Format
+	if format == 0 {
+		format = FormatJSONL
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			format = FormatCSV
+		}
+	}
+	return l.load(ctx, f, format)
+}
+
+// Load loads the records read from r in the format of the options
+func (l *Loader) Load(ctx context.Context, r io.Reader) (*Stats, error) {
+	if l.opts.Format == 0 {
+		return nil, errors.New("loader: the format of the reader is not set")
+	}
+	return l.load(ctx, r, l.opts.Format)
+}
+
+func (l *Loader) load(ctx context.Context, r io.Reader, format Format) (*Stats, error) {
+	m := l.opts.Mapping
+	var rr recordReader
+	switch format {
+	case FormatCSV:
+		cr, err := newCSVReader(r, &m)
+		if err != nil {
+			return nil, err
+		}
+		rr = cr
+	case FormatJSONL:
+		rr = newJSONReader(r, &m)
+	default:
+		return nil, fmt.Errorf("loader: unknown format %d", format)
+	}
+
+	start, err := readCheckpoint(l.opts.Checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	report, err := openReport(l.opts.ErrorReport)
+	if err != nil {
+		return nil, err
+	}
+	defer report.close()
+
+	p := &progress{
+		next:       start,
+		done:       make(map[int]bool),
+		checkpoint: l.opts.Checkpoint,
+		every:      l.opts.CheckpointEvery,
+		report:     report,
+	}
+
+	type job struct {
+		offset int
+		e      *element
+	}
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < l.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				err := l.send(ctx, j.e)
+				p.finish(j.offset, err)
+			}
+		}()
+	}
+
+	var readErr error
+	offset, skipped := 0, 0
+read:
+	for {
+		fields, err := rr.next()
+		if err == io.EOF {
+			break
+		}
+		offset++
+		if offset <= start {
+			skipped++
+			continue
+		}
+		var e *element
+		var rerr recordError
+		switch {
+		case errors.As(err, &rerr):
+			p.finish(offset, err)
+			continue
+		case err != nil:
+			readErr = err
+			break read
+		case fields == nil:
+			// blank lines only count for the offset
+			p.mark(offset)
+			continue
+		}
+		if e, err = m.element(fields, l.opts.Edges); err != nil {
+			p.finish(offset, err)
+			continue
+		}
+		select {
+		case jobs <- job{offset, e}:
+		case <-ctx.Done():
+			break read
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	p.stats.Skipped = skipped
+	stats, err := p.close()
+	if readErr != nil {
+		return stats, readErr
+	}
+	if err != nil {
+		return stats, err
+	}
+	return stats, ctx.Err()
+}
+
+// send adds an element to the graph, an edge whose vertices do not exist fails with gremgoser.ErrorNotFound
+func (l *Loader) send(ctx context.Context, e *element) error {
+	query, bindings := script(e, l.opts.Edges, l.opts.IdToken)
+	resp, err := l.exec.ExecuteContext(ctx, query, bindings, nil)
+	if err != nil {
+		return err
+	}
+	if l.opts.Edges && len(resp) == 0 {
+		return gremgoser.ErrorNotFound
+	}
+	return nil
+}
+
+// script returns the traversal adding an element, all values and property names are bound
+func script(e *element, edge, idToken bool) (string, map[string]interface{}) {
+	var b strings.Builder
+	bindings := make(map[string]interface{})
+	bind := func(v interface{}) string {
+		name := fmt.Sprintf("_p%d", len(bindings))
+		bindings[name] = v
+		return name
+	}
+	if edge {
+		fmt.Fprintf(&b, "g.V(%s).addE(%s).to(g.V(%s))", bind(e.from), bind(e.label), bind(e.to))
+	} else {
+		fmt.Fprintf(&b, "g.addV(%s)", bind(e.label))
+	}
+	if e.id != nil && idToken {
+		fmt.Fprintf(&b, ".property(id, %s)", bind(e.id))
+	} else if e.id != nil {
+		fmt.Fprintf(&b, ".property('id', %s)", bind(e.id))
+	}
+	for _, prop := range e.props {
+		key := bind(prop.name)
+		for _, v := range prop.values {
+			fmt.Fprintf(&b, ".property(%s, %s)", key, bind(v))
+		}
+	}
+	return b.String(), bindings
+}
+
+// progress tracks the records that are done, the checkpoint is the offset all records up to are done
+type progress struct {
+	mu         sync.Mutex
+	next       int
+	done       map[int]bool
+	checkpoint string
+	every      int
+	saved      int
+	report     *report
+	stats      Stats
+	err        error
+}
+
+// finish marks a record as done and reports its error
+func (p *progress) finish(offset int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			// the record was not sent, it is loaded again on resume
+			return
+		}
+		p.stats.Failed++
+		if rerr := p.report.write(Failure{Offset: offset, Error: err.Error()}); rerr != nil && p.err == nil {
+			p.err = rerr
+		}
+	} else {
+		p.stats.Loaded++
+	}
+	p.advance(offset)
+}
+
+// mark marks a record without element as done
+func (p *progress) mark(offset int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.advance(offset)
+}
+
+// advance moves the checkpoint past the records that are done, saving it every so many records
+func (p *progress) advance(offset int) {
+	p.done[offset] = true
+	for p.done[p.next+1] {
+		delete(p.done, p.next+1)
+		p.next++
+	}
+	if p.next-p.saved >= p.every {
+		p.save()
+	}
+}
+
+func (p *progress) save() {
+	if err := writeCheckpoint(p.checkpoint, p.next); err != nil && p.err == nil {
+		p.err = err
+	}
+	p.saved = p.next
+}
+
+// close saves the final checkpoint and returns the stats of the load
+func (p *progress) close() (*Stats, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.save()
+	stats := p.stats
+	stats.Offset = p.next
+	return &stats, p.err
+}
+
+// report appends failed records to the error report
+type report struct {
+	f   *os.File
+	enc *json.Encoder
+}
+
+func openReport(path string) (*report, error) {
+	if path == "" {
+		return &report{}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &report{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (r *report) write(failure Failure) error {
+	if r.enc == nil {
+		return nil
+	}
+	return r.enc.Encode(failure)
+}
+
+func (r *report) close() {
+	if r.f != nil {
+		r.f.Close()
+	}
+}
@@ -0,0 +1,190 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/intwinelabs/gremgoser"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExecutor records the scripts with their bindings inlined, scripts of the vertex 999 find nothing and scripts
+// with a 'boom' value fail
+type fakeExecutor struct {
+	mu      sync.Mutex
+	scripts []string
+}
+
+var binding = regexp.MustCompile(`_p\d+`)
+
+func (f *fakeExecutor) ExecuteContext(ctx context.Context, query string, bindings, rebindings map[string]interface{}) ([]*gremgoser.GremlinRespData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	query = binding.ReplaceAllStringFunc(query, func(name string) string {
+		if s, ok := bindings[name].(string); ok {
+			return "'" + s + "'"
+		}
+		return fmt.Sprintf("%v", bindings[name])
+	})
+	f.mu.Lock()
+	f.scripts = append(f.scripts, query)
+	f.mu.Unlock()
+	if strings.Contains(query, "'boom'") {
+		return nil, gremgoser.Error597ScriptEvaluationError
+	}
+	if strings.Contains(query, "g.V(999)") {
+		return nil, nil
+	}
+	return []*gremgoser.GremlinRespData{{"id": 1}}, nil
+}
+
+func (f *fakeExecutor) sorted() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	scripts := append([]string(nil), f.scripts...)
+	sort.Strings(scripts)
+	return scripts
+}
+
+func readReport(t *testing.T, path string) []Failure {
+	b, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	var failures []Failure
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var f Failure
+		assert.Nil(t, json.Unmarshal([]byte(line), &f))
+		failures = append(failures, f)
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Offset < failures[j].Offset })
+	return failures
+}
+
+const vertices = `~id,~label,name,age:int,tags:string[],tenant:partitionKey
+1,person,ann,30,a;b,t1
+2,person,bob,x,,t1
+3,,carl,40,,t1
+4,person,dan,50,,
+5,person,boom,60,,t2
+6,person,eve
+7,person,fay,,c,t2
+`
+
+func TestLoadCSV(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vertices.csv")
+	assert.Nil(os.WriteFile(path, []byte(vertices), 0644))
+	checkpoint, errorReport := filepath.Join(dir, "checkpoint"), filepath.Join(dir, "errors.jsonl")
+
+	exec := &fakeExecutor{}
+	l := New(exec, Options{
+		Mapping:         Mapping{DefaultLabel: "person"},
+		Concurrency:     3,
+		Checkpoint:      checkpoint,
+		CheckpointEvery: 2,
+		ErrorReport:     errorReport,
+	})
+	stats, err := l.LoadFile(context.Background(), path)
+	assert.Nil(err)
+	assert.Equal(&Stats{Offset: 7, Loaded: 3, Failed: 4}, stats)
+	assert.Equal([]string{
+		"g.addV('person').property('id', '1').property('name', 'ann').property('age', 30).property('tags', 'a').property('tags', 'b').property('tenant', 't1')",
+		"g.addV('person').property('id', '3').property('name', 'carl').property('age', 40).property('tenant', 't1')",
+		"g.addV('person').property('id', '5').property('name', 'boom').property('age', 60).property('tenant', 't2')",
+		"g.addV('person').property('id', '7').property('name', 'fay').property('tags', 'c').property('tenant', 't2')",
+	}, exec.sorted())
+
+	failures := readReport(t, errorReport)
+	assert.Equal(4, len(failures))
+	assert.Equal(2, failures[0].Offset)
+	assert.True(strings.Contains(failures[0].Error, "column age"))
+	assert.Equal(Failure{Offset: 4, Error: "the vertex has no partition key tenant"}, failures[1])
+	assert.Equal(Failure{Offset: 5, Error: gremgoser.Error597ScriptEvaluationError.Error()}, failures[2])
+	assert.Equal(6, failures[3].Offset)
+	assert.True(strings.Contains(failures[3].Error, "wrong number of fields"))
+
+	// the whole file is done
+	offset, err := readCheckpoint(checkpoint)
+	assert.Nil(err)
+	assert.Equal(7, offset)
+
+	// a load resumes after the checkpoint
+	assert.Nil(writeCheckpoint(checkpoint, 5))
+	exec = &fakeExecutor{}
+	stats, err = New(exec, Options{Checkpoint: checkpoint}).LoadFile(context.Background(), path)
+	assert.Nil(err)
+	assert.Equal(&Stats{Offset: 7, Skipped: 5, Loaded: 1, Failed: 1}, stats)
+	assert.Equal(1, len(exec.sorted()))
+
+	// only the records the file has are counted as skipped
+	assert.Nil(writeCheckpoint(checkpoint, 5))
+	stats, err = New(&fakeExecutor{}, Options{Format: FormatCSV, Checkpoint: checkpoint}).Load(context.Background(), strings.NewReader("~id,~label\n1,person\n2,person\n"))
+	assert.Nil(err)
+	assert.Equal(2, stats.Skipped)
+	assert.Equal(0, stats.Loaded)
+
+	// ids are set with the id token for servers like TinkerGraph
+	exec = &fakeExecutor{}
+	_, err = New(exec, Options{Format: FormatCSV, IdToken: true}).Load(context.Background(), strings.NewReader("~id,~label\n1,person\n"))
+	assert.Nil(err)
+	assert.Equal([]string{"g.addV('person').property(id, '1')"}, exec.sorted())
+
+	// a canceled load leaves the checkpoint where it was
+	assert.Nil(writeCheckpoint(checkpoint, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	exec = &fakeExecutor{}
+	stats, err = New(exec, Options{Format: FormatCSV, Checkpoint: checkpoint}).Load(ctx, strings.NewReader("~id,~label\n1,person\n2,person\n"))
+	assert.Equal(context.Canceled, err)
+	assert.Equal(0, stats.Offset)
+	offset, err = readCheckpoint(checkpoint)
+	assert.Nil(err)
+	assert.Equal(0, offset)
+
+	// readers need a format
+	_, err = New(exec, Options{}).Load(context.Background(), strings.NewReader(vertices))
+	assert.NotNil(err)
+}
+
+const edges = `{"~id":"e1","~label":"knows","~from":1,"~to":2,"weight:double":"0.5","since":2010}
+
+{"~label":"knows","~from":999,"~to":2}
+{"~label":"knows","~from":1}
+{"~label":
+{"~label":"knows","~from":2,"~to":3,"meta":{"a":1}}
+{"~label":"knows","~from":2,"~to":3,"tags":["a","b"],"note":null}
+`
+
+func TestLoadJSONL(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	errorReport := filepath.Join(dir, "errors.jsonl")
+
+	exec := &fakeExecutor{}
+	l := New(exec, Options{Format: FormatJSONL, Edges: true, ErrorReport: errorReport})
+	stats, err := l.Load(context.Background(), strings.NewReader(edges))
+	assert.Nil(err)
+	assert.Equal(&Stats{Offset: 7, Loaded: 2, Failed: 4}, stats)
+	assert.Equal([]string{
+		"g.V(1).addE('knows').to(g.V(2)).property('id', 'e1').property('since', 2010).property('weight', 0.5)",
+		"g.V(2).addE('knows').to(g.V(3)).property('tags', 'a').property('tags', 'b')",
+		"g.V(999).addE('knows').to(g.V(2))",
+	}, exec.sorted())
+
+	failures := readReport(t, errorReport)
+	assert.Equal([]int{3, 4, 5, 6}, []int{failures[0].Offset, failures[1].Offset, failures[2].Offset, failures[3].Offset})
+	assert.Equal(gremgoser.ErrorNotFound.Error(), failures[0].Error)
+	assert.Equal("the edge has no ~from or ~to vertex", failures[1].Error)
+	assert.Equal("column meta: objects are not supported", failures[3].Error)
+}
@@ -0,0 +1,184 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Mapping maps the columns of a file to the parts of graph elements. Column names come from the CSV header or the
+// keys of the JSON objects and may carry the type of their values after a colon, like `age:int`, `tags:string[]`
+// or `tenant:partitionKey`. The supported types are string, int, long, float, double, bool and partitionKey,
+// array types end with [] and are separated by ArraySeparator in CSV files. Columns without type are strings in CSV
+// files and keep their JSON type in JSON Lines files.
+type Mapping struct {
+	ID             string            // column of the element id, "~id" by default
+	Label          string            // column of the label, "~label" by default
+	From           string            // column of the id of the outgoing vertex of an edge, "~from" by default
+	To             string            // column of the id of the incoming vertex of an edge, "~to" by default
+	PartitionKey   string            // property holding the partition key, vertices without it fail
+	DefaultLabel   string            // label of the elements without a label
+	Types          map[string]string // types by column name, taking precedence over the types in the header
+	ArraySeparator string            // separator of the values of array columns in CSV files, ";" by default
+}
+
+// column is a column of a file with the type of its values
+type column struct {
+	name  string
+	typ   string
+	array bool
+}
+
+// parseColumn splits a header name into the column name and its type
+func (m *Mapping) parseColumn(header string) column {
+	c := column{name: header}
+	if i := strings.LastIndex(header, ":"); i > 0 {
+		c.name, c.typ = header[:i], header[i+1:]
+	}
+	if typ, ok := m.Types[c.name]; ok {
+		c.typ = typ
+	}
+	c.typ = strings.ToLower(c.typ)
+	if strings.HasSuffix(c.typ, "[]") {
+		c.typ = strings.TrimSuffix(c.typ, "[]")
+		c.array = true
+	}
+	if c.typ == "partitionkey" && m.PartitionKey == "" {
+		m.PartitionKey = c.name
+	}
+	return c
+}
+
+// field is a column of a record and its raw value, a string in CSV files and a JSON value in JSON Lines files
+type field struct {
+	column
+	raw interface{}
+}
+
+// property is a typed property of an element, array columns have several values
+type property struct {
+	name   string
+	values []interface{}
+}
+
+// element is a vertex or an edge read from a record
+type element struct {
+	id       interface{}
+	label    string
+	from, to interface{}
+	props    []property
+}
+
+func (m *Mapping) name(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
+
+// element maps the fields of a record to an element, edges need a label and both vertices
+func (m *Mapping) element(fields []field, edges bool) (*element, error) {
+	e := &element{label: m.DefaultLabel}
+	pk := false
+	for _, f := range fields {
+		values, err := m.values(f)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %s", f.name, err)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		switch f.name {
+		case m.name(m.ID, "~id"):
+			e.id = values[0]
+		case m.name(m.Label, "~label"):
+			e.label = fmt.Sprint(values[0])
+		case m.name(m.From, "~from"):
+			e.from = values[0]
+		case m.name(m.To, "~to"):
+			e.to = values[0]
+		default:
+			pk = pk || f.name == m.PartitionKey
+			e.props = append(e.props, property{name: f.name, values: values})
+		}
+	}
+	if e.label == "" {
+		return nil, fmt.Errorf("the element has no label")
+	}
+	if edges && (e.from == nil || e.to == nil) {
+		return nil, fmt.Errorf("the edge has no %s or %s vertex", m.name(m.From, "~from"), m.name(m.To, "~to"))
+	}
+	if !edges && m.PartitionKey != "" && !pk {
+		return nil, fmt.Errorf("the vertex has no partition key %s", m.PartitionKey)
+	}
+	return e, nil
+}
+
+// values converts the raw value of a field to the values of its type, empty values have none
+func (m *Mapping) values(f field) ([]interface{}, error) {
+	var raws []interface{}
+	switch raw := f.raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if raw == "" {
+			return nil, nil
+		}
+		if f.array {
+			for _, s := range strings.Split(raw, m.name(m.ArraySeparator, ";")) {
+				raws = append(raws, s)
+			}
+		} else {
+			raws = []interface{}{raw}
+		}
+	case []interface{}:
+		raws = raw
+	case map[string]interface{}:
+		return nil, fmt.Errorf("objects are not supported")
+	default:
+		raws = []interface{}{raw}
+	}
+	values := make([]interface{}, 0, len(raws))
+	for _, raw := range raws {
+		if raw == nil {
+			continue
+		}
+		v, err := convert(f.typ, raw)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// convert converts a raw value to a type, values without type keep theirs and JSON numbers become integers when
+// they have no fraction
+func convert(typ string, raw interface{}) (interface{}, error) {
+	s := fmt.Sprint(raw)
+	switch typ {
+	case "":
+		switch v := raw.(type) {
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				return i, nil
+			}
+			return v.Float64()
+		case string, bool:
+			return v, nil
+		}
+		return nil, fmt.Errorf("unsupported value %v", raw)
+	case "string", "partitionkey":
+		return s, nil
+	case "int", "long":
+		return strconv.ParseInt(s, 10, 64)
+	case "float", "double":
+		return strconv.ParseFloat(s, 64)
+	case "bool", "boolean":
+		return strconv.ParseBool(s)
+	}
+	return nil, fmt.Errorf("unknown type %s", typ)
+}
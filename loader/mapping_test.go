@@ -0,0 +1,75 @@
+package loader
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseColumn(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Mapping{Types: map[string]string{"score": "Double"}}
+	assert.Equal(column{name: "name"}, m.parseColumn("name"))
+	assert.Equal(column{name: "age", typ: "int"}, m.parseColumn("age:Int"))
+	assert.Equal(column{name: "tags", typ: "string", array: true}, m.parseColumn("tags:String[]"))
+	assert.Equal(column{name: "score", typ: "double"}, m.parseColumn("score:int"))
+	assert.Equal(column{name: "a:b", typ: "long"}, m.parseColumn("a:b:long"))
+
+	// the partition key is taken from the header unless it is mapped
+	assert.Equal(column{name: "tenant", typ: "partitionkey"}, m.parseColumn("tenant:partitionKey"))
+	assert.Equal("tenant", m.PartitionKey)
+	m = &Mapping{PartitionKey: "pk"}
+	m.parseColumn("tenant:partitionKey")
+	assert.Equal("pk", m.PartitionKey)
+}
+
+func TestConvert(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range []struct {
+		typ   string
+		raw   interface{}
+		value interface{}
+	}{
+		{"", "a", "a"},
+		{"", true, true},
+		{"", json.Number("12"), int64(12)},
+		{"", json.Number("1.5"), 1.5},
+		{"string", json.Number("12"), "12"},
+		{"partitionkey", "p", "p"},
+		{"int", "12", int64(12)},
+		{"long", json.Number("12"), int64(12)},
+		{"float", "1.5", 1.5},
+		{"double", json.Number("1.5"), 1.5},
+		{"bool", "true", true},
+		{"boolean", false, false},
+	} {
+		v, err := convert(test.typ, test.raw)
+		assert.Nil(err, test.typ)
+		assert.Equal(test.value, v, test.typ)
+	}
+
+	for _, test := range []struct {
+		typ string
+		raw interface{}
+	}{
+		{"", []interface{}{}},
+		{"int", "1.5"},
+		{"bool", "yes"},
+		{"date", "2020-01-01"},
+	} {
+		_, err := convert(test.typ, test.raw)
+		assert.NotNil(err, test.typ)
+	}
+
+	// empty values are left out and arrays are split
+	m := &Mapping{ArraySeparator: "|"}
+	values, err := m.values(field{column: column{typ: "int", array: true}, raw: "1|2"})
+	assert.Nil(err)
+	assert.Equal([]interface{}{int64(1), int64(2)}, values)
+	values, err = m.values(field{raw: ""})
+	assert.Nil(err)
+	assert.Equal(0, len(values))
+}
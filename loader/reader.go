@@ -0,0 +1,103 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+)
+
+// recordReader reads the records of a file one by one
+type recordReader interface {
+	// next returns the fields of the next record, io.EOF after the last one. Errors of a single record are returned
+	// as a recordError and the reader can go on with the next one.
+	next() ([]field, error)
+}
+
+// recordError is an error of a single record
+type recordError struct {
+	err error
+}
+
+func (e recordError) Error() string {
+	return e.err.Error()
+}
+
+// csvReader reads records from CSV files with a header
+type csvReader struct {
+	r       *csv.Reader
+	columns []column
+}
+
+func newCSVReader(r io.Reader, m *Mapping) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]column, len(header))
+	for i, h := range header {
+		columns[i] = m.parseColumn(h)
+	}
+	return &csvReader{r: cr, columns: columns}, nil
+}
+
+func (r *csvReader) next() ([]field, error) {
+	record, err := r.r.Read()
+	if err != nil {
+		if errors.Is(err, csv.ErrFieldCount) {
+			return nil, recordError{err}
+		}
+		return nil, err
+	}
+	fields := make([]field, len(record))
+	for i, value := range record {
+		fields[i] = field{column: r.columns[i], raw: value}
+	}
+	return fields, nil
+}
+
+// jsonReader reads records from JSON Lines files, every line is an object
+type jsonReader struct {
+	s *bufio.Scanner
+	m *Mapping
+}
+
+func newJSONReader(r io.Reader, m *Mapping) *jsonReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &jsonReader{s: s, m: m}
+}
+
+func (r *jsonReader) next() ([]field, error) {
+	if !r.s.Scan() {
+		if err := r.s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	line := bytes.TrimSpace(r.s.Bytes())
+	if len(line) == 0 {
+		return nil, nil
+	}
+	var obj map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, recordError{err}
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]field, len(keys))
+	for i, k := range keys {
+		fields[i] = field{column: r.m.parseColumn(k), raw: obj[k]}
+	}
+	return fields, nil
+}
@@ -0,0 +1,39 @@
+package gremgoser
+
+import "reflect"
+
+// GraphMarshaler is implemented by field types that control how they are written as a graph property by AddV and
+// UpdateV, like money, enums or geo points. MarshalGraph returns the property value, a string, bool or number, and
+// a nil value leaves the property out. The hook is used whatever the option type of the graph tag is, and for the
+// elements of slice fields, which are written as list properties.
+type GraphMarshaler interface {
+	MarshalGraph() (interface{}, error)
+}
+
+// GraphUnmarshaler is implemented by field types that read themselves from a graph property by Get. UnmarshalGraph
+// gets the property value as decoded from the response, a string, bool or json.Number, and must be implemented on
+// the pointer receiver.
+type GraphUnmarshaler interface {
+	UnmarshalGraph(value interface{}) error
+}
+
+var (
+	graphMarshalerType   = reflect.TypeOf((*GraphMarshaler)(nil)).Elem()
+	graphUnmarshalerType = reflect.TypeOf((*GraphUnmarshaler)(nil)).Elem()
+)
+
+// marshalGraph returns the property value of a value whose type implements GraphMarshaler, on the value or on the
+// pointer receiver
+func marshalGraph(v reflect.Value) (interface{}, error) {
+	if m, ok := v.Interface().(GraphMarshaler); ok {
+		return m.MarshalGraph()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Interface().(GraphMarshaler).MarshalGraph()
+}
+
+// unmarshalGraph sets an addressable value whose type implements GraphUnmarshaler from a property value
+func unmarshalGraph(v reflect.Value, value interface{}) error {
+	return v.Addr().Interface().(GraphUnmarshaler).UnmarshalGraph(value)
+}
@@ -0,0 +1,144 @@
+package gremgoser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Money is written as a string like "12.50 EUR"
+type Money struct {
+	Cents    int64
+	Currency string
+}
+
+func (m Money) MarshalGraph() (interface{}, error) {
+	if m.Currency == "" {
+		return nil, errors.New("money without currency")
+	}
+	return fmt.Sprintf("%d.%02d %s", m.Cents/100, m.Cents%100, m.Currency), nil
+}
+
+func (m *Money) UnmarshalGraph(value interface{}) error {
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("money is not a string: %v", value)
+	}
+	var units, cents int64
+	if _, err := fmt.Sscanf(s, "%d.%d %s", &units, &cents, &m.Currency); err != nil {
+		return err
+	}
+	m.Cents = units*100 + cents
+	return nil
+}
+
+// Color is an enum written as a number
+type Color int
+
+const (
+	Red Color = iota + 1
+	Green
+)
+
+func (c *Color) MarshalGraph() (interface{}, error) {
+	if *c == 0 {
+		return nil, nil
+	}
+	return int(*c), nil
+}
+
+func (c *Color) UnmarshalGraph(value interface{}) error {
+	n, ok := value.(json.Number)
+	if !ok {
+		return fmt.Errorf("color is not a number: %v", value)
+	}
+	i, err := n.Int64()
+	*c = Color(i)
+	return err
+}
+
+type Item struct {
+	Id     uuid.UUID `graph:"id,string"`
+	Price  Money     `graph:"price"`
+	Sale   *Money    `graph:"sale,string"`
+	Color  Color     `graph:"color,number"`
+	Colors []Color   `graph:"colors,[]number"`
+	Name   string    `graph:"name,string"`
+}
+
+func TestGraphMarshaler(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	c.conf.SetDisableBindings()
+
+	sc := codecOf(reflect.TypeOf(Item{}))
+	assert.True(sc.fields[1].marshal && sc.fields[1].unmarshal)
+	assert.True(sc.fields[2].marshal && sc.fields[2].unmarshal)
+	assert.True(sc.fields[4].marshal && sc.fields[4].unmarshal)
+	assert.False(sc.fields[5].marshal || sc.fields[5].unmarshal)
+
+	item := Item{
+		Id:     uuid.MustParse("64795211-c4a1-4eac-9e0a-b674ced77461"),
+		Price:  Money{1250, "EUR"},
+		Sale:   &Money{999, "EUR"},
+		Color:  Red,
+		Colors: []Color{Red, 0, Green},
+		Name:   "it's",
+	}
+	q := c.newScript()
+	assert.Nil(sc.addProps(q, reflect.ValueOf(item)))
+	assert.Equal(`.property('id', '64795211-c4a1-4eac-9e0a-b674ced77461').property('price', '12.50 EUR').property('sale', '9.99 EUR').property('color', 1).property('colors', 1).property('colors', 2).property('name', 'it\'s')`, q.String())
+
+	q = c.newScript()
+	item.Sale = nil
+	assert.Nil(sc.updateV(q, reflect.ValueOf(&item).Elem()))
+	assert.Equal(`.property('price', '12.50 EUR').property('color', 1).sideEffect(properties('colors').drop()).property(list, 'colors', 1).property(list, 'colors', 2).property('name', 'it\'s')`, q.String())
+
+	// errors of the hook are returned
+	item.Price = Money{}
+	assert.NotNil(sc.addProps(c.newScript(), reflect.ValueOf(item)))
+	assert.NotNil(sc.updateV(c.newScript(), reflect.ValueOf(item)))
+}
+
+func TestGraphUnmarshaler(t *testing.T) {
+	assert := assert.New(t)
+
+	row := func(props string) *GremlinData {
+		var d *GremlinData
+		decoder := json.NewDecoder(strings.NewReader(`{"id":"64795211-c4a1-4eac-9e0a-b674ced77461","label":"item","type":"vertex","properties":` + props + `}`))
+		decoder.UseNumber()
+		assert.Nil(decoder.Decode(&d))
+		return d
+	}
+
+	sc := codecOf(reflect.TypeOf(Item{}))
+	items := make([]Item, 1)
+	err := sc.decode(row(`{"price":[{"id":"a","value":"12.50 EUR"}],"sale":[{"id":"b","value":"9.99 EUR"}],"color":[{"id":"c","value":2}],"colors":[{"id":"d","value":1},{"id":"e","value":2}],"name":[{"id":"f","value":"n"}]}`), reflect.ValueOf(items).Index(0))
+	assert.Nil(err)
+	assert.Equal(Item{
+		Id:     uuid.MustParse("64795211-c4a1-4eac-9e0a-b674ced77461"),
+		Price:  Money{1250, "EUR"},
+		Sale:   &Money{999, "EUR"},
+		Color:  Green,
+		Colors: []Color{Red, Green},
+		Name:   "n",
+	}, items[0])
+
+	// a single list property still fills the slice
+	items = make([]Item, 1)
+	assert.Nil(sc.decode(row(`{"colors":[{"id":"d","value":1}]}`), reflect.ValueOf(items).Index(0)))
+	assert.Equal([]Color{Red}, items[0].Colors)
+
+	// errors of the hook are returned
+	err = sc.decode(row(`{"price":[{"id":"a","value":12}]}`), reflect.ValueOf(items).Index(0))
+	assert.NotNil(err)
+	assert.True(strings.Contains(err.Error(), "money is not a string"))
+}
@@ -0,0 +1,432 @@
+package gremgoser
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// pool keeps a set of websocket connections to Gremlin Server and routes each request to the least busy
+// healthy connection. Dead connections are retired and re-dialed with a jittered exponential backoff so the
+// pool gets back to minIdle connections.
+type pool struct {
+	client      *Client
+	mu          sync.Mutex
+	conns       []*poolConn
+	dialing     int                     // number of connections currently being dialed
+	assigned    map[uuid.UUID]*poolConn // assigned maps a request to the connection it was written on
+	replays     map[uuid.UUID][]byte    // replays holds the messages of in-flight requests that are safe to replay
+	sessions    map[uuid.UUID]*poolConn // sessions maps a session to the connection its requests are written on
+	changed     chan struct{}           // changed is closed and replaced whenever capacity frees up or connections change
+	done        chan struct{}           // done is closed when the pool is closed
+	failures    int32                   // failures counts connection failures since the server last answered
+	state       ConnectionState
+	disposed    bool
+	size        int
+	minIdle     int
+	maxInFlight int
+}
+
+// poolConn is a single connection in the pool with its own write queue and workers
+type poolConn struct {
+	conn     dialer
+	requests chan []byte   // requests takes any request routed to this connection and delivers it to the writeWorker
+	quit     chan struct{} // quit stops the workers of the connection once it is retired
+	inFlight int
+	errored  bool
+}
+
+// stop marks the connection as errored and stops its workers, it must be called with the lock held
+func (pc *poolConn) stop() {
+	if !pc.errored {
+		pc.errored = true
+		close(pc.quit)
+	}
+}
+
+// newPool returns an empty pool for the client, sizes lower than one fall back to a single connection
+func newPool(c *Client, size, minIdle, maxInFlight int) *pool {
+	if size < 1 {
+		size = 1
+	}
+	if minIdle < 1 {
+		minIdle = 1
+	}
+	if minIdle > size {
+		minIdle = size
+	}
+	if maxInFlight < 0 {
+		maxInFlight = 0
+	}
+	return &pool{
+		client:      c,
+		assigned:    make(map[uuid.UUID]*poolConn),
+		replays:     make(map[uuid.UUID][]byte),
+		sessions:    make(map[uuid.UUID]*poolConn),
+		changed:     make(chan struct{}),
+		done:        make(chan struct{}),
+		size:        size,
+		minIdle:     minIdle,
+		maxInFlight: maxInFlight,
+	}
+}
+
+// notify wakes up every request waiting for a connection, it must be called with the lock held
+func (p *pool) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// fill dials connections until the pool holds at least minIdle connections
+func (p *pool) fill() error {
+	for {
+		p.mu.Lock()
+		if p.disposed || len(p.conns)+p.dialing >= p.minIdle {
+			p.mu.Unlock()
+			return nil
+		}
+		p.dialing++
+		p.mu.Unlock()
+		if _, err := p.dial(); err != nil {
+			return err
+		}
+	}
+}
+
+// dial opens a new connection, adds it to the pool and starts its workers. The caller must have
+// incremented p.dialing beforehand.
+func (p *pool) dial() (*poolConn, error) {
+	c := p.client
+	ws := newWs(c.conf)
+	err := ws.connect()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dialing--
+	if err != nil {
+		c.debug("error connecting to %s: %s", c.conf.URI, err)
+		p.notify()
+		return nil, err
+	}
+	pc := &poolConn{
+		conn:     ws,
+		requests: make(chan []byte, 3),
+		quit:     make(chan struct{}),
+	}
+	if p.disposed {
+		ws.close()
+		return nil, ErrorConnectionDisposed
+	}
+	p.conns = append(p.conns, pc)
+	p.notify()
+
+	pings := make(chan error)
+	go c.writeWorker(pc)
+	go c.readWorker(pc)
+	go ws.ping(pings)
+	go c.pingWorker(pc, pings)
+
+	return pc, nil
+}
+
+// healthy reports whether the connection can take new requests, it must be called with the lock held
+func (p *pool) healthy(pc *poolConn) bool {
+	return !pc.errored && pc.conn.isConnected() && (p.maxInFlight == 0 || pc.inFlight < p.maxInFlight)
+}
+
+// acquire returns the connection a request should be written on. Requests that already have a connection, like
+// the authentication reply to a challenge, stay on it. Otherwise an idle healthy connection is picked, the pool
+// grows up to its size when every connection is busy, and only then are requests shared with the least busy one or
+// wait for capacity. A connection that cannot be dialed leaves the requests on the connections there are.
+func (p *pool) acquire(ctx context.Context, id uuid.UUID) (*poolConn, error) {
+	var timeout <-chan time.Time // a zero WritingWait waits until capacity frees up or the context is done
+	if p.client.conf.WritingWait > 0 {
+		timer := time.NewTimer(p.client.conf.WritingWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	grow := true // grow is cleared once dialing failed while other connections were there
+	for {
+		p.mu.Lock()
+		if p.disposed {
+			p.mu.Unlock()
+			return nil, ErrorConnectionDisposed
+		}
+		if pc, ok := p.assigned[id]; ok && !pc.errored {
+			p.mu.Unlock()
+			return pc, nil
+		}
+		var best *poolConn
+		for _, pc := range p.conns {
+			if p.healthy(pc) && (best == nil || pc.inFlight < best.inFlight) {
+				best = pc
+			}
+		}
+		canGrow := grow && len(p.conns)+p.dialing < p.size
+		if best != nil && (best.inFlight == 0 || !canGrow) {
+			best.inFlight++
+			p.assigned[id] = best
+			p.mu.Unlock()
+			return best, nil
+		}
+		if canGrow {
+			p.dialing++
+			p.mu.Unlock()
+			if _, err := p.dial(); err != nil {
+				if best == nil {
+					return nil, ErrorWSConnection
+				}
+				grow = false
+			}
+			continue
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			return nil, ErrorNoConnection
+		}
+	}
+}
+
+// acquireSession returns the connection a request of a session should be written on. The first request picks a
+// connection like acquire and pins the session to it, since Gremlin Server drops a session when its connection
+// closes. Once that connection is retired the session is lost.
+func (p *pool) acquireSession(ctx context.Context, session, id uuid.UUID) (*poolConn, error) {
+	p.mu.Lock()
+	if pc, ok := p.sessions[session]; ok {
+		defer p.mu.Unlock()
+		if p.disposed {
+			return nil, ErrorConnectionDisposed
+		}
+		if pc.errored {
+			return nil, ErrorSessionLost
+		}
+		pc.inFlight++
+		p.assigned[id] = pc
+		return pc, nil
+	}
+	p.mu.Unlock()
+
+	pc, err := p.acquire(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.sessions[session] = pc
+	p.mu.Unlock()
+	return pc, nil
+}
+
+// unpin forgets the connection of a closed session
+func (p *pool) unpin(session uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sessions, session)
+}
+
+// release frees the capacity a request held on its connection
+func (p *pool) release(id uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pc, ok := p.assigned[id]
+	if !ok {
+		return
+	}
+	delete(p.assigned, id)
+	delete(p.replays, id)
+	pc.inFlight--
+	p.notify()
+}
+
+// replayable registers the message of a request that may be written again if its connection is lost
+func (p *pool) replayable(id uuid.UUID, msg []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.replays[id] = msg
+}
+
+// alive resets the backoff once the server answers on any connection
+func (p *pool) alive() {
+	if atomic.LoadInt32(&p.failures) != 0 {
+		atomic.StoreInt32(&p.failures, 0)
+	}
+}
+
+// retire removes a dead connection from the pool, closes it and starts recovering the pool. The requests in
+// flight on it that cannot be replayed fail with ErrorConnectionLost.
+func (p *pool) retire(pc *poolConn) {
+	p.mu.Lock()
+	pc.stop()
+	for i, conn := range p.conns {
+		if conn == pc {
+			p.conns = append(p.conns[:i], p.conns[i+1:]...)
+			break
+		}
+	}
+	replays := make(map[uuid.UUID][]byte)
+	var lost []uuid.UUID
+	for id, conn := range p.assigned {
+		if conn == pc {
+			delete(p.assigned, id)
+			if msg, ok := p.replays[id]; ok {
+				replays[id] = msg
+				delete(p.replays, id) // replay registers the request again
+			} else {
+				lost = append(lost, id)
+			}
+		}
+	}
+	disposed := p.disposed
+	p.notify()
+	p.mu.Unlock()
+
+	if !pc.conn.isDisposed() {
+		pc.conn.close()
+	}
+	if len(lost) > 0 {
+		go p.client.failRequests(lost, ErrorConnectionLost)
+	}
+	if !disposed {
+		go p.recover(replays)
+	}
+}
+
+// recover re-dials with a jittered exponential backoff until the pool holds minIdle connections again, then
+// replays the idempotent requests that were in flight on the retired connection. Once ReconnectAttempts are
+// exhausted those requests fail with ErrorReconnectFailed.
+func (p *pool) recover(replays map[uuid.UUID][]byte) {
+	c := p.client
+	attempts := 0
+	for {
+		p.mu.Lock()
+		if p.disposed {
+			p.mu.Unlock()
+			return
+		}
+		if len(p.conns)+p.dialing >= p.minIdle {
+			p.mu.Unlock()
+			break
+		}
+		p.dialing++
+		p.mu.Unlock()
+
+		c.setState(StateReconnecting)
+		wait := p.backoff(atomic.AddInt32(&p.failures, 1))
+		c.debug("reconnecting to %s in %s", c.conf.URI, wait)
+		select {
+		case <-time.After(wait):
+		case <-p.done:
+			p.mu.Lock()
+			p.dialing--
+			p.mu.Unlock()
+			return
+		}
+		if _, err := p.dial(); err != nil {
+			attempts++
+			if c.conf.ReconnectAttempts > 0 && attempts >= c.conf.ReconnectAttempts {
+				c.setState(StateDisconnected)
+				ids := make([]uuid.UUID, 0, len(replays))
+				for id := range replays {
+					ids = append(ids, id)
+				}
+				c.failRequests(ids, ErrorReconnectFailed)
+				return
+			}
+			continue
+		}
+		c.setState(StateConnected)
+	}
+	for id, msg := range replays {
+		c.replay(id, msg)
+	}
+}
+
+// backoff returns the delay before the next reconnect attempt, it doubles with every failure up to the
+// configured maximum and is jittered so pooled connections do not re-dial in lockstep
+func (p *pool) backoff(failures int32) time.Duration {
+	min, max := p.client.conf.ReconnectBackoff, p.client.conf.ReconnectMaxBackoff
+	if min <= 0 {
+		min = 100 * time.Millisecond
+	}
+	if max < min {
+		max = 30 * time.Second
+	}
+	d := min
+	for i := int32(1); i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// reconnect re-dials every connection that lost its link to the server and refills the pool
+func (p *pool) reconnect() error {
+	p.mu.Lock()
+	conns := make([]*poolConn, len(p.conns))
+	copy(conns, p.conns)
+	p.mu.Unlock()
+	for _, pc := range conns {
+		if !pc.conn.isConnected() {
+			if err := pc.conn.connect(); err != nil {
+				return err
+			}
+		}
+	}
+	return p.fill()
+}
+
+// isConnected reports whether at least one connection of the pool is connected
+func (p *pool) isConnected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pc := range p.conns {
+		if !pc.errored && pc.conn.isConnected() {
+			return true
+		}
+	}
+	return false
+}
+
+// isDisposed reports whether the pool has been closed
+func (p *pool) isDisposed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.disposed
+}
+
+// close closes every connection and marks the pool as disposed
+func (p *pool) close() error {
+	p.mu.Lock()
+	if p.disposed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.disposed = true
+	close(p.done)
+	conns := p.conns
+	p.conns = nil
+	for _, pc := range conns {
+		pc.stop()
+	}
+	p.notify()
+	p.mu.Unlock()
+
+	var err error
+	for _, pc := range conns {
+		if cerr := pc.conn.close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,429 @@
+package gremgoser
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/intwinelabs/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestPoolConn adds a connected connection without workers to the client pool
+func newTestPoolConn(c *Client) *poolConn {
+	pc := &poolConn{
+		conn:     &Ws{connected: true, quit: make(chan struct{})},
+		requests: make(chan []byte, 3),
+		quit:     make(chan struct{}),
+	}
+	c.pool.mu.Lock()
+	c.pool.conns = append(c.pool.conns, pc)
+	c.pool.mu.Unlock()
+	return pc
+}
+
+// TestPoolAcquireLeastBusy tests that requests are routed to the connection with the fewest requests in flight
+func TestPoolAcquireLeastBusy(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{Logger: logger.New(), WritingWait: time.Second}
+	pc1 := newTestPoolConn(c)
+	pc2 := newTestPoolConn(c)
+
+	id1, id2, id3 := uuid.New(), uuid.New(), uuid.New()
+	_pc, err := c.pool.acquire(context.Background(), id1)
+	assert.Nil(err)
+	assert.Equal(pc1, _pc)
+	_pc, err = c.pool.acquire(context.Background(), id2)
+	assert.Nil(err)
+	assert.Equal(pc2, _pc)
+
+	// a request keeps its connection
+	_pc, err = c.pool.acquire(context.Background(), id1)
+	assert.Nil(err)
+	assert.Equal(pc1, _pc)
+	assert.Equal(1, pc1.inFlight)
+
+	// releasing makes the connection the least busy one
+	c.pool.release(id1)
+	assert.Equal(0, pc1.inFlight)
+	_pc, err = c.pool.acquire(context.Background(), id3)
+	assert.Nil(err)
+	assert.Equal(pc1, _pc)
+
+	// errored connections are skipped
+	c.pool.mu.Lock()
+	pc1.stop()
+	c.pool.mu.Unlock()
+	_pc, err = c.pool.acquire(context.Background(), uuid.New())
+	assert.Nil(err)
+	assert.Equal(pc2, _pc)
+}
+
+// TestPoolAcquireMaxInFlight tests that a request waits for capacity when every connection is at max in flight
+func TestPoolAcquireMaxInFlight(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{Logger: logger.New(), WritingWait: time.Second}
+	c.pool = newPool(c, 1, 1, 1)
+	pc := newTestPoolConn(c)
+
+	id := uuid.New()
+	_, err := c.pool.acquire(context.Background(), id)
+	assert.Nil(err)
+
+	// the pool is exhausted, the context expires
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = c.pool.acquire(ctx, uuid.New())
+	assert.Equal(context.DeadlineExceeded, err)
+
+	// the pool is exhausted until the first request is released
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		c.pool.release(id)
+	}()
+	_pc, err := c.pool.acquire(context.Background(), uuid.New())
+	assert.Nil(err)
+	assert.Equal(pc, _pc)
+}
+
+// TestPoolRetire tests that a retired connection is removed and its requests are unassigned
+func TestPoolRetire(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{Logger: logger.New(), WritingWait: time.Second}
+	pc := newTestPoolConn(c)
+	c.pool.mu.Lock()
+	c.pool.disposed = true // keeps retire from dialing a replacement
+	c.pool.mu.Unlock()
+
+	c.pool.assigned[uuid.New()] = pc
+	c.pool.retire(pc)
+	assert.True(pc.errored)
+	assert.Equal(0, len(c.pool.conns))
+	assert.Equal(0, len(c.pool.assigned))
+}
+
+// TestConnectionErroredUnread tests that connection failures do not wait on an error channel nobody reads
+func TestConnectionErroredUnread(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{Logger: logger.New(), WritingWait: time.Second}
+	c.errs = make(chan error, 1)
+	c.pool.mu.Lock()
+	c.pool.disposed = true // keeps retire from dialing a replacement
+	c.pool.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			c.connectionErrored(newTestPoolConn(c), ErrorWSConnection)
+		}
+		c.reportError(ErrorWSConnection)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail("connectionErrored blocked on the error channel")
+	}
+	assert.True(c.HasErrored())
+	assert.Equal(1, len(c.errs))
+}
+
+// TestPoolClient tests that a pooled client spreads concurrent queries over its connections
+func TestPoolClient(t *testing.T) {
+	assert := assert.New(t)
+
+	// Create test server with the mock handler.
+	s := httptest.NewServer(http.HandlerFunc(mock))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conf := NewClientConfig(u)
+	conf.SetPoolSize(3)
+	conf.SetMinIdle(2)
+	conf.SetMaxInFlight(1)
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	assert.Equal(2, len(g.pool.conns))
+	assert.True(g.IsConnected())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := g.Execute(gremV, nil, nil)
+			assert.Nil(err)
+			assert.Nil(resp)
+		}()
+	}
+	wg.Wait()
+	assert.True(len(g.pool.conns) <= 3)
+	assert.Equal(0, len(g.pool.assigned))
+
+	g.Close()
+	assert.True(g.pool.isDisposed())
+	_, err := g.Execute(gremV, nil, nil)
+	assert.Equal(ErrorConnectionDisposed, err)
+}
+
+// TestPoolGrow tests that concurrent requests open more connections than MinIdle before they share one, without a
+// MaxInFlight set
+func TestPoolGrow(t *testing.T) {
+	assert := assert.New(t)
+
+	// every answer takes a while so the requests overlap
+	m := &recordingMock{answer: func(string) (int, []interface{}) {
+		time.Sleep(100 * time.Millisecond)
+		return 200, nil
+	}}
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conf := NewClientConfig(u)
+	conf.SetPoolSize(4)
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	assert.Equal(1, len(g.pool.conns))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := g.Execute(gremV, nil, nil)
+			assert.Nil(err)
+		}()
+	}
+	wg.Wait()
+	g.pool.mu.Lock()
+	conns := len(g.pool.conns)
+	g.pool.mu.Unlock()
+	assert.True(conns > 1, "%d connections", conns)
+	assert.True(conns <= 4, "%d connections", conns)
+}
+
+// TestPoolBackoff tests that the reconnect delay grows exponentially, is jittered and capped
+func TestPoolBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{Logger: logger.New()}
+	c.conf.SetReconnectBackoff(100*time.Millisecond, time.Second)
+
+	for failures, max := range []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		if failures == 0 {
+			continue
+		}
+		d := c.pool.backoff(int32(failures))
+		assert.True(d >= max/2 && d <= max, "failures: %d, backoff: %s", failures, d)
+	}
+}
+
+// TestIsIdempotent tests the detection of scripts that are safe to replay
+func TestIsIdempotent(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isIdempotent(gremV))
+	assert.True(isIdempotent(gremGet))
+	assert.False(isIdempotent(gremV1))
+	assert.False(isIdempotent(gremDropV1))
+	assert.False(isIdempotent(gremE))
+	assert.False(isIdempotent(gremUpdateV1))
+}
+
+// TestReconnect tests that a lost connection is re-dialed and the in-flight read-only request is replayed
+func TestReconnect(t *testing.T) {
+	assert := assert.New(t)
+
+	// the first connection drops the first request without answering it
+	var dialed int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&dialed, 1) == 1 {
+			c, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			c.ReadMessage()
+			c.Close()
+			return
+		}
+		mock(w, r)
+	}))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	var mu sync.Mutex
+	states := []ConnectionState{}
+	conf := NewClientConfig(u)
+	conf.SetReconnectBackoff(10*time.Millisecond, 50*time.Millisecond)
+	conf.SetReplayIdempotent()
+	conf.SetStateCallback(func(state ConnectionState) {
+		mu.Lock()
+		states = append(states, state)
+		mu.Unlock()
+	})
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+
+	// setup err channel, the dropped connection is reported
+	go func(chan error) {
+		err := <-errs
+		assert.NotNil(err)
+	}(errs)
+
+	resp, err := g.Execute(gremV, nil, nil)
+	assert.Nil(err)
+	assert.Nil(resp)
+	assert.True(g.IsConnected())
+	assert.Equal(int32(2), atomic.LoadInt32(&dialed))
+
+	g.Close()
+	mu.Lock()
+	assert.Equal([]ConnectionState{StateConnecting, StateConnected, StateReconnecting, StateConnected, StateClosed}, states)
+	mu.Unlock()
+}
+
+// dropMock drops the connection after the first request it receives, the first dial is upgraded and later dials
+// are answered like mock or, with refuse set, hung up on before the handshake
+func dropMock(dialed *int32, refuse bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(dialed, 1) == 1 {
+			c, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			c.ReadMessage()
+			c.Close()
+			return
+		}
+		if !refuse {
+			mock(w, r)
+			return
+		}
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+		}
+	}
+}
+
+// TestReconnectLost tests that a request that cannot be replayed fails as soon as its connection is lost
+func TestReconnectLost(t *testing.T) {
+	assert := assert.New(t)
+
+	var dialed int32
+	s := httptest.NewServer(dropMock(&dialed, false))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conf := NewClientConfig(u)
+	conf.SetReconnectBackoff(10*time.Millisecond, 50*time.Millisecond)
+	conf.SetReplayIdempotent()
+	conf.SetReadingWait(3)
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel, the dropped connections are reported
+	go func(chan error) {
+		for err := range errs {
+			assert.NotNil(err)
+		}
+	}(errs)
+
+	start := time.Now()
+	_, err := g.Execute("g.addV('x')", nil, nil)
+	assert.Equal(ErrorConnectionLost, err)
+	assert.True(time.Since(start) < time.Second)
+	pending := 0
+	g.responseNotifier.Range(func(key, value interface{}) bool {
+		pending++
+		return true
+	})
+	assert.Equal(0, pending)
+
+	// the pool recovers for the next request
+	_, err = g.Execute(gremV, nil, nil)
+	assert.Nil(err)
+
+	// streams get the error as their last frame
+	atomic.StoreInt32(&dialed, 0)
+	g.pool.mu.Lock()
+	pc := g.pool.conns[0]
+	g.pool.mu.Unlock()
+	g.connectionErrored(pc, ErrorMissedPong)
+	stream, err := g.ExecuteStream(context.Background(), "g.addV('x')", nil, nil)
+	assert.Nil(err)
+	assert.False(stream.Next())
+	assert.Equal(ErrorConnectionLost, stream.Err())
+	stream.Close()
+}
+
+// TestReconnectFailed tests that a replayable request fails once the reconnect attempts are exhausted
+func TestReconnectFailed(t *testing.T) {
+	assert := assert.New(t)
+
+	var dialed int32
+	s := httptest.NewServer(dropMock(&dialed, true))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conf := NewClientConfig(u)
+	conf.SetReconnectBackoff(10*time.Millisecond, 50*time.Millisecond)
+	conf.SetReconnectAttempts(2)
+	conf.SetReplayIdempotent()
+	conf.SetReadingWait(3)
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel, the dropped connection is reported
+	go func(chan error) {
+		err := <-errs
+		assert.NotNil(err)
+	}(errs)
+
+	start := time.Now()
+	_, err := g.Execute(gremV, nil, nil)
+	assert.Equal(ErrorReconnectFailed, err)
+	assert.True(time.Since(start) < time.Second)
+	assert.False(g.IsConnected())
+}
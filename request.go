@@ -1,10 +1,11 @@
 package gremgoser
 
 import (
+	"context"
 	"encoding/base64"
-	"encoding/json"
 
 	"github.com/google/uuid"
+	"github.com/intwinelabs/gremgoser/traversal"
 )
 
 type requester interface {
@@ -29,6 +30,42 @@ func prepareRequest(query string, bindings, rebindings map[string]interface{}) *
 	return req
 }
 
+// prepareBytecodeRequest packages a traversal as bytecode for the traversal processor of Gremlin Server. The g the
+// bytecode is spawned from is aliased to the traversal source the traversal was built from.
+func prepareBytecodeRequest(t *traversal.Traversal) *GremlinRequest {
+	req := &GremlinRequest{}
+	req.RequestId = uuid.New()
+	req.Op = "bytecode"
+	req.Processor = "traversal"
+
+	req.Args = make(map[string]interface{})
+	req.Args["gremlin"] = t.Bytecode()
+	req.Args["aliases"] = map[string]interface{}{"g": t.Source()}
+
+	return req
+}
+
+// prepareSessionRequest packages a query for the session processor of Gremlin Server
+func prepareSessionRequest(session uuid.UUID, query string, bindings, rebindings map[string]interface{}) *GremlinRequest {
+	req := prepareRequest(query, bindings, rebindings)
+	req.Processor = "session"
+	req.Args["session"] = session.String()
+
+	return req
+}
+
+// prepareSessionCloseRequest creates a request asking Gremlin Server to close a session
+func prepareSessionCloseRequest(session uuid.UUID) *GremlinRequest {
+	req := &GremlinRequest{}
+	req.RequestId = uuid.New()
+	req.Op = "close"
+	req.Processor = "session"
+	req.Args = make(map[string]interface{})
+	req.Args["session"] = session.String()
+
+	return req
+}
+
 // prepareAuthRequest creates a ws request for Gremlin Server
 func prepareAuthRequest(requestId uuid.UUID, username, password string) *GremlinRequest {
 	req := &GremlinRequest{}
@@ -51,21 +88,52 @@ func prepareAuthRequest(requestId uuid.UUID, username, password string) *Gremlin
 	return req
 }
 
-// formatMessage takes a request type and formats it into being able to be delivered to Gremlin Server
+// prepareCloseRequest creates a request asking Gremlin Server to stop sending results for a request
+func prepareCloseRequest(requestId uuid.UUID) *GremlinRequest {
+	req := &GremlinRequest{}
+	req.RequestId = requestId
+	req.Op = "close"
+	req.Processor = ""
+	req.Args = make(map[string]interface{})
+
+	return req
+}
+
+// packageRequest formats a request to be delivered to Gremlin Server with the default GraphSON v2 serializer
 func packageRequest(req *GremlinRequest) ([]byte, error) {
-	msg := []byte{}
-	j, err := json.Marshal(req) // Formats request into byte format
+	return graphSONv2.serializeRequest(req)
+}
+
+// dispatchRequest routes the request to a pooled connection for writing to the remote Gremlin Server
+func (c *Client) dispatchRequest(ctx context.Context, id uuid.UUID, msg []byte) error {
+	c.verbose("dispatching request: %s", msg)
+	pc, err := c.pool.acquire(ctx, id)
 	if err != nil {
-		return msg, err
+		return err
 	}
-	mimeType := []byte("!application/vnd.gremlin-v2.0+json")
-	msg = append(mimeType, j...)
+	return c.writeRequest(ctx, pc, id, msg)
+}
 
-	return msg, nil
+// dispatchSessionRequest routes a request of a session to the connection the session is pinned to
+func (c *Client) dispatchSessionRequest(ctx context.Context, session, id uuid.UUID, msg []byte) error {
+	c.verbose("dispatching request of session %s: %s", session, msg)
+	pc, err := c.pool.acquireSession(ctx, session, id)
+	if err != nil {
+		return err
+	}
+	return c.writeRequest(ctx, pc, id, msg)
 }
 
-// dispatchRequest sends the request for writing to the remote Gremlin Server
-func (c *Client) dispatchRequest(msg []byte) {
-	c.verbose("dispatching request: %s", msg)
-	c.requests <- msg
+// writeRequest queues the request on its connection
+func (c *Client) writeRequest(ctx context.Context, pc *poolConn, id uuid.UUID, msg []byte) error {
+	select {
+	case pc.requests <- msg:
+		return nil
+	case <-pc.quit:
+		c.pool.release(id)
+		return ErrorWSConnection
+	case <-ctx.Done():
+		c.pool.release(id)
+		return ctx.Err()
+	}
 }
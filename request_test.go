@@ -1,6 +1,7 @@
 package gremgoser
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -95,8 +96,10 @@ func TestRequestDispatch(t *testing.T) {
 	assert.NotNil(c)
 	msg, err := packageRequest(req)
 	assert.Nil(err)
-	c.dispatchRequest(msg)
-	_req := <-c.requests // c.requests is the channel where all requests are sent for writing to Gremlin Server, write workers listen on this channel
+	pc := newTestPoolConn(c)
+	err = c.dispatchRequest(context.Background(), id, msg)
+	assert.Nil(err)
+	_req := <-pc.requests // pc.requests is the channel where all requests routed to a connection are sent for writing to Gremlin Server, write workers listen on this channel
 	assert.Equal(_req, msg)
 }
 
@@ -113,8 +116,10 @@ func TestAuthRequestDispatch(t *testing.T) {
 	assert.NotNil(c)
 	msg, err := packageRequest(req)
 	assert.Nil(err)
-	c.dispatchRequest(msg)
-	_req := <-c.requests // c.requests is the channel where all requests are sent for writing to Gremlin Server, write workers listen on this channel
+	pc := newTestPoolConn(c)
+	err = c.dispatchRequest(context.Background(), id, msg)
+	assert.Nil(err)
+	_req := <-pc.requests // pc.requests is the channel where all requests routed to a connection are sent for writing to Gremlin Server, write workers listen on this channel
 	assert.Equal(_req, msg)
 }
 
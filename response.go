@@ -2,22 +2,28 @@ package gremgoser
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// handleResponse sorts a frame read from Gremlin Server. Error statuses are delivered to the requester by
+// saveResponse, only frames that cannot be decoded are returned.
 func (c *Client) handleResponse(msg []byte) error {
-	resp, err := marshalResponse(msg)
-	if err != nil && err != Error407Authenticate {
+	resp, err := c.serializer().deserializeResponse(msg)
+	var gerr *GremlinError
+	if err != nil && !errors.As(err, &gerr) {
 		c.debug("error handling response: %s", err)
-		c.saveResponse(resp)
 		return err
 	}
 
 	c.verbose("handling response: %+v", resp)
 
+	c.charge.add(float64(resp.Status.Attributes.XMsRequestCharge))
 	if resp.Status.Code == 407 { //Server request authentication
 		return c.authenticate(resp.RequestId)
 	}
@@ -39,14 +45,159 @@ func marshalResponse(msg []byte) (*GremlinResponse, error) {
 
 	err = responseDetectError(resp.Status.Code)
 	if err != nil {
-		return resp, err
+		return resp, newGremlinError(resp, err)
 	}
 	return resp, nil
 }
 
+// newGremlinError wraps the sentinel error of a response status with the details sent by the server
+func newGremlinError(resp *GremlinResponse, err error) *GremlinError {
+	return &GremlinError{
+		Code:       resp.Status.Code,
+		Message:    resp.Status.Message,
+		RequestId:  resp.RequestId,
+		Attributes: resp.Status.Attributes,
+		err:        err,
+	}
+}
+
+// UnmarshalJSON decodes the known Cosmos DB attributes and keeps every attribute in Raw. GraphSON v3 sends the
+// attributes as typed g:Map, which is decoded first.
+func (a *GremlinStatusAttributes) UnmarshalJSON(b []byte) error {
+	var raw interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+	decoded, err := decodeGraphSON(raw)
+	if err != nil {
+		return err
+	}
+	attrs, ok := decoded.(map[string]interface{})
+	if !ok {
+		*a = GremlinStatusAttributes{}
+		return nil
+	}
+	if _, typed := raw.(map[string]interface{})["@type"]; typed {
+		if b, err = json.Marshal(attrs); err != nil {
+			return err
+		}
+	}
+	type attributes GremlinStatusAttributes // attributes has no methods, avoiding recursion
+	var known attributes
+	if err := json.Unmarshal(b, &known); err != nil {
+		return err
+	}
+	*a = GremlinStatusAttributes(known)
+	a.Raw = attrs
+	return nil
+}
+
+// UnmarshalJSON decodes the result data. Data keeps the results that are JSON objects as they were sent, typed
+// GraphSON v3 objects untyped, Values holds every result with its GraphSON types decoded. The g:List GraphSON v3 wraps the data in is unwrapped and
+// traversers are expanded by their bulk.
+func (r *GremlinResult) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Data interface{} `json:"data"`
+		Meta interface{} `json:"meta"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+	meta, err := decodeGraphSON(raw.Meta)
+	if err != nil {
+		return err
+	}
+	*r = GremlinResult{Meta: meta}
+
+	var items []interface{}
+	switch data := raw.Data.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		items = data
+	case map[string]interface{}:
+		if typ, value, typed := graphSONType(data); typed && typ == "g:List" {
+			if items, typed = value.([]interface{}); !typed {
+				return fmt.Errorf("gremgoser: graphson %s is not a list: %v", typ, value)
+			}
+		} else {
+			items = []interface{}{data}
+		}
+	default:
+		items = []interface{}{data}
+	}
+	values, err := decodeGraphSONList(items)
+	if err != nil {
+		return err
+	}
+	r.Values = expandTraversers(values)
+	for i, item := range items {
+		switch data := item.(type) {
+		case map[string]interface{}:
+			if _, _, typed := graphSONType(data); typed { // GraphSON v3 elements are kept untyped like v2 sends them
+				for _, v := range expandTraversers(values[i : i+1]) {
+					r.appendData(v)
+				}
+				continue
+			}
+			d := GremlinRespData(data)
+			r.Data = append(r.Data, &d)
+		case nil:
+			r.Data = append(r.Data, nil)
+		}
+	}
+	return nil
+}
+
+// appendData appends a decoded result to Data if it is an object, elements are rendered the way untyped GraphSON
+// sends them so the struct helpers read them whatever the serializer is
+func (r *GremlinResult) appendData(v interface{}) {
+	switch obj := untypedGraphSON(v).(type) {
+	case map[string]interface{}:
+		d := GremlinRespData(obj)
+		r.Data = append(r.Data, &d)
+	case nil:
+		r.Data = append(r.Data, nil)
+	}
+}
+
+// expandTraversers replaces every traverser by its value repeated bulk times
+func expandTraversers(values []interface{}) []interface{} {
+	expanded := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		t, ok := v.(*Traverser)
+		if !ok {
+			expanded = append(expanded, v)
+			continue
+		}
+		for i := int64(0); i < t.Bulk; i++ {
+			expanded = append(expanded, t.Value)
+		}
+	}
+	return expanded
+}
+
 // saveResponse makes the response available for retrieval by the requester. Mutexes are used for thread safety.
+// The terminating frame notifies the requester with the error of its status, nil on success. Frames of requests
+// nobody waits on anymore, like late frames after a timeout, are discarded.
 func (c *Client) saveResponse(resp *GremlinResponse) {
+	if n, ok := c.responseNotifier.Load(resp.RequestId); ok {
+		if stream, ok := n.(*ResultStream); ok { // streamed requests get every frame handed over as it arrives
+			stream.deliver(resp)
+			return
+		}
+	}
 	c.respMutex.Lock()
+	defer c.respMutex.Unlock()
+	respNotifier, ok := c.responseNotifier.Load(resp.RequestId)
+	if !ok {
+		c.debug("RequestId: %s, discarding response of abandoned request", resp.RequestId)
+		return
+	}
 	var container []*GremlinRespData
 	existingData, ok := c.results.Load(resp.RequestId) // Retrieve old data container (for requests with multiple responses)
 	if ok {
@@ -56,47 +207,123 @@ func (c *Client) saveResponse(resp *GremlinResponse) {
 	container = append(container, resp.Result.Data...)
 	c.verbose("RequestId: %s, new data: %+v", resp.RequestId, container)
 	c.results.Store(resp.RequestId, container) // Add new data to buffer for future retrieval
-	respNotifier, _ := c.responseNotifier.LoadOrStore(resp.RequestId, make(chan int, 1))
+	var values []interface{}
+	if existingValues, ok := c.values.Load(resp.RequestId); ok {
+		values = existingValues.([]interface{})
+	}
+	c.values.Store(resp.RequestId, append(values, resp.Result.Values...))
+	c.aggregate(resp)
 	if resp.Status.Code != 206 {
-		respNotifier.(chan int) <- 1
+		c.pool.release(resp.RequestId)
+		var respErr error
+		if err := responseDetectError(resp.Status.Code); err != nil {
+			c.debug("RequestId: %s, error response: %s", resp.RequestId, err)
+			respErr = newGremlinError(resp, err)
+		}
+		respNotifier.(chan error) <- respErr
+	}
+}
+
+// failRequests ends requests whose frames will never arrive, like those in flight on a lost connection, with the
+// error. Streams get it as their last frame.
+func (c *Client) failRequests(ids []uuid.UUID, err error) {
+	for _, id := range ids {
+		n, ok := c.responseNotifier.Load(id)
+		if !ok { // the requester stopped waiting
+			continue
+		}
+		switch n := n.(type) {
+		case *ResultStream:
+			n.fail(err)
+		case chan error:
+			c.debug("RequestId: %s, failing request: %s", id, err)
+			c.respMutex.Lock()
+			select {
+			case n <- err:
+			default: // the terminating frame arrived in the meantime
+			}
+			c.respMutex.Unlock()
+		}
 	}
-	c.respMutex.Unlock()
 }
 
-// retrieveResponse retrieves the response saved by saveResponse.
-func (c *Client) retrieveResponse(id uuid.UUID) []*GremlinRespData {
+// retrieveResponse retrieves the response saved by saveResponse. If the context is done or ReadingWait expires
+// before the response arrives the request is abandoned and ctx.Err() or ErrorResponseTimeout is returned.
+func (c *Client) retrieveResponse(ctx context.Context, id uuid.UUID) ([]*GremlinRespData, error) {
+	if _, err := c.waitResponse(ctx, id); err != nil {
+		return nil, err
+	}
 	data := []*GremlinRespData{}
+	if dataI, ok := c.results.Load(id); ok {
+		data = dataI.([]*GremlinRespData)
+	}
+	c.deleteResponse(id)
+	return data, nil
+}
+
+// retrieveValues is like retrieveResponse but returns the results with GraphSON types decoded
+func (c *Client) retrieveValues(ctx context.Context, id uuid.UUID) ([]interface{}, error) {
+	if _, err := c.waitResponse(ctx, id); err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	if valuesI, ok := c.values.Load(id); ok {
+		values = valuesI.([]interface{})
+	}
+	c.deleteResponse(id)
+	return values, nil
+}
+
+// waitResponse waits for the terminating frame of a request and removes its notifier, the results are left
+// for the caller to load. The results are deleted as well when an error is returned, along with the diagnostics
+// of the frames received so far.
+func (c *Client) waitResponse(ctx context.Context, id uuid.UUID) (*Result, error) {
 	resp, _ := c.responseNotifier.Load(id)
-	timeout := make(chan bool, 1)
-	go func() {
-		time.Sleep(c.conf.ReadingWait)
-		timeout <- true
-	}()
+	var timeout <-chan time.Time // a zero ReadingWait waits until the response arrives or the context is done
+	if c.conf.ReadingWait > 0 {
+		timer := time.NewTimer(c.conf.ReadingWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
 	select {
-	case n := <-resp.(chan int):
-		if n == 1 {
-			if dataI, ok := c.results.Load(id); ok {
-				data, ok = dataI.([]*GremlinRespData)
-				if !ok {
-					return nil
-				}
-				close(resp.(chan int))
-				c.responseNotifier.Delete(id)
-				c.deleteResponse(id)
-			}
+	case err := <-resp.(chan error):
+		c.responseNotifier.Delete(id)
+		if err != nil {
+			c.respMutex.Lock()
+			defer c.respMutex.Unlock()
+			return c.deleteResponse(id), err
 		}
+		return nil, nil
 	case <-timeout:
 		// the read from resp ch has timed out
-		c.debug("timeout on response")
-		return nil
+		c.debug("timeout on response: %s", id)
+		return c.abandonResponse(id), ErrorResponseTimeout
+	case <-ctx.Done():
+		c.debug("context done while waiting on response: %s", ctx.Err())
+		return c.abandonResponse(id), ctx.Err()
 	}
-	return data
 }
 
-// deleteRespones deletes the response from the container. Used for cleanup purposes by requester.
-func (c *Client) deleteResponse(id uuid.UUID) {
+// abandonResponse cleans up a request the requester stopped waiting on, frames arriving later are discarded by
+// saveResponse. It returns the diagnostics of the frames received until then.
+func (c *Client) abandonResponse(id uuid.UUID) *Result {
+	c.respMutex.Lock()
+	c.responseNotifier.Delete(id)
+	result := c.deleteResponse(id)
+	c.respMutex.Unlock()
+	c.pool.release(id)
+	return result
+}
+
+// deleteResponse deletes the response from the containers and returns its diagnostics, a zero Result when no
+// frame arrived. Used for cleanup purposes by requester.
+func (c *Client) deleteResponse(id uuid.UUID) *Result {
 	c.results.Delete(id)
-	return
+	c.values.Delete(id)
+	if result, ok := c.diagnostics.LoadAndDelete(id); ok {
+		return result.(*Result)
+	}
+	return &Result{}
 }
 
 // responseDetectError detects any possible errors in responses from Gremlin Server and generates an error for each code
@@ -127,5 +354,4 @@ func responseDetectError(code int) error {
 	default:
 		return ErrorUnknownCode
 	}
-	return nil
 }
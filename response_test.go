@@ -1,7 +1,11 @@
 package gremgoser
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/intwinelabs/logger"
@@ -33,6 +37,8 @@ var dummyPartialResponse2 = []byte(`{"result":{"data":[{"id": "b0a7e695-d43f-48f
  "requestId":"1d6d02bd-8e56-421d-9438-3bd6d0079ff1",
  "status":{"code":200,"attributes":{},"message":""}}`)
 
+var dummyScriptErrorResponse = []byte(`{"requestId":"1d6d02bd-8e56-421d-9438-3bd6d0079ff1","status":{"code":597,"attributes":{"x-ms-status-code":400,"x-ms-request-charge":1.5,"x-ms-activity-id":"7a5d3b1e-2b64-4e4a-9a4f-30a0ea0a47d7","x-ms-substatus-code":1001},"message":"ScriptEvaluationError: unexpected token"},"result":{"data":null,"meta":{}}}`)
+
 var dataMap = &GremlinRespData{"id": id2.String(), "label": "test"}
 
 var dummySuccessfulResponseMarshalled = &GremlinResponse{
@@ -66,12 +72,14 @@ func TestResponseHandling(t *testing.T) {
 	c := newClient(nil)
 	c.conf = &ClientConfig{Logger: logger.New()}
 	assert.NotNil(c)
+	c.responseNotifier.Store(id, make(chan error, 1))
 
 	err := c.handleResponse(dummySuccessfulResponse)
 
 	assert.Nil(err)
 	_resp := dummySuccessfulResponseMarshalled.Result.Data
-	resp := c.retrieveResponse(dummySuccessfulResponseMarshalled.RequestId)
+	resp, err := c.retrieveResponse(context.Background(), dummySuccessfulResponseMarshalled.RequestId)
+	assert.Nil(err)
 	assert.Equal(_resp, resp)
 }
 
@@ -81,15 +89,17 @@ func TestResponseAuthHandling(t *testing.T) {
 	c := newClient(nil)
 	c.conf = &ClientConfig{Logger: logger.New()}
 	c.conf.SetAuthentication("test", "pass")
+	pc := newTestPoolConn(c)
 
 	c.handleResponse(dummyNeedAuthenticationResponse)
 
-	req := c.conf.AuthReq
+	req := *c.conf.AuthReq
+	req.RequestId = id
 
-	sampleAuthRequest, err := packageRequest(req)
+	sampleAuthRequest, err := packageRequest(&req)
 	assert.Nil(err)
 
-	authRequest := <-c.requests //Simulate that client send auth challenge to server
+	authRequest := <-pc.requests //Simulate that client send auth challenge to server
 
 	assert.Equal(authRequest, sampleAuthRequest)
 
@@ -110,6 +120,7 @@ func TestResponseSortingSingleResponse(t *testing.T) {
 
 	c := newClient(nil)
 	c.conf = &ClientConfig{Logger: logger.New()}
+	c.responseNotifier.Store(id, make(chan error, 1))
 
 	c.saveResponse(dummySuccessfulResponseMarshalled)
 
@@ -124,6 +135,7 @@ func TestResponseSortingMultipleResponse(t *testing.T) {
 
 	c := newClient(nil)
 	c.conf = &ClientConfig{Logger: logger.New()}
+	c.responseNotifier.Store(id, make(chan error, 1))
 
 	c.saveResponse(dummyPartialResponse1Marshalled)
 	c.saveResponse(dummyPartialResponse2Marshalled)
@@ -143,11 +155,13 @@ func TestResponseRetrieval(t *testing.T) {
 
 	c := newClient(nil)
 	c.conf = &ClientConfig{Logger: logger.New()}
+	c.responseNotifier.Store(id, make(chan error, 1))
 
 	c.saveResponse(dummyPartialResponse1Marshalled)
 	c.saveResponse(dummyPartialResponse2Marshalled)
 
-	resp := c.retrieveResponse(dummyPartialResponse1Marshalled.RequestId)
+	resp, err := c.retrieveResponse(context.Background(), dummyPartialResponse1Marshalled.RequestId)
+	assert.Nil(err)
 
 	var expected []*GremlinRespData
 	expected = append(expected, dummyPartialResponse1Marshalled.Result.Data...)
@@ -156,12 +170,57 @@ func TestResponseRetrieval(t *testing.T) {
 	assert.Equal(resp, expected)
 }
 
+// TestResponseRetrievalCanceled tests that a done context stops the wait and cleans up the request entries
+func TestResponseRetrievalCanceled(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{Logger: logger.New(), ReadingWait: time.Second}
+	c.responseNotifier.Store(id, make(chan error, 1))
+
+	c.saveResponse(dummyPartialResponse1Marshalled)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp, err := c.retrieveResponse(ctx, dummyPartialResponse1Marshalled.RequestId)
+	assert.Nil(resp)
+	assert.Equal(context.Canceled, err)
+
+	_, ok := c.results.Load(dummyPartialResponse1Marshalled.RequestId)
+	assert.False(ok)
+	_, ok = c.responseNotifier.Load(dummyPartialResponse1Marshalled.RequestId)
+	assert.False(ok)
+}
+
+// TestResponseRetrievalTimeout tests that an expired ReadingWait is reported and frames arriving later are discarded
+func TestResponseRetrievalTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{Logger: logger.New(), ReadingWait: 10 * time.Millisecond}
+	c.responseNotifier.Store(id, make(chan error, 1))
+
+	c.saveResponse(dummyPartialResponse1Marshalled)
+
+	resp, err := c.retrieveResponse(context.Background(), id)
+	assert.Nil(resp)
+	assert.Equal(ErrorResponseTimeout, err)
+
+	// the late terminating frame does not leak into the results
+	c.saveResponse(dummyPartialResponse2Marshalled)
+	_, ok := c.results.Load(id)
+	assert.False(ok)
+	_, ok = c.responseNotifier.Load(id)
+	assert.False(ok)
+}
+
 // TestResponseDeletion tests the ability for a requester to clean up after retrieving a response after delivery to a client
 func TestResponseDeletion(t *testing.T) {
 	assert := assert.New(t)
 
 	c := newClient(nil)
 	c.conf = &ClientConfig{Logger: logger.New()}
+	c.responseNotifier.Store(id, make(chan error, 1))
 
 	c.saveResponse(dummyPartialResponse1Marshalled)
 	c.saveResponse(dummyPartialResponse2Marshalled)
@@ -172,6 +231,29 @@ func TestResponseDeletion(t *testing.T) {
 	assert.False(ok)
 }
 
+// TestResponseGremlinError tests that error statuses carry the server details and wrap the sentinel error
+func TestResponseGremlinError(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := marshalResponse(dummyScriptErrorResponse)
+	assert.True(errors.Is(err, Error597ScriptEvaluationError))
+	assert.Equal("gremgoser: SCRIPT EVALUATION ERROR: ScriptEvaluationError: unexpected token", err.Error())
+
+	var gerr *GremlinError
+	assert.True(errors.As(err, &gerr))
+	assert.Equal(597, gerr.Code)
+	assert.Equal("ScriptEvaluationError: unexpected token", gerr.Message)
+	assert.Equal(id, gerr.RequestId)
+	assert.Equal(400, gerr.Attributes.XMsStatusCode)
+	assert.Equal(float32(1.5), gerr.Attributes.XMsRequestCharge)
+	assert.Equal("7a5d3b1e-2b64-4e4a-9a4f-30a0ea0a47d7", gerr.Attributes.XMsActivityId.String())
+	assert.Equal(json.Number("1001"), gerr.Attributes.Raw["x-ms-substatus-code"])
+
+	// the authentication challenge is still detected
+	_, err = marshalResponse(dummyNeedAuthenticationResponse)
+	assert.True(errors.Is(err, Error407Authenticate))
+}
+
 var codes = []struct {
 	code int
 }{
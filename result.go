@@ -0,0 +1,126 @@
+package gremgoser
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/google/uuid"
+)
+
+// ExecuteResult formats a raw Gremlin query, sends it to Gremlin Server, and returns the results with the request
+// charge, server time, activity id and status code Cosmos DB reported for the request. The error of a failed
+// request is a *GremlinError holding the attributes of its last frame. A request that failed, was throttled or
+// timed out after it was sent still returns its Result, without results but with the diagnostics of the frames
+// that arrived, so it can be accounted for and correlated.
+func (c *Client) ExecuteResult(query string, bindings, rebindings map[string]interface{}) (*Result, error) {
+	return c.ExecuteResultContext(context.Background(), query, bindings, rebindings)
+}
+
+// ExecuteResultContext is the context aware version of ExecuteResult.
+func (c *Client) ExecuteResultContext(ctx context.Context, query string, bindings, rebindings map[string]interface{}) (*Result, error) {
+	if c.pool.isDisposed() {
+		return nil, ErrorConnectionDisposed
+	}
+	c.verbose("query: %s", query)
+	id, err := c.sendRequest(ctx, query, bindings, rebindings)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.retrieveResult(ctx, id)
+	c.verbose("result: %+v", spew.Sprint(result))
+	return result, err
+}
+
+// retrieveResult is like retrieveResponse but returns the results with the aggregated diagnostics of the request,
+// which are returned along with the error of a failed request
+func (c *Client) retrieveResult(ctx context.Context, id uuid.UUID) (*Result, error) {
+	if partial, err := c.waitResponse(ctx, id); err != nil {
+		return partial, err
+	}
+	result := &Result{}
+	if resultI, ok := c.diagnostics.Load(id); ok {
+		result = resultI.(*Result)
+	}
+	result.Data = []*GremlinRespData{}
+	if dataI, ok := c.results.Load(id); ok {
+		result.Data = dataI.([]*GremlinRespData)
+	}
+	if valuesI, ok := c.values.Load(id); ok {
+		result.Values = valuesI.([]interface{})
+	}
+	c.deleteResponse(id)
+	return result, nil
+}
+
+// aggregate adds the status attributes of a frame to the diagnostics of its request, it is called by saveResponse
+// while it holds respMutex
+func (c *Client) aggregate(resp *GremlinResponse) {
+	result := &Result{}
+	if existing, ok := c.diagnostics.Load(resp.RequestId); ok {
+		result = existing.(*Result)
+	} else {
+		c.diagnostics.Store(resp.RequestId, result)
+	}
+	result.add(resp.Status)
+}
+
+// add adds the attributes of a frame. Cosmos DB sends the charge and server time of the frame and the totals of
+// the request so far, the totals are taken when they are sent and the frames are summed otherwise.
+func (r *Result) add(status GremlinStatus) {
+	attrs := status.Attributes
+	r.Frames++
+	r.RequestCharge += float64(attrs.XMsRequestCharge)
+	if attrs.XMsTotalRequestCharge > 0 {
+		r.RequestCharge = float64(attrs.XMsTotalRequestCharge)
+	}
+	r.ServerTimeMs += float64(attrs.XMsServerTimeMs)
+	if attrs.XMsTotalServerTimeMs > 0 {
+		r.ServerTimeMs = float64(attrs.XMsTotalServerTimeMs)
+	}
+	if attrs.XMsActivityId != uuid.Nil {
+		r.ActivityId = attrs.XMsActivityId
+	}
+	r.StatusCode = status.Code
+	if attrs.XMsStatusCode != 0 {
+		r.StatusCode = attrs.XMsStatusCode
+	}
+}
+
+// RequestCharge returns the request units Cosmos DB charged for every frame the client received since it was
+// created or ResetRequestCharge was called, including the frames of failed, streamed and abandoned requests.
+// It can be published with expvar.Func to export it.
+func (c *Client) RequestCharge() float64 {
+	return c.charge.load()
+}
+
+// ResetRequestCharge sets the request charge of the client back to zero and returns the charge it had
+func (c *Client) ResetRequestCharge() float64 {
+	return c.charge.reset()
+}
+
+// requestCharge is a float64 counter the connections add to without a lock
+type requestCharge struct {
+	bits atomic.Uint64
+}
+
+func (r *requestCharge) add(charge float64) {
+	if charge == 0 {
+		return
+	}
+	for {
+		old := r.bits.Load()
+		if r.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+charge)) {
+			return
+		}
+	}
+}
+
+func (r *requestCharge) load() float64 {
+	return math.Float64frombits(r.bits.Load())
+}
+
+func (r *requestCharge) reset() float64 {
+	return math.Float64frombits(r.bits.Swap(0))
+}
@@ -0,0 +1,138 @@
+package gremgoser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var gremResult = `g.V().hasLabel('person')`
+
+var gremResultError = `g.V().hasLabel('person').fail()`
+
+var gremResultThrottled = `g.V().hasLabel('person').out()`
+
+var resultActivityId = uuid.MustParse("b2e2a3f4-0d2c-4e5f-9a1b-2c3d4e5f6a7b")
+
+// resultMock answers like Cosmos DB, with the charge and server time of each frame and the totals of the request
+// in the status attributes
+func resultMock(w http.ResponseWriter, r *http.Request) {
+	c, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	frame := func(id uuid.UUID, code int, attrs GremlinStatusAttributes, data ...*GremlinRespData) error {
+		msg, err := json.Marshal(GremlinResponse{
+			RequestId: id,
+			Status:    GremlinStatus{Code: code, Attributes: attrs},
+			Result:    GremlinResult{Data: data},
+		})
+		if err != nil {
+			return err
+		}
+		return c.WriteMessage(1, msg)
+	}
+	for {
+		_, message, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		msg := bytes.SplitAfter(message, []byte("!application/vnd.gremlin-v2.0+json"))
+		var req GremlinRequest
+		if err := json.Unmarshal(msg[1], &req); err != nil {
+			return
+		}
+		switch req.Args["gremlin"] {
+		case gremResult:
+			frame(req.RequestId, 206, GremlinStatusAttributes{XMsStatusCode: 200, XMsRequestCharge: 1.5, XMsTotalRequestCharge: 1.5, XMsServerTimeMs: 2, XMsTotalServerTimeMs: 2, XMsActivityId: resultActivityId}, &GremlinRespData{"id": "1", "label": "person"})
+			frame(req.RequestId, 200, GremlinStatusAttributes{XMsStatusCode: 200, XMsRequestCharge: 2, XMsTotalRequestCharge: 3.5, XMsServerTimeMs: 1, XMsTotalServerTimeMs: 3, XMsActivityId: resultActivityId}, &GremlinRespData{"id": "2", "label": "person"})
+		case gremResultError:
+			frame(req.RequestId, 597, GremlinStatusAttributes{XMsStatusCode: 400, XMsRequestCharge: 1, XMsTotalRequestCharge: 1, XMsActivityId: resultActivityId})
+		case gremResultThrottled:
+			frame(req.RequestId, 206, GremlinStatusAttributes{XMsStatusCode: 200, XMsRequestCharge: 1.5, XMsActivityId: resultActivityId}, &GremlinRespData{"id": "1", "label": "person"})
+			frame(req.RequestId, 500, GremlinStatusAttributes{XMsStatusCode: 429, XMsRequestCharge: 0.5, XMsActivityId: resultActivityId})
+		}
+	}
+}
+
+func TestExecuteResult(t *testing.T) {
+	assert := assert.New(t)
+
+	s := httptest.NewServer(http.HandlerFunc(resultMock))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	g, errs := NewClient(NewClientConfig(u))
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	// the diagnostics are aggregated over the partial frames
+	result, err := g.ExecuteResult(gremResult, nil, nil)
+	assert.Nil(err)
+	assert.Equal(2, len(result.Data))
+	assert.Equal(2, len(result.Values))
+	assert.Equal(3.5, result.RequestCharge)
+	assert.Equal(3.0, result.ServerTimeMs)
+	assert.Equal(resultActivityId, result.ActivityId)
+	assert.Equal(200, result.StatusCode)
+	assert.Equal(2, result.Frames)
+	pending := 0
+	g.diagnostics.Range(func(key, value interface{}) bool {
+		pending++
+		return true
+	})
+	assert.Equal(0, pending)
+
+	// failed requests return the attributes in the error and the diagnostics in the result, and are charged to the
+	// client as well
+	result, err = g.ExecuteResult(gremResultError, nil, nil)
+	var gerr *GremlinError
+	assert.True(errors.As(err, &gerr))
+	assert.Equal(400, gerr.Attributes.XMsStatusCode)
+	assert.Equal(float32(1), gerr.Attributes.XMsRequestCharge)
+	assert.Equal(&Result{RequestCharge: 1, ActivityId: resultActivityId, StatusCode: 400, Frames: 1}, result)
+
+	// throttled requests are charged for every frame that arrived, the partial results are dropped
+	result, err = g.ExecuteResult(gremResultThrottled, nil, nil)
+	assert.True(errors.Is(err, Error500ServerError))
+	assert.Equal(&Result{RequestCharge: 2, ActivityId: resultActivityId, StatusCode: 429, Frames: 2}, result)
+	g.diagnostics.Range(func(key, value interface{}) bool {
+		pending++
+		return true
+	})
+	assert.Equal(0, pending)
+
+	// requests of the other execute variants are counted too
+	_, err = g.Execute(gremResult, nil, nil)
+	assert.Nil(err)
+	assert.Equal(10.0, g.RequestCharge())
+	assert.Equal(10.0, g.ResetRequestCharge())
+	assert.Equal(0.0, g.RequestCharge())
+}
+
+func TestResultAdd(t *testing.T) {
+	assert := assert.New(t)
+
+	// frames without totals are summed, servers that send no attributes leave the Gremlin status code
+	var r Result
+	r.add(GremlinStatus{Code: 206, Attributes: GremlinStatusAttributes{XMsRequestCharge: 1.25, XMsServerTimeMs: 2}})
+	r.add(GremlinStatus{Code: 206, Attributes: GremlinStatusAttributes{XMsRequestCharge: 0.75, XMsServerTimeMs: 0.5}})
+	r.add(GremlinStatus{Code: 200})
+	assert.Equal(Result{RequestCharge: 2, ServerTimeMs: 2.5, StatusCode: 200, Frames: 3}, r)
+}
@@ -0,0 +1,128 @@
+package gremgoser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// script builds the Gremlin scripts of the struct helpers like AddV. Values are bound as parameters, so the
+// server can cache the script and field values cannot change the query. Servers restricting bindings get the
+// values inlined instead when ClientConfig.DisableBindings is set.
+type script struct {
+	query      strings.Builder
+	bindings   map[string]interface{}
+	inline     bool
+	idToken    bool // idToken sets ids with T.id instead of the 'id' property
+	timeFormat TimeFormat
+}
+
+// newScript returns an empty script honoring the bindings setting of the client
+func (c *Client) newScript() *script {
+	s := &script{
+		bindings:   make(map[string]interface{}),
+		timeFormat: TimeFormatMillis,
+	}
+	if c.conf != nil {
+		s.inline = c.conf.DisableBindings
+		s.idToken = c.conf.IdToken
+		if c.conf.TimeFormat != 0 {
+			s.timeFormat = c.conf.TimeFormat
+		}
+	}
+	return s
+}
+
+// add appends formatted text to the script
+func (s *script) add(format string, args ...interface{}) {
+	fmt.Fprintf(&s.query, format, args...)
+}
+
+// str returns the binding name of a string value or the value as an escaped string literal
+func (s *script) str(v string) string {
+	if s.inline {
+		return "'" + escapeString(v) + "'"
+	}
+	return s.bind(v)
+}
+
+// val returns the binding name of a bool or number value or the value as literal. A time.Duration is its number
+// of nanoseconds, like timeValue writes fields tagged with the time option.
+func (s *script) val(v interface{}) string {
+	if d, ok := v.(time.Duration); ok {
+		v = int64(d) // not its string form
+	}
+	if s.inline {
+		return fmt.Sprintf("%v", v)
+	}
+	return s.bind(v)
+}
+
+// id returns the binding name of a vertex id or the id as literal, integer ids stay numbers so graphs with long
+// ids find their vertices
+func (s *script) id(v interface{}) (string, error) {
+	id, err := idValue(v)
+	if err != nil {
+		return "", err
+	}
+	return s.prop(id), nil
+}
+
+// idOf returns the id of a struct like id, it fails with ErrorInterfaceHasNoIdField when the struct has no id field
+func (s *script) idOf(data interface{}) (string, error) {
+	id, ok := idField(getValue(data))
+	if !ok {
+		return "", ErrorInterfaceHasNoIdField
+	}
+	return s.id(id.Interface())
+}
+
+// prop returns the binding name of a property value or the value as literal, strings are quoted
+func (s *script) prop(v interface{}) string {
+	if str, ok := v.(string); ok {
+		return s.str(str)
+	}
+	return s.val(v)
+}
+
+// json returns the binding name of a JSON document or the document as string literal
+func (s *script) json(doc []byte) string {
+	if s.inline {
+		return "'" + jsonEscaper.Replace(string(doc)) + "'"
+	}
+	return s.bind(string(doc))
+}
+
+// jsonEscaper escapes a JSON document for a single quoted string literal, double quotes need no escaping
+var jsonEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// bind adds a value to the bindings and returns the name it is bound to
+func (s *script) bind(v interface{}) string {
+	name := fmt.Sprintf("_p%d", len(s.bindings))
+	s.bindings[name] = v
+	return name
+}
+
+// truncate drops what was added to the script after its query had length n and it had the given number of
+// bindings
+func (s *script) truncate(n, bindings int) {
+	query := s.query.String()[:n]
+	s.query.Reset()
+	s.query.WriteString(query)
+	for i, end := bindings, len(s.bindings); i < end; i++ {
+		delete(s.bindings, fmt.Sprintf("_p%d", i))
+	}
+}
+
+// String returns the script
+func (s *script) String() string {
+	return s.query.String()
+}
+
+// params returns the bindings to pass along with the script, nil when the values are inlined
+func (s *script) params() map[string]interface{} {
+	if len(s.bindings) == 0 {
+		return nil
+	}
+	return s.bindings
+}
@@ -0,0 +1,28 @@
+package gremgoser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScript(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+
+	// values are bound and never end up in the script
+	evil := "x').drop().V('"
+	q := c.newScript()
+	q.add("g.V(%s).property('a', %s).property('b', %s)", q.str(evil), q.val(10), q.json([]byte(`{"A":"it's"}`)))
+	assert.Equal("g.V(_p0).property('a', _p1).property('b', _p2)", q.String())
+	assert.Equal(map[string]interface{}{"_p0": evil, "_p1": 10, "_p2": `{"A":"it's"}`}, q.params())
+
+	// values are inlined and escaped
+	c.conf.SetDisableBindings()
+	q = c.newScript()
+	q.add("g.V(%s).property('a', %s).property('b', %s)", q.str(evil), q.val(10), q.json([]byte(`{"A":"it's \"q\""}`)))
+	assert.Equal(`g.V('x\').drop().V(\'').property('a', 10).property('b', '{"A":"it\'s \\"q\\""}')`, q.String())
+	assert.Nil(q.params())
+}
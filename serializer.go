@@ -0,0 +1,68 @@
+package gremgoser
+
+import (
+	"encoding/json"
+
+	"github.com/intwinelabs/gremgoser/traversal"
+)
+
+// serializer writes requests and reads responses in one of the formats Gremlin Server speaks
+type serializer interface {
+	serializeRequest(req *GremlinRequest) ([]byte, error)
+	deserializeResponse(msg []byte) (*GremlinResponse, error)
+}
+
+// graphSON is the GraphSON serializer, version 3 types every value of the request. Version 2 sends the arguments
+// of scripts as plain JSON and types the values of bytecode only.
+type graphSON struct {
+	mimeType Serializer
+	typed    bool
+}
+
+var (
+	graphSONv2 = &graphSON{mimeType: SerializerGraphSONv2}
+	graphSONv3 = &graphSON{mimeType: SerializerGraphSONv3, typed: true}
+)
+
+// serializer returns the serializer selected in the client config, GraphSON v2 by default
+func (c *Client) serializer() serializer {
+	if c.conf != nil {
+		switch c.conf.Serializer {
+		case SerializerGraphSONv3:
+			return graphSONv3
+		case SerializerGraphBinary:
+			return graphBinaryV1
+		}
+	}
+	return graphSONv2
+}
+
+func (s *graphSON) serializeRequest(req *GremlinRequest) ([]byte, error) {
+	var body interface{} = req
+	if s.typed {
+		body = map[string]interface{}{
+			"requestId": s.encode(req.RequestId),
+			"op":        req.Op,
+			"processor": req.Processor,
+			"args":      s.encode(req.Args),
+		}
+	} else if bytecode, ok := req.Args["gremlin"].(*traversal.Bytecode); ok {
+		typed := *req
+		typed.Args = make(map[string]interface{}, len(req.Args))
+		for k, v := range req.Args {
+			typed.Args[k] = v
+		}
+		typed.Args["gremlin"] = s.encode(bytecode)
+		body = &typed
+	}
+	j, err := json.Marshal(body) // Formats request into byte format
+	if err != nil {
+		return nil, err
+	}
+	msg := append([]byte{byte(len(s.mimeType))}, s.mimeType...)
+	return append(msg, j...), nil
+}
+
+func (s *graphSON) deserializeResponse(msg []byte) (*GremlinResponse, error) {
+	return marshalResponse(msg)
+}
@@ -0,0 +1,254 @@
+package gremgoser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/intwinelabs/gremgoser/traversal"
+	"github.com/stretchr/testify/assert"
+)
+
+// graphSONv3Mock answers every request with typed GraphSON v3 results
+func graphSONv3Mock(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			mt, message, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			mimeType := []byte("!application/vnd.gremlin-v3.0+json")
+			if !bytes.HasPrefix(message, mimeType) {
+				t.Errorf("unexpected mime type: %s", message)
+				return
+			}
+			var req struct {
+				RequestId map[string]string      `json:"requestId"`
+				Args      map[string]interface{} `json:"args"`
+			}
+			if err := json.Unmarshal(message[len(mimeType):], &req); err != nil {
+				return
+			}
+			resp := `{"requestId":"` + req.RequestId["@value"] + `","status":{"message":"","code":200,"attributes":{"@type":"g:Map","@value":["x-ms-request-charge",{"@type":"g:Double","@value":1.5}]}},"result":{"data":{"@type":"g:List","@value":[` + testGraphSONVertex + `,{"@type":"g:Int64","@value":6},{"@type":"g:Traverser","@value":{"bulk":{"@type":"g:Int64","@value":2},"value":"lop"}}]},"meta":{"@type":"g:Map","@value":[]}}}`
+			if err := c.WriteMessage(mt, []byte(resp)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestSerializeRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	req := prepareRequest("g.V(x)", map[string]interface{}{"x": 1}, nil)
+
+	// GraphSON v2 sends plain JSON
+	msg, err := graphSONv2.serializeRequest(req)
+	assert.Nil(err)
+	assert.True(bytes.HasPrefix(msg, []byte("!application/vnd.gremlin-v2.0+json{")))
+	assert.Contains(string(msg), `"bindings":{"x":1}`)
+
+	// GraphSON v3 types every value
+	msg, err = graphSONv3.serializeRequest(req)
+	assert.Nil(err)
+	assert.True(bytes.HasPrefix(msg, []byte("!application/vnd.gremlin-v3.0+json{")))
+	assert.Contains(string(msg), `"requestId":{"@type":"g:UUID","@value":"`+req.RequestId.String()+`"}`)
+	assert.Contains(string(msg), `"bindings",{"@type":"g:Map","@value":["x",{"@type":"g:Int64","@value":1}]}`)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	assert.Equal(graphSONv2, c.serializer())
+	c.conf.SetSerializer(SerializerGraphSONv3)
+	assert.Equal(graphSONv3, c.serializer())
+}
+
+func TestExecuteValuesGraphSONv3(t *testing.T) {
+	assert := assert.New(t)
+
+	s := httptest.NewServer(graphSONv3Mock(t))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conf := NewClientConfig(u)
+	conf.SetSerializer(SerializerGraphSONv3)
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	values, err := g.ExecuteValues("g.V()", nil, nil)
+	assert.Nil(err)
+	assert.Equal(4, len(values))
+	v, ok := values[0].(*Vertex)
+	assert.True(ok)
+	assert.Equal(int64(1), v.Id)
+	assert.Equal("marko", v.Properties["name"][0].Value)
+	assert.Equal(int64(6), values[1])
+	assert.Equal("lop", values[2])
+	assert.Equal("lop", values[3])
+
+	// Execute returns the elements untyped like GraphSON v2 sends them
+	data, err := g.Execute("g.V()", nil, nil)
+	assert.Nil(err)
+	assert.Equal(1, len(data))
+	assert.Equal("vertex", (*data[0])["type"])
+	assert.Equal(int64(1), (*data[0])["id"])
+
+	// the struct helpers read the same data as with GraphSON v2
+	type person struct {
+		Id   int64  `graph:"id,id"`
+		Name string `graph:"name,string"`
+	}
+	var people []person
+	assert.Nil(g.Get("g.V()", nil, &people))
+	assert.Equal([]person{{Id: 1, Name: "marko"}}, people)
+	one, err := GetOne[person](context.Background(), g, "g.V()", nil)
+	assert.Nil(err)
+	assert.Equal(person{Id: 1, Name: "marko"}, one)
+}
+
+func TestResponseGraphSONv3(t *testing.T) {
+	assert := assert.New(t)
+
+	resp, err := marshalResponse([]byte(`{"requestId":"1d6d02bd-8e56-421d-9438-3bd6d0079ff1","status":{"message":"","code":200,"attributes":{"@type":"g:Map","@value":["x-ms-request-charge",{"@type":"g:Double","@value":1.5},"host","/127.0.0.1:1"]}},"result":{"data":{"@type":"g:List","@value":[{"@type":"g:Int64","@value":6}]},"meta":{"@type":"g:Map","@value":[]}}}`))
+	assert.Nil(err)
+	assert.Equal(float32(1.5), resp.Status.Attributes.XMsRequestCharge)
+	assert.Equal("/127.0.0.1:1", resp.Status.Attributes.Raw["host"])
+	assert.Equal([]interface{}{int64(6)}, resp.Result.Values)
+	assert.Equal(0, len(resp.Result.Data))
+	assert.Equal(map[string]interface{}{}, resp.Result.Meta)
+}
+
+func TestSerializeBytecode(t *testing.T) {
+	assert := assert.New(t)
+
+	req := prepareBytecodeRequest(traversal.NewSource("modern").V().Has("age", traversal.Within(29, 32)).Order().By("age", traversal.Decr).Where(traversal.Anon().Out()))
+	assert.Equal("bytecode", req.Op)
+	assert.Equal("traversal", req.Processor)
+	assert.Equal(map[string]interface{}{"g": "modern"}, req.Args["aliases"])
+
+	// GraphSON v2 types the values of the bytecode only
+	msg, err := graphSONv2.serializeRequest(req)
+	assert.Nil(err)
+	assert.Contains(string(msg), `"aliases":{"g":"modern"}`)
+	assert.Contains(string(msg), `"gremlin":{"@type":"g:Bytecode","@value":{"step":[["V"],["has","age",{"@type":"g:P","@value":{"predicate":"within","value":[{"@type":"g:Int64","@value":29},{"@type":"g:Int64","@value":32}]}}],["order"],["by","age",{"@type":"g:Order","@value":"decr"}],["where",{"@type":"g:Bytecode","@value":{"step":[["out"]]}}]]}}`)
+
+	// GraphSON v3 types the lists too
+	msg, err = graphSONv3.serializeRequest(req)
+	assert.Nil(err)
+	assert.Contains(string(msg), `"aliases",{"@type":"g:Map","@value":["g","modern"]}`)
+	assert.Contains(string(msg), `{"@type":"g:P","@value":{"predicate":"within","value":{"@type":"g:List","@value":[{"@type":"g:Int64","@value":29},{"@type":"g:Int64","@value":32}]}}}`)
+	encoded, err := json.Marshal(graphSONv3.encode(traversal.TextContains("a")))
+	assert.Nil(err)
+	assert.Equal(`{"@type":"g:TextP","@value":{"predicate":"containing","value":"a"}}`, string(encoded))
+	encoded, err = json.Marshal(graphSONv3.encode(traversal.Gt(1).And(traversal.Not(traversal.Eq(2)))))
+	assert.Nil(err)
+	assert.Equal(`{"@type":"g:P","@value":{"predicate":"and","value":{"@type":"g:List","@value":[{"@type":"g:P","@value":{"predicate":"gt","value":{"@type":"g:Int64","@value":1}}},{"@type":"g:P","@value":{"predicate":"not","value":{"@type":"g:P","@value":{"predicate":"eq","value":{"@type":"g:Int64","@value":2}}}}}]}}}`, string(encoded))
+	encoded, err = json.Marshal(graphSONv2.encode(traversal.TId))
+	assert.Nil(err)
+	assert.Equal(`{"@type":"g:T","@value":"id"}`, string(encoded))
+}
+
+// bytecodeMock answers bytecode submitted on the modern alias with a traverser in the format of the request
+func bytecodeMock(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			mt, message, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			mimeType := Serializer(message[1 : 1+message[0]])
+			body := message[1+message[0]:]
+			var resp []byte
+			switch mimeType {
+			case SerializerGraphBinary:
+				req := &graphBinaryReader{buf: body}
+				req.byte() // version
+				id, _ := req.uuid()
+				op, _ := req.string()
+				processor, _ := req.string()
+				args, _ := req.valueOf(gbMap)
+				aliases, _ := args.(map[string]interface{})["aliases"].(map[string]interface{})
+				_, isBytecode := args.(map[string]interface{})["gremlin"].(*traversal.Bytecode)
+				if op != "bytecode" || processor != "traversal" || aliases["g"] != "modern" || !isBytecode {
+					t.Errorf("unexpected request: %s %s %v", op, processor, args)
+				}
+				resp = graphBinaryResponse(id, 200, "", nil, []interface{}{&Traverser{Bulk: 2, Value: "marko"}})
+			default:
+				var req struct {
+					RequestId interface{}            `json:"requestId"`
+					Op        string                 `json:"op"`
+					Processor string                 `json:"processor"`
+					Args      map[string]interface{} `json:"args"`
+				}
+				if err := json.Unmarshal(body, &req); err != nil {
+					t.Error(err)
+					return
+				}
+				id, _ := decodeGraphSON(req.RequestId)
+				args, _ := decodeGraphSON(req.Args)
+				aliases, _ := args.(map[string]interface{})["aliases"].(map[string]interface{})
+				if req.Op != "bytecode" || req.Processor != "traversal" || aliases["g"] != "modern" {
+					t.Errorf("unexpected request: %s", body)
+				}
+				resp = []byte(`{"requestId":"` + fmt.Sprint(id) + `","status":{"message":"","code":200,"attributes":{}},"result":{"data":[{"@type":"g:Traverser","@value":{"bulk":{"@type":"g:Int64","@value":2},"value":"marko"}}],"meta":{}}}`)
+			}
+			if err := c.WriteMessage(mt, resp); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestExecuteBytecode(t *testing.T) {
+	s := httptest.NewServer(bytecodeMock(t))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	for _, serializer := range []Serializer{SerializerGraphSONv2, SerializerGraphSONv3, SerializerGraphBinary} {
+		t.Run(string(serializer), func(t *testing.T) {
+			assert := assert.New(t)
+
+			conf := NewClientConfig(u)
+			conf.SetSerializer(serializer)
+			g, errs := NewClient(conf)
+			assert.NotNil(g)
+			defer g.Close()
+
+			// setup err channel
+			go func(chan error) {
+				err := <-errs
+				assert.Nil(err)
+			}(errs)
+
+			modern := traversal.NewSource("modern")
+			values, err := g.ExecuteBytecode(modern.V().Has("name", traversal.TextStartsWith("m")).Values("name"))
+			assert.Nil(err)
+			assert.Equal([]interface{}{"marko", "marko"}, values)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package gremgoser
+
+import (
+	"context"
+	"sync"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/google/uuid"
+)
+
+// Session runs requests in a Gremlin Server session. The server keeps the variables a script defines for the
+// following scripts of the session, and on graphs like JanusGraph the scripts share one transaction that is
+// committed or rolled back explicitly. All requests of a session are written on the same pooled connection and
+// run one after another. A session must be closed to free its resources on the server.
+type Session struct {
+	client *Client
+	id     uuid.UUID
+	mu     sync.Mutex // mu serializes the requests of the session
+	closed bool
+}
+
+// NewSession returns a new session, the server opens it with its first request
+func (c *Client) NewSession() *Session {
+	return &Session{client: c, id: uuid.New()}
+}
+
+// ID returns the id of the session
+func (s *Session) ID() uuid.UUID {
+	return s.id
+}
+
+// Execute runs a raw Gremlin query in the session and returns the result.
+func (s *Session) Execute(query string, bindings, rebindings map[string]interface{}) ([]*GremlinRespData, error) {
+	return s.ExecuteContext(context.Background(), query, bindings, rebindings)
+}
+
+// ExecuteContext is like Execute but stops waiting for the response and returns ctx.Err() when the context is done.
+func (s *Session) ExecuteContext(ctx context.Context, query string, bindings, rebindings map[string]interface{}) ([]*GremlinRespData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.execute(ctx, prepareSessionRequest(s.id, query, bindings, rebindings))
+}
+
+// Commit commits the transaction of the session
+func (s *Session) Commit() error {
+	return s.CommitContext(context.Background())
+}
+
+// CommitContext is the context aware version of Commit.
+func (s *Session) CommitContext(ctx context.Context) error {
+	_, err := s.ExecuteContext(ctx, "g.tx().commit()", nil, nil)
+	return err
+}
+
+// Rollback rolls back the transaction of the session
+func (s *Session) Rollback() error {
+	return s.RollbackContext(context.Background())
+}
+
+// RollbackContext is the context aware version of Rollback.
+func (s *Session) RollbackContext(ctx context.Context) error {
+	_, err := s.ExecuteContext(ctx, "g.tx().rollback()", nil, nil)
+	return err
+}
+
+// Close closes the session on the server, which rolls back a transaction that was not committed. Closing a
+// closed session does nothing.
+func (s *Session) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+// CloseContext is the context aware version of Close.
+func (s *Session) CloseContext(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	_, err := s.execute(ctx, prepareSessionCloseRequest(s.id))
+	s.closed = true
+	s.client.pool.unpin(s.id)
+	if err == ErrorSessionLost {
+		return nil // the server dropped the session with its connection
+	}
+	return err
+}
+
+// execute sends a request of the session and waits for its response, it must be called with the lock held
+func (s *Session) execute(ctx context.Context, req *GremlinRequest) ([]*GremlinRespData, error) {
+	c := s.client
+	if s.closed {
+		return nil, ErrorSessionClosed
+	}
+	if c.pool.isDisposed() {
+		return nil, ErrorConnectionDisposed
+	}
+	c.verbose("session %s request: %+v", s.id, req.Args["gremlin"])
+	id, err := c.submitRequest(ctx, req, s.id, false)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.retrieveResponse(ctx, id)
+	c.verbose("session %s response: %+v", s.id, spew.Sprint(resp))
+	return resp, err
+}
@@ -0,0 +1,202 @@
+package gremgoser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// sessionMock is a server keeping a transaction per session, it fails requests of a session that arrive on
+// another connection than the first request of the session
+type sessionMock struct {
+	t        *testing.T
+	mu       sync.Mutex
+	conns    map[string]*websocket.Conn
+	pending  map[string]int
+	vertices int
+	closed   []string
+}
+
+func newSessionMock(t *testing.T) *sessionMock {
+	return &sessionMock{t: t, conns: make(map[string]*websocket.Conn), pending: make(map[string]int)}
+}
+
+func (m *sessionMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	for {
+		mt, message, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req GremlinRequest
+		if err := json.Unmarshal(bytes.TrimPrefix(message, []byte("!application/vnd.gremlin-v2.0+json")), &req); err != nil {
+			return
+		}
+		code, data := m.handle(c, &req)
+		resp, _ := json.Marshal(map[string]interface{}{
+			"requestId": req.RequestId,
+			"status":    map[string]interface{}{"code": code, "message": "", "attributes": map[string]interface{}{}},
+			"result":    map[string]interface{}{"data": data, "meta": map[string]interface{}{}},
+		})
+		if err := c.WriteMessage(mt, resp); err != nil {
+			return
+		}
+	}
+}
+
+func (m *sessionMock) handle(c *websocket.Conn, req *GremlinRequest) (int, interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if req.Processor != "session" {
+		return 200, []interface{}{map[string]interface{}{"vertices": m.vertices}}
+	}
+	session, _ := req.Args["session"].(string)
+	if conn, ok := m.conns[session]; ok && conn != c {
+		m.t.Errorf("request of session %s on another connection", session)
+		return 499, nil
+	}
+	m.conns[session] = c
+	if req.Op == "close" {
+		delete(m.conns, session)
+		delete(m.pending, session)
+		m.closed = append(m.closed, session)
+		return 204, nil
+	}
+	switch req.Args["gremlin"] {
+	case "g.addV('a')":
+		m.pending[session]++
+	case "g.tx().commit()":
+		m.vertices += m.pending[session]
+		m.pending[session] = 0
+	case "g.tx().rollback()":
+		m.pending[session] = 0
+	default:
+		return 597, nil
+	}
+	return 200, []interface{}{map[string]interface{}{"vertices": m.vertices + m.pending[session]}}
+}
+
+func TestSession(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newSessionMock(t)
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conf := NewClientConfig(u)
+	conf.SetPoolSize(3)
+	conf.SetMinIdle(3)
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	s1, s2 := g.NewSession(), g.NewSession()
+	assert.NotEqual(s1.ID(), s2.ID())
+
+	// the sessions see their own pending vertices only
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := s1.Execute("g.addV('a')", nil, nil)
+			assert.Nil(err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := s2.Execute("g.addV('a')", nil, nil)
+			assert.Nil(err)
+		}()
+	}
+	wg.Wait()
+	data, err := s1.Execute("g.addV('a')", nil, nil)
+	assert.Nil(err)
+	assert.Equal(json.Number("6"), (*data[0])["vertices"])
+
+	// commit and rollback
+	assert.Nil(s1.Commit())
+	assert.Nil(s2.Rollback())
+	data, err = g.Execute("g.V().count()", nil, nil)
+	assert.Nil(err)
+	assert.Equal(json.Number("6"), (*data[0])["vertices"])
+
+	// errors of the server are returned
+	_, err = s1.Execute("g.V(", nil, nil)
+	assert.True(errors.Is(err, Error597ScriptEvaluationError))
+
+	// closing sends the close op once
+	assert.Nil(s1.Close())
+	assert.Nil(s1.Close())
+	assert.Nil(s2.CloseContext(context.Background()))
+	m.mu.Lock()
+	assert.Equal([]string{s1.ID().String(), s2.ID().String()}, m.closed)
+	m.mu.Unlock()
+	_, err = s1.Execute("g.addV('a')", nil, nil)
+	assert.Equal(ErrorSessionClosed, err)
+	assert.Equal(ErrorSessionClosed, s1.Commit())
+
+	g.pool.mu.Lock()
+	assert.Equal(0, len(g.pool.sessions))
+	g.pool.mu.Unlock()
+}
+
+// TestPoolAcquireSession tests that a session stays on its connection and is lost with it
+func TestPoolAcquireSession(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	pc1 := newTestPoolConn(c)
+	pc2 := newTestPoolConn(c)
+
+	session := uuid.New()
+	id1, id2 := uuid.New(), uuid.New()
+	_pc, err := c.pool.acquireSession(context.Background(), session, id1)
+	assert.Nil(err)
+	assert.Equal(pc1, _pc)
+
+	// the session keeps its connection although another one is less busy
+	_pc, err = c.pool.acquireSession(context.Background(), session, id2)
+	assert.Nil(err)
+	assert.Equal(pc1, _pc)
+	assert.Equal(2, pc1.inFlight)
+	assert.Equal(0, pc2.inFlight)
+	c.pool.release(id1)
+	c.pool.release(id2)
+	assert.Equal(0, pc1.inFlight)
+
+	// the session is lost with its connection
+	c.pool.retire(pc1)
+	_, err = c.pool.acquireSession(context.Background(), session, uuid.New())
+	assert.Equal(ErrorSessionLost, err)
+
+	// a closed session is forgotten
+	c.pool.unpin(session)
+	_pc, err = c.pool.acquireSession(context.Background(), session, uuid.New())
+	assert.Nil(err)
+	assert.Equal(pc2, _pc)
+	c.pool.close()
+}
@@ -0,0 +1,240 @@
+package gremgoser
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ResultStream iterates over the batches of a streamed request as the partial (206) frames arrive. The connection
+// queues the frames for the consumer without waiting on it, so a slow consumer never holds back the other requests
+// sharing its pooled connection, the frames it has not read yet are held in memory instead. A stream must be
+// closed, one that is not keeps its request counted against its connection and its unread frames in memory.
+//
+//	stream, err := g.ExecuteStream(ctx, "g.V()", nil, nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		batch := stream.Batch()
+//		...
+//	}
+//	return stream.Err()
+type ResultStream struct {
+	client   *Client
+	ctx      context.Context
+	id       uuid.UUID
+	mu       sync.Mutex
+	queue    []streamFrame // queue holds the frames delivered by the readWorker that Next has not read yet
+	ready    chan struct{} // ready is signalled when a frame is queued
+	done     chan struct{} // done is closed when the stream is closed, later frames are discarded
+	once     sync.Once
+	batch    []*GremlinRespData
+	values   []interface{}
+	attrs    GremlinStatusAttributes
+	result   Result // result aggregates the status attributes of every frame read
+	err      error
+	finished bool
+}
+
+// streamFrame is a single frame of a streamed request
+type streamFrame struct {
+	data   []*GremlinRespData
+	values []interface{}
+	status *GremlinStatus // status of the frame, nil when the stream failed without one
+	err    error
+	final  bool
+}
+
+// ExecuteStream sends a query to Gremlin Server and returns a ResultStream delivering the results batch by
+// batch. The stream must be closed, closing it before the last batch asks the server to stop the request.
+func (c *Client) ExecuteStream(ctx context.Context, query string, bindings, rebindings map[string]interface{}) (*ResultStream, error) {
+	if c.pool.isDisposed() {
+		return nil, ErrorConnectionDisposed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.verbose("stream query: %s", query)
+	req := prepareRequest(query, bindings, rebindings)
+	msg, err := c.serializer().serializeRequest(req)
+	if err != nil {
+		c.debug("error packing request: %s", err)
+		return nil, err
+	}
+	c.debug("packed request: %+v", req)
+	s := &ResultStream{
+		client: c,
+		ctx:    ctx,
+		id:     req.RequestId,
+		ready:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	c.responseNotifier.Store(s.id, s)
+	if err := c.dispatchRequest(ctx, s.id, msg); err != nil {
+		c.responseNotifier.Delete(s.id)
+		return nil, err
+	}
+	return s, nil
+}
+
+// deliver queues a frame for the consumer, it is called by the readWorker and never waits on the consumer
+func (s *ResultStream) deliver(resp *GremlinResponse) {
+	frame := streamFrame{data: resp.Result.Data, values: resp.Result.Values, status: &resp.Status, final: resp.Status.Code != 206}
+	if frame.final {
+		s.client.responseNotifier.Delete(s.id)
+		s.client.pool.release(s.id)
+		if err := responseDetectError(resp.Status.Code); err != nil {
+			s.client.debug("RequestId: %s, error response: %s", s.id, err)
+			frame.err = newGremlinError(resp, err)
+		}
+	}
+	s.push(frame)
+}
+
+// fail ends the stream with an error as its last frame
+func (s *ResultStream) fail(err error) {
+	s.client.responseNotifier.Delete(s.id)
+	s.push(streamFrame{final: true, err: err})
+}
+
+// push queues a frame and wakes up Next, frames of a closed stream are discarded
+func (s *ResultStream) push(frame streamFrame) {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+		s.client.debug("RequestId: %s, discarding frame of closed stream", s.id)
+		return
+	default:
+	}
+	s.queue = append(s.queue, frame)
+	s.mu.Unlock()
+	select {
+	case s.ready <- struct{}{}:
+	default: // Next is already woken up
+	}
+}
+
+// pop takes the oldest queued frame
+func (s *ResultStream) pop() (streamFrame, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return streamFrame{}, false
+	}
+	frame := s.queue[0]
+	s.queue[0] = streamFrame{}
+	s.queue = s.queue[1:]
+	return frame, true
+}
+
+// Next waits for the next batch and reports whether there is one. It returns false once the results are
+// exhausted or an error occurred, Err tells them apart. Waiting stops when the context of the stream is done
+// or no frame arrives within ReadingWait.
+func (s *ResultStream) Next() bool {
+	s.batch, s.values = nil, nil
+	for !s.finished {
+		if frame, ok := s.pop(); ok {
+			if frame.status != nil {
+				s.attrs = frame.status.Attributes
+				s.result.add(*frame.status)
+			}
+			if frame.final {
+				s.finished = true
+				s.err = frame.err
+			}
+			if frame.err == nil && len(frame.values) > 0 {
+				s.batch, s.values = frame.data, frame.values
+				return true
+			}
+			continue
+		}
+		var timeout <-chan time.Time // a zero ReadingWait waits until the next frame arrives or the context is done
+		var timer *time.Timer
+		if s.client.conf.ReadingWait > 0 {
+			timer = time.NewTimer(s.client.conf.ReadingWait)
+			timeout = timer.C
+		}
+		select {
+		case <-s.ready:
+		case <-timeout:
+			s.client.debug("timeout on stream: %s", s.id)
+			s.abort(ErrorResponseTimeout)
+		case <-s.ctx.Done():
+			s.client.debug("context done while waiting on stream: %s", s.ctx.Err())
+			s.abort(s.ctx.Err())
+		case <-s.done:
+			s.finished = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	return false
+}
+
+// Batch returns the batch fetched by the last call to Next
+func (s *ResultStream) Batch() []*GremlinRespData {
+	return s.batch
+}
+
+// Values returns the batch fetched by the last call to Next with GraphSON types decoded, it also holds the
+// results that are not JSON objects like counts
+func (s *ResultStream) Values() []interface{} {
+	return s.values
+}
+
+// Attributes returns the status attributes of the last frame read, Cosmos DB sends the request charge of the frame
+// and the total charge of the request so far in them
+func (s *ResultStream) Attributes() GremlinStatusAttributes {
+	return s.attrs
+}
+
+// Result returns the request charge, server time, activity id and status code of the frames read so far,
+// aggregated like ExecuteResult does. Frames without results and the frame of a server error count too. Data and
+// Values stay empty, the results are handed over by Values.
+func (s *ResultStream) Result() Result {
+	return s.result
+}
+
+// Err returns the error that ended the stream, nil when all results were read
+func (s *ResultStream) Err() error {
+	return s.err
+}
+
+// abort ends the stream with an error and closes it
+func (s *ResultStream) abort(err error) {
+	s.finished = true
+	s.err = err
+	s.Close()
+}
+
+// Close stops the stream. If the server has not sent the last frame yet the request is abandoned, any frames
+// still arriving are discarded and the server is sent a close for the request.
+func (s *ResultStream) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.mu.Lock()
+		close(s.done)
+		s.queue = nil
+		s.mu.Unlock()
+		c := s.client
+		if _, ok := c.responseNotifier.Load(s.id); !ok { // the last frame was delivered
+			return
+		}
+		c.responseNotifier.Delete(s.id)
+		msg, perr := c.serializer().serializeRequest(prepareCloseRequest(s.id))
+		if perr != nil {
+			err = perr
+		} else {
+			// the close is written on the connection the request runs on
+			err = c.dispatchRequest(context.Background(), s.id, msg)
+		}
+		c.pool.release(s.id)
+	})
+	return err
+}
@@ -0,0 +1,276 @@
+package gremgoser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var gremStream = `g.V().limit(3)`
+
+var gremStreamEndless = `g.V()`
+
+var gremStreamError = `g.V().fail()`
+
+var gremStreamEmpty = `g.V().limit(1)`
+
+// streamMock answers every query with partial frames holding one vertex each and charging 1.5 RU, 204 frames hold
+// none. The ids of closed requests are sent on closed
+func streamMock(closed chan uuid.UUID) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		frame := func(id uuid.UUID, code int) error {
+			resp := GremlinResponse{
+				RequestId: id,
+				Status:    GremlinStatus{Code: code, Attributes: GremlinStatusAttributes{XMsRequestCharge: 1.5}},
+				Result:    GremlinResult{Data: []*GremlinRespData{{"id": uuid.New().String(), "label": "test"}}},
+			}
+			if code == 204 {
+				resp.Result.Data = nil
+			}
+			msg, err := json.Marshal(resp)
+			if err != nil {
+				return err
+			}
+			return c.WriteMessage(1, msg)
+		}
+		for {
+			_, message, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			msg := bytes.SplitAfter(message, []byte("!application/vnd.gremlin-v2.0+json"))
+			var req GremlinRequest
+			if err := json.Unmarshal(msg[1], &req); err != nil {
+				return
+			}
+			if req.Op == "close" {
+				closed <- req.RequestId
+				continue
+			}
+			switch req.Args["gremlin"] {
+			case gremStream:
+				frame(req.RequestId, 206)
+				frame(req.RequestId, 206)
+				frame(req.RequestId, 200)
+			case gremStreamEndless:
+				for i := 0; i < 3; i++ {
+					frame(req.RequestId, 206)
+				}
+			case gremStreamError:
+				frame(req.RequestId, 206)
+				frame(req.RequestId, 597)
+			case gremStreamEmpty:
+				frame(req.RequestId, 206)
+				frame(req.RequestId, 204)
+			}
+		}
+	}
+}
+
+func TestExecuteStream(t *testing.T) {
+	assert := assert.New(t)
+
+	closed := make(chan uuid.UUID, 1)
+	s := httptest.NewServer(streamMock(closed))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	g, errs := NewClient(NewClientConfig(u))
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	// every frame is delivered as its own batch
+	stream, err := g.ExecuteStream(context.Background(), gremStream, nil, nil)
+	assert.Nil(err)
+	batches := 0
+	var charge float32
+	for stream.Next() {
+		assert.Equal(1, len(stream.Batch()))
+		charge += stream.Attributes().XMsRequestCharge
+		batches++
+	}
+	assert.Nil(stream.Err())
+	assert.Equal(3, batches)
+	assert.Equal(float32(4.5), charge)
+	assert.Equal(Result{RequestCharge: 4.5, StatusCode: 200, Frames: 3}, stream.Result())
+	assert.Nil(stream.Close())
+	assert.Equal(0, len(g.pool.assigned))
+
+	// frames without results are charged too
+	stream, err = g.ExecuteStream(context.Background(), gremStreamEmpty, nil, nil)
+	assert.Nil(err)
+	assert.True(stream.Next())
+	assert.False(stream.Next())
+	assert.Nil(stream.Err())
+	assert.Equal(Result{RequestCharge: 3, StatusCode: 204, Frames: 2}, stream.Result())
+	assert.Nil(stream.Close())
+	assert.Equal(0, len(g.pool.assigned))
+
+	// a server error ends the stream
+	stream, err = g.ExecuteStream(context.Background(), gremStreamError, nil, nil)
+	assert.Nil(err)
+	assert.True(stream.Next())
+	assert.False(stream.Next())
+	assert.True(errors.Is(stream.Err(), Error597ScriptEvaluationError))
+	assert.Equal(Result{RequestCharge: 3, StatusCode: 597, Frames: 2}, stream.Result())
+	stream.Close()
+
+	// no close is sent for finished requests
+	select {
+	case id := <-closed:
+		assert.Fail("unexpected close", "%s", id)
+	default:
+	}
+}
+
+func TestExecuteStreamClose(t *testing.T) {
+	assert := assert.New(t)
+
+	closed := make(chan uuid.UUID, 1)
+	s := httptest.NewServer(streamMock(closed))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	g, errs := NewClient(NewClientConfig(u))
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	// closing early sends the server a close for the request
+	stream, err := g.ExecuteStream(context.Background(), gremStreamEndless, nil, nil)
+	assert.Nil(err)
+	assert.True(stream.Next())
+	assert.Nil(stream.Close())
+	assert.Equal(stream.id, <-closed)
+	assert.False(stream.Next())
+	_, ok := g.responseNotifier.Load(stream.id)
+	assert.False(ok)
+
+	// canceling the context ends the stream
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err = g.ExecuteStream(ctx, gremStreamEndless, nil, nil)
+	assert.Nil(err)
+	for i := 0; i < 3; i++ {
+		assert.True(stream.Next())
+	}
+	cancel()
+	assert.False(stream.Next())
+	assert.Equal(context.Canceled, stream.Err())
+	assert.Equal(stream.id, <-closed)
+
+	// the connection keeps working
+	stream, err = g.ExecuteStream(context.Background(), gremStream, nil, nil)
+	assert.Nil(err)
+	batches := 0
+	for stream.Next() {
+		batches++
+	}
+	assert.Equal(3, batches)
+	stream.Close()
+}
+
+// TestExecuteStreamSlowConsumer tests that a stream nobody reads does not hold back the other requests on its
+// connection
+func TestExecuteStreamSlowConsumer(t *testing.T) {
+	assert := assert.New(t)
+
+	closed := make(chan uuid.UUID, 1)
+	s := httptest.NewServer(streamMock(closed))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conf := NewClientConfig(u)
+	conf.ReadingWait = 500 * time.Millisecond
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	// both streams share the single connection, the first is not read until the second is done
+	slow, err := g.ExecuteStream(context.Background(), gremStream, nil, nil)
+	assert.Nil(err)
+	defer slow.Close()
+	stream, err := g.ExecuteStream(context.Background(), gremStream, nil, nil)
+	assert.Nil(err)
+	batches := 0
+	for stream.Next() {
+		batches++
+	}
+	assert.Nil(stream.Err())
+	assert.Equal(3, batches)
+	stream.Close()
+
+	// the frames of the slow stream were queued meanwhile
+	batches = 0
+	for slow.Next() {
+		batches++
+	}
+	assert.Nil(slow.Err())
+	assert.Equal(3, batches)
+}
+
+func TestExecuteStreamTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	closed := make(chan uuid.UUID, 1)
+	s := httptest.NewServer(streamMock(closed))
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conf := NewClientConfig(u)
+	conf.ReadingWait = 50 * time.Millisecond
+	g, errs := NewClient(conf)
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel, the idle connection may hit the read deadline as well
+	go func(chan error) {
+		<-errs
+	}(errs)
+
+	stream, err := g.ExecuteStream(context.Background(), gremStreamEndless, nil, nil)
+	assert.Nil(err)
+	for stream.Next() {
+	}
+	assert.Equal(ErrorResponseTimeout, stream.Err())
+	_, ok := g.responseNotifier.Load(stream.id)
+	assert.False(ok)
+}
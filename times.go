@@ -0,0 +1,92 @@
+package gremgoser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// timeValue returns the property value of a time.Time or time.Duration tagged with the time option. Times are
+// written in the given format. Durations are a number of nanoseconds like everywhere else, see script.val, unless
+// the field asks for milliseconds with the millis option.
+func timeValue(v reflect.Value, format TimeFormat) (interface{}, error) {
+	switch v.Type() {
+	case timeType:
+		t := v.Interface().(time.Time)
+		if format == TimeFormatRFC3339 {
+			return t.Format(time.RFC3339Nano), nil
+		}
+		return t.UnixMilli(), nil
+	case durationType:
+		d := v.Interface().(time.Duration)
+		if format == TimeFormatMillis {
+			return d.Milliseconds(), nil
+		}
+		return int64(d), nil
+	}
+	return nil, fmt.Errorf("gremgoser: field of type %s tagged time is not a time.Time or time.Duration", v.Type())
+}
+
+// setTime sets an addressable time.Time or time.Duration from a property value, times are read from epoch
+// milliseconds, RFC 3339 strings or GraphSON dates. Durations are read from duration strings like "1m30s" or from
+// numbers in the unit timeValue writes them in for the format of the field, nanoseconds unless it is millis.
+func setTime(v reflect.Value, value interface{}, format TimeFormat) error {
+	value, err := decodeGraphSON(value)
+	if err != nil {
+		return err
+	}
+	switch v.Type() {
+	case timeType:
+		switch val := value.(type) {
+		case time.Time:
+			v.Set(reflect.ValueOf(val))
+			return nil
+		case json.Number, int64, int32:
+			ms, err := graphSONInt(val, 64)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(time.UnixMilli(ms).UTC()))
+			return nil
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, val)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(t))
+			return nil
+		}
+	case durationType:
+		switch val := value.(type) {
+		case time.Duration:
+			v.SetInt(int64(val))
+			return nil
+		case json.Number, int64, int32:
+			n, err := graphSONInt(val, 64)
+			if err != nil {
+				return err
+			}
+			if format == TimeFormatMillis {
+				n *= int64(time.Millisecond)
+			}
+			v.SetInt(n)
+			return nil
+		case string:
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+	default:
+		return fmt.Errorf("gremgoser: field of type %s tagged time is not a time.Time or time.Duration", v.Type())
+	}
+	return fmt.Errorf("gremgoser: cannot read a %s from the property value %v", v.Type(), value)
+}
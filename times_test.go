@@ -0,0 +1,155 @@
+package gremgoser
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type Event struct {
+	Id        uuid.UUID     `graph:"id,string"`
+	CreatedAt time.Time     `graph:"createdAt,time"`
+	UpdatedAt *time.Time    `graph:"updatedAt,time,rfc3339"`
+	Seen      []time.Time   `graph:"seen,time"`
+	TTL       time.Duration `graph:"ttl,time"`
+	Timeout   time.Duration `graph:"timeout,number"`
+}
+
+func TestTimeScripts(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	c.conf.SetDisableBindings()
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	updated := time.Date(2021, 6, 7, 8, 9, 10, 0, time.FixedZone("CEST", 2*60*60))
+	e := Event{
+		Id:        uuid.MustParse("64795211-c4a1-4eac-9e0a-b674ced77461"),
+		CreatedAt: created,
+		UpdatedAt: &updated,
+		Seen:      []time.Time{created, created.Add(time.Hour)},
+		TTL:       90 * time.Second,
+		Timeout:   time.Second,
+	}
+	sc := codecOf(reflect.TypeOf(e))
+
+	// times are epoch millis by default, durations nanoseconds
+	q := c.newScript()
+	assert.Nil(sc.addProps(q, reflect.ValueOf(e)))
+	assert.Equal(".property('id', '64795211-c4a1-4eac-9e0a-b674ced77461').property('createdAt', 1577934245600).property('updatedAt', '2021-06-07T08:09:10+02:00').property('seen', 1577934245600).property('seen', 1577937845600).property('ttl', 90000000000).property('timeout', 1000000000)", q.String())
+
+	// the format of the client applies to fields without their own
+	c.conf.SetTimeFormat(TimeFormatRFC3339)
+	q = c.newScript()
+	e.UpdatedAt = nil
+	assert.Nil(sc.updateV(q, reflect.ValueOf(e)))
+	assert.Equal(".property('createdAt', '2020-01-02T03:04:05.6Z').sideEffect(properties('seen').drop()).property(list, 'seen', '2020-01-02T03:04:05.6Z').property(list, 'seen', '2020-01-02T04:04:05.6Z').property('ttl', 90000000000).property('timeout', 1000000000)", q.String())
+
+	// bound values keep their type
+	c.conf.DisableBindings = false
+	q = c.newScript()
+	assert.Nil(sc.addProps(q, reflect.ValueOf(Event{CreatedAt: created})))
+	assert.Equal("2020-01-02T03:04:05.6Z", q.params()["_p1"])
+	assert.Equal(int64(0), q.params()["_p2"])
+	assert.Equal(int64(0), q.params()["_p3"])
+
+	// the time option only applies to times and durations
+	bad := struct {
+		Id uuid.UUID `graph:"id,string"`
+		At string    `graph:"at,time"`
+	}{}
+	assert.NotNil(codecOf(reflect.TypeOf(bad)).addProps(c.newScript(), reflect.ValueOf(bad)))
+}
+
+func TestTimeDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	row := func(props string) *GremlinData {
+		var d *GremlinData
+		decoder := json.NewDecoder(strings.NewReader(`{"id":"64795211-c4a1-4eac-9e0a-b674ced77461","label":"event","type":"vertex","properties":` + props + `}`))
+		decoder.UseNumber()
+		assert.Nil(decoder.Decode(&d))
+		return d
+	}
+
+	sc := codecOf(reflect.TypeOf(Event{}))
+	events := make([]Event, 1)
+	err := sc.decode(row(`{"createdAt":[{"id":"a","value":1577934245600}],"updatedAt":[{"id":"b","value":"2021-06-07T08:09:10+02:00"}],"seen":[{"id":"c","value":1577934245600},{"id":"d","value":"2020-01-02T04:04:05.6Z"}],"ttl":[{"id":"e","value":90000000000}],"timeout":[{"id":"f","value":1000000000}]}`), reflect.ValueOf(events).Index(0))
+	assert.Nil(err)
+	e := events[0]
+	created := time.Date(2020, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	assert.Equal(created, e.CreatedAt)
+	assert.True(time.Date(2021, 6, 7, 6, 9, 10, 0, time.UTC).Equal(*e.UpdatedAt))
+	assert.Equal(2, len(e.Seen))
+	assert.Equal(created, e.Seen[0])
+	assert.True(created.Add(time.Hour).Equal(e.Seen[1]))
+	assert.Equal(90*time.Second, e.TTL)
+	assert.Equal(time.Second, e.Timeout)
+
+	// typed GraphSON dates and duration strings
+	events = make([]Event, 1)
+	err = sc.decode(row(`{"createdAt":[{"id":"a","value":{"@type":"g:Date","@value":1577934245600}}],"ttl":[{"id":"e","value":"1m30s"}]}`), reflect.ValueOf(events).Index(0))
+	assert.Nil(err)
+	assert.Equal(created, events[0].CreatedAt)
+	assert.Equal(90*time.Second, events[0].TTL)
+
+	// values that are no time fail
+	err = sc.decode(row(`{"createdAt":[{"id":"a","value":true}]}`), reflect.ValueOf(events).Index(0))
+	assert.NotNil(err)
+	err = sc.decode(row(`{"createdAt":[{"id":"a","value":"yesterday"}]}`), reflect.ValueOf(events).Index(0))
+	assert.NotNil(err)
+}
+
+// Grace keeps its duration in milliseconds
+type Grace struct {
+	Id    uuid.UUID     `graph:"id,string"`
+	Grace time.Duration `graph:"grace,time,millis"`
+}
+
+func TestDurationUnits(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	c.conf.SetDisableBindings()
+
+	// durations are nanoseconds whether tagged time or number and in property maps, whatever the time format
+	e := Event{TTL: 1500 * time.Microsecond, Timeout: 1500 * time.Millisecond}
+	for _, format := range []TimeFormat{TimeFormatMillis, TimeFormatRFC3339} {
+		c.conf.SetTimeFormat(format)
+		q := c.newScript()
+		assert.Nil(codecOf(reflect.TypeOf(e)).updateV(q, reflect.ValueOf(e)))
+		assert.Contains(q.String(), ".property('ttl', 1500000).property('timeout', 1500000000)")
+	}
+	q := c.newScript()
+	assert.Nil(buildProps(q, map[string]interface{}{"timeout": 1500 * time.Millisecond}))
+	assert.Equal(".property('timeout', 1500000000)", q.String())
+
+	// the millis option asks for milliseconds
+	g := Grace{Grace: 1500 * time.Millisecond}
+	q = c.newScript()
+	assert.Nil(codecOf(reflect.TypeOf(g)).updateV(q, reflect.ValueOf(g)))
+	assert.Equal(".property('grace', 1500)", q.String())
+
+	// each is read back in the unit it was written in
+	row := func(props string) *GremlinData {
+		var d *GremlinData
+		decoder := json.NewDecoder(strings.NewReader(`{"id":"64795211-c4a1-4eac-9e0a-b674ced77461","label":"event","type":"vertex","properties":` + props + `}`))
+		decoder.UseNumber()
+		assert.Nil(decoder.Decode(&d))
+		return d
+	}
+	events := make([]Event, 1)
+	assert.Nil(codecOf(reflect.TypeOf(e)).decode(row(`{"ttl":[{"id":"a","value":1500000}],"timeout":[{"id":"b","value":1500000000}]}`), reflect.ValueOf(events).Index(0)))
+	assert.Equal(1500*time.Microsecond, events[0].TTL)
+	assert.Equal(1500*time.Millisecond, events[0].Timeout)
+	graces := make([]Grace, 1)
+	assert.Nil(codecOf(reflect.TypeOf(g)).decode(row(`{"grace":[{"id":"a","value":1500}]}`), reflect.ValueOf(graces).Index(0)))
+	assert.Equal(1500*time.Millisecond, graces[0].Grace)
+}
@@ -0,0 +1,64 @@
+package traversal
+
+import (
+	"strings"
+)
+
+// Bytecode is the language independent form of a traversal that Gremlin Server runs without compiling a script,
+// the form the official drivers submit. Anonymous traversals among the step arguments are nested bytecode.
+type Bytecode struct {
+	Steps []Step
+}
+
+// Bytecode returns the bytecode of the traversal
+func (t *Traversal) Bytecode() *Bytecode {
+	b := &Bytecode{Steps: make([]Step, len(t.steps))}
+	for i, step := range t.steps {
+		args := make([]interface{}, len(step.Args))
+		for j, arg := range step.Args {
+			args[j] = bytecodeArg(arg)
+		}
+		b.Steps[i] = Step{Name: step.Name, Args: args}
+	}
+	return b
+}
+
+func bytecodeArg(v interface{}) interface{} {
+	switch a := v.(type) {
+	case *Traversal:
+		return a.Bytecode()
+	case P:
+		values := make([]interface{}, len(a.Values))
+		for i, value := range a.Values {
+			values[i] = bytecodeArg(value)
+		}
+		return P{Operator: a.Operator, Values: values}
+	}
+	return v
+}
+
+// Enum returns the Gremlin enum the token belongs to, like T or Order, and its name within the enum
+func (t Token) Enum() (string, string) {
+	switch t {
+	case Single, List, Set:
+		return "Cardinality", string(t)
+	case Incr, Decr, Asc, Desc, Shuffle:
+		return "Order", string(t)
+	case Local, Global:
+		return "Scope", string(t)
+	case Keys, Values:
+		return "Column", string(t)
+	}
+	if i := strings.Index(string(t), "."); i > 0 {
+		return string(t[:i]), string(t[i+1:])
+	}
+	return "T", string(t)
+}
+
+// Predicate returns the name of the predicate without the TextP prefix and whether it is a text predicate
+func (p P) Predicate() (string, bool) {
+	if strings.HasPrefix(p.Operator, "TextP.") {
+		return strings.TrimPrefix(p.Operator, "TextP."), true
+	}
+	return p.Operator, false
+}
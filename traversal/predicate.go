@@ -0,0 +1,97 @@
+package traversal
+
+// P is a predicate used by steps like has, is and where
+type P struct {
+	Operator string
+	Values   []interface{}
+}
+
+// And returns a predicate matching when both predicates match
+func (p P) And(other P) P {
+	return P{Operator: "and", Values: []interface{}{p, other}}
+}
+
+// Or returns a predicate matching when either predicate matches
+func (p P) Or(other P) P {
+	return P{Operator: "or", Values: []interface{}{p, other}}
+}
+
+// Eq matches values equal to v
+func Eq(v interface{}) P {
+	return P{Operator: "eq", Values: []interface{}{v}}
+}
+
+// Neq matches values not equal to v
+func Neq(v interface{}) P {
+	return P{Operator: "neq", Values: []interface{}{v}}
+}
+
+// Lt matches values lower than v
+func Lt(v interface{}) P {
+	return P{Operator: "lt", Values: []interface{}{v}}
+}
+
+// Lte matches values lower than or equal to v
+func Lte(v interface{}) P {
+	return P{Operator: "lte", Values: []interface{}{v}}
+}
+
+// Gt matches values greater than v
+func Gt(v interface{}) P {
+	return P{Operator: "gt", Values: []interface{}{v}}
+}
+
+// Gte matches values greater than or equal to v
+func Gte(v interface{}) P {
+	return P{Operator: "gte", Values: []interface{}{v}}
+}
+
+// Between matches values greater than or equal to low and lower than high
+func Between(low, high interface{}) P {
+	return P{Operator: "between", Values: []interface{}{low, high}}
+}
+
+// Inside matches values greater than low and lower than high
+func Inside(low, high interface{}) P {
+	return P{Operator: "inside", Values: []interface{}{low, high}}
+}
+
+// Outside matches values lower than low or greater than high
+func Outside(low, high interface{}) P {
+	return P{Operator: "outside", Values: []interface{}{low, high}}
+}
+
+// Within matches values contained in the passed values
+func Within(values ...interface{}) P {
+	return P{Operator: "within", Values: values}
+}
+
+// Without matches values not contained in the passed values
+func Without(values ...interface{}) P {
+	return P{Operator: "without", Values: values}
+}
+
+// Not negates a predicate
+func Not(p P) P {
+	return P{Operator: "not", Values: []interface{}{p}}
+}
+
+// TextContains matches strings containing s
+func TextContains(s string) P {
+	return P{Operator: "TextP.containing", Values: []interface{}{s}}
+}
+
+// TextNotContains matches strings not containing s
+func TextNotContains(s string) P {
+	return P{Operator: "TextP.notContaining", Values: []interface{}{s}}
+}
+
+// TextStartsWith matches strings starting with s
+func TextStartsWith(s string) P {
+	return P{Operator: "TextP.startingWith", Values: []interface{}{s}}
+}
+
+// TextEndsWith matches strings ending with s
+func TextEndsWith(s string) P {
+	return P{Operator: "TextP.endingWith", Values: []interface{}{s}}
+}
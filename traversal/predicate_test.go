@@ -0,0 +1,37 @@
+package traversal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredicates(t *testing.T) {
+	assert := assert.New(t)
+
+	g := G()
+	for _, test := range []struct {
+		p        P
+		query    string
+		bindings map[string]interface{}
+	}{
+		{Eq(1), "g.V().has(_p0, eq(_p1))", map[string]interface{}{"_p0": "k", "_p1": 1}},
+		{Neq(1), "g.V().has(_p0, neq(_p1))", map[string]interface{}{"_p0": "k", "_p1": 1}},
+		{Gt(1), "g.V().has(_p0, gt(_p1))", map[string]interface{}{"_p0": "k", "_p1": 1}},
+		{Lte(1), "g.V().has(_p0, lte(_p1))", map[string]interface{}{"_p0": "k", "_p1": 1}},
+		{Between(1, 5), "g.V().has(_p0, between(_p1, _p2))", map[string]interface{}{"_p0": "k", "_p1": 1, "_p2": 5}},
+		{Within("a", "b", "c"), "g.V().has(_p0, within(_p1, _p2, _p3))", map[string]interface{}{"_p0": "k", "_p1": "a", "_p2": "b", "_p3": "c"}},
+		{Without("a"), "g.V().has(_p0, without(_p1))", map[string]interface{}{"_p0": "k", "_p1": "a"}},
+		{TextContains("ar"), "g.V().has(_p0, TextP.containing(_p1))", map[string]interface{}{"_p0": "k", "_p1": "ar"}},
+		{TextStartsWith("m"), "g.V().has(_p0, TextP.startingWith(_p1))", map[string]interface{}{"_p0": "k", "_p1": "m"}},
+		{Gt(1).And(Lt(5)), "g.V().has(_p0, gt(_p1).and(lt(_p2)))", map[string]interface{}{"_p0": "k", "_p1": 1, "_p2": 5}},
+		{Not(Within(1)).Or(Eq(2)), "g.V().has(_p0, not(within(_p1)).or(eq(_p2)))", map[string]interface{}{"_p0": "k", "_p1": 1, "_p2": 2}},
+	} {
+		query, bindings := g.V().Has("k", test.p).Build()
+		assert.Equal(test.query, query)
+		assert.Equal(test.bindings, bindings)
+	}
+
+	query, _ := g.V().Values("age").Is(Inside(20, 30)).Build()
+	assert.Equal("g.V().values(_p0).is(inside(_p1, _p2))", query)
+}
@@ -0,0 +1,359 @@
+package traversal
+
+// V adds a V step over the vertices with the passed ids
+func (t *Traversal) V(args ...interface{}) *Traversal {
+	return t.Add("V", args...)
+}
+
+// AddV adds a vertex with the passed label
+func (t *Traversal) AddV(label string) *Traversal {
+	return t.Add("addV", label)
+}
+
+// AddE adds an edge with the passed label
+func (t *Traversal) AddE(label string) *Traversal {
+	return t.Add("addE", label)
+}
+
+// To sets the incoming vertex of addE, a step label or an anonymous traversal
+func (t *Traversal) To(vertex interface{}) *Traversal {
+	return t.Add("to", vertex)
+}
+
+// From sets the outgoing vertex of addE, a step label or an anonymous traversal
+func (t *Traversal) From(vertex interface{}) *Traversal {
+	return t.Add("from", vertex)
+}
+
+// Property sets a property, a leading Cardinality token like List is passed on
+func (t *Traversal) Property(args ...interface{}) *Traversal {
+	return t.Add("property", args...)
+}
+
+// Has filters elements by a property key, optionally with a label first and a value or predicate last
+func (t *Traversal) Has(args ...interface{}) *Traversal {
+	return t.Add("has", args...)
+}
+
+// HasLabel filters elements by their label
+func (t *Traversal) HasLabel(labels ...string) *Traversal {
+	return t.Add("hasLabel", strs(labels)...)
+}
+
+// HasId filters elements by their id
+func (t *Traversal) HasId(args ...interface{}) *Traversal {
+	return t.Add("hasId", args...)
+}
+
+// HasKey filters properties by their key
+func (t *Traversal) HasKey(labels ...string) *Traversal {
+	return t.Add("hasKey", strs(labels)...)
+}
+
+// HasNot filters elements lacking the property
+func (t *Traversal) HasNot(key string) *Traversal {
+	return t.Add("hasNot", key)
+}
+
+// Is filters values equal to the value or matching the predicate
+func (t *Traversal) Is(value interface{}) *Traversal {
+	return t.Add("is", value)
+}
+
+// Out moves to the outgoing adjacent vertices over edges with the passed labels
+func (t *Traversal) Out(labels ...string) *Traversal {
+	return t.Add("out", strs(labels)...)
+}
+
+// In moves to the incoming adjacent vertices over edges with the passed labels
+func (t *Traversal) In(labels ...string) *Traversal {
+	return t.Add("in", strs(labels)...)
+}
+
+// Both moves to the adjacent vertices over edges with the passed labels
+func (t *Traversal) Both(labels ...string) *Traversal {
+	return t.Add("both", strs(labels)...)
+}
+
+// OutE moves to the outgoing edges with the passed labels
+func (t *Traversal) OutE(labels ...string) *Traversal {
+	return t.Add("outE", strs(labels)...)
+}
+
+// InE moves to the incoming edges with the passed labels
+func (t *Traversal) InE(labels ...string) *Traversal {
+	return t.Add("inE", strs(labels)...)
+}
+
+// BothE moves to the edges with the passed labels
+func (t *Traversal) BothE(labels ...string) *Traversal {
+	return t.Add("bothE", strs(labels)...)
+}
+
+// OutV moves to the outgoing vertex of an edge
+func (t *Traversal) OutV() *Traversal {
+	return t.Add("outV")
+}
+
+// InV moves to the incoming vertex of an edge
+func (t *Traversal) InV() *Traversal {
+	return t.Add("inV")
+}
+
+// BothV moves to both vertices of an edge
+func (t *Traversal) BothV() *Traversal {
+	return t.Add("bothV")
+}
+
+// OtherV moves to the vertex of an edge that was not traversed from
+func (t *Traversal) OtherV() *Traversal {
+	return t.Add("otherV")
+}
+
+// Id emits the id of the elements
+func (t *Traversal) Id() *Traversal {
+	return t.Add("id")
+}
+
+// Label emits the label of the elements
+func (t *Traversal) Label() *Traversal {
+	return t.Add("label")
+}
+
+// Values emits the values of the properties with the passed keys
+func (t *Traversal) Values(labels ...string) *Traversal {
+	return t.Add("values", strs(labels)...)
+}
+
+// ValueMap emits the properties of the elements as a map
+func (t *Traversal) ValueMap(args ...interface{}) *Traversal {
+	return t.Add("valueMap", args...)
+}
+
+// Properties emits the properties with the passed keys
+func (t *Traversal) Properties(labels ...string) *Traversal {
+	return t.Add("properties", strs(labels)...)
+}
+
+// Key emits the key of the properties
+func (t *Traversal) Key() *Traversal {
+	return t.Add("key")
+}
+
+// Value emits the value of the properties
+func (t *Traversal) Value() *Traversal {
+	return t.Add("value")
+}
+
+// Limit emits the first n traversers, a leading Scope token like Local is passed on
+func (t *Traversal) Limit(args ...interface{}) *Traversal {
+	return t.Add("limit", args...)
+}
+
+// Range emits the traversers from low to high, a leading Scope token like Local is passed on
+func (t *Traversal) Range(args ...interface{}) *Traversal {
+	return t.Add("range", args...)
+}
+
+// Skip drops the first n traversers
+func (t *Traversal) Skip(args ...interface{}) *Traversal {
+	return t.Add("skip", args...)
+}
+
+// Tail emits the last n traversers
+func (t *Traversal) Tail(args ...interface{}) *Traversal {
+	return t.Add("tail", args...)
+}
+
+// Count emits the number of traversers
+func (t *Traversal) Count(args ...interface{}) *Traversal {
+	return t.Add("count", args...)
+}
+
+// Dedup removes duplicates
+func (t *Traversal) Dedup(args ...interface{}) *Traversal {
+	return t.Add("dedup", args...)
+}
+
+// Order sorts the traversers, use By to set the criteria
+func (t *Traversal) Order(args ...interface{}) *Traversal {
+	return t.Add("order", args...)
+}
+
+// By modulates the previous step with a key, a traversal or an Order token
+func (t *Traversal) By(args ...interface{}) *Traversal {
+	return t.Add("by", args...)
+}
+
+// As labels the step so it can be selected later
+func (t *Traversal) As(labels ...string) *Traversal {
+	return t.Add("as", strs(labels)...)
+}
+
+// Select emits the labeled steps or map keys
+func (t *Traversal) Select(args ...interface{}) *Traversal {
+	return t.Add("select", args...)
+}
+
+// Project emits a map with the passed keys, use By to set their values
+func (t *Traversal) Project(labels ...string) *Traversal {
+	return t.Add("project", strs(labels)...)
+}
+
+// Where filters by an anonymous traversal or a predicate
+func (t *Traversal) Where(filter interface{}) *Traversal {
+	return t.Add("where", filter)
+}
+
+// Not filters traversers for which the traversal emits nothing
+func (t *Traversal) Not(anon *Traversal) *Traversal {
+	return t.Add("not", anon)
+}
+
+// And filters traversers for which every traversal emits something
+func (t *Traversal) And(traversals ...*Traversal) *Traversal {
+	return t.Add("and", travs(traversals)...)
+}
+
+// Or filters traversers for which any traversal emits something
+func (t *Traversal) Or(traversals ...*Traversal) *Traversal {
+	return t.Add("or", travs(traversals)...)
+}
+
+// Union merges the results of the traversals
+func (t *Traversal) Union(traversals ...*Traversal) *Traversal {
+	return t.Add("union", travs(traversals)...)
+}
+
+// Coalesce emits the results of the first traversal emitting something
+func (t *Traversal) Coalesce(traversals ...*Traversal) *Traversal {
+	return t.Add("coalesce", travs(traversals)...)
+}
+
+// Optional emits the results of the traversal or the traverser itself
+func (t *Traversal) Optional(anon *Traversal) *Traversal {
+	return t.Add("optional", anon)
+}
+
+// Choose branches on a predicate or traversal
+func (t *Traversal) Choose(args ...interface{}) *Traversal {
+	return t.Add("choose", args...)
+}
+
+// Repeat loops over the traversal, use Times, Until and Emit to stop and emit
+func (t *Traversal) Repeat(anon *Traversal) *Traversal {
+	return t.Add("repeat", anon)
+}
+
+// Times stops a repeat after n loops
+func (t *Traversal) Times(n int) *Traversal {
+	return t.Add("times", n)
+}
+
+// Until stops a repeat once the traversal emits something
+func (t *Traversal) Until(anon *Traversal) *Traversal {
+	return t.Add("until", anon)
+}
+
+// Emit emits the traversers of a repeat, filtered by the optional traversal
+func (t *Traversal) Emit(traversals ...*Traversal) *Traversal {
+	return t.Add("emit", travs(traversals)...)
+}
+
+// SideEffect runs the traversal without changing the traversers
+func (t *Traversal) SideEffect(anon *Traversal) *Traversal {
+	return t.Add("sideEffect", anon)
+}
+
+// Fold collects the traversers into a list
+func (t *Traversal) Fold() *Traversal {
+	return t.Add("fold")
+}
+
+// Unfold emits the items of lists and maps
+func (t *Traversal) Unfold() *Traversal {
+	return t.Add("unfold")
+}
+
+// Path emits the path of the traversers
+func (t *Traversal) Path() *Traversal {
+	return t.Add("path")
+}
+
+// SimplePath filters paths that visit an element twice
+func (t *Traversal) SimplePath() *Traversal {
+	return t.Add("simplePath")
+}
+
+// Constant emits the value
+func (t *Traversal) Constant(value interface{}) *Traversal {
+	return t.Add("constant", value)
+}
+
+// Group groups the traversers, use By to set key and value
+func (t *Traversal) Group(args ...interface{}) *Traversal {
+	return t.Add("group", args...)
+}
+
+// GroupCount counts the traversers by key, use By to set the key
+func (t *Traversal) GroupCount(args ...interface{}) *Traversal {
+	return t.Add("groupCount", args...)
+}
+
+// Aggregate collects the traversers into a side effect
+func (t *Traversal) Aggregate(label string) *Traversal {
+	return t.Add("aggregate", label)
+}
+
+// Store lazily collects the traversers into a side effect
+func (t *Traversal) Store(label string) *Traversal {
+	return t.Add("store", label)
+}
+
+// Cap emits the side effects with the passed labels
+func (t *Traversal) Cap(labels ...string) *Traversal {
+	return t.Add("cap", strs(labels)...)
+}
+
+// Sum emits the sum of the values
+func (t *Traversal) Sum(args ...interface{}) *Traversal {
+	return t.Add("sum", args...)
+}
+
+// Max emits the largest value
+func (t *Traversal) Max(args ...interface{}) *Traversal {
+	return t.Add("max", args...)
+}
+
+// Min emits the smallest value
+func (t *Traversal) Min(args ...interface{}) *Traversal {
+	return t.Add("min", args...)
+}
+
+// Mean emits the mean of the values
+func (t *Traversal) Mean(args ...interface{}) *Traversal {
+	return t.Add("mean", args...)
+}
+
+// Drop removes the elements or properties
+func (t *Traversal) Drop() *Traversal {
+	return t.Add("drop")
+}
+
+// strs converts step labels or keys to step arguments
+func strs(s []string) []interface{} {
+	args := make([]interface{}, len(s))
+	for i, v := range s {
+		args[i] = v
+	}
+	return args
+}
+
+// travs converts anonymous traversals to step arguments
+func travs(t []*Traversal) []interface{} {
+	args := make([]interface{}, len(t))
+	for i, v := range t {
+		args[i] = v
+	}
+	return args
+}
@@ -0,0 +1,186 @@
+// Package traversal builds Gremlin traversals with a fluent API. Every value passed to a step is sent as a
+// binding instead of being formatted into the script, so user input cannot change the query.
+//
+//	g := traversal.G()
+//	query, bindings := g.V().HasLabel("person").Has("age", traversal.Gt(30)).Out("knows").Limit(10).Build()
+//	resp, err := client.Execute(query, bindings, nil)
+//
+// Traversals can also be submitted as bytecode, which the server runs without compiling a script:
+//
+//	values, err := client.ExecuteBytecode(g.V().HasLabel("person").Values("name"))
+package traversal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Token is a Gremlin enum value like T.id or incr that is written to the script as is
+type Token string
+
+const (
+	TId     Token = "T.id"
+	TLabel  Token = "T.label"
+	TKey    Token = "T.key"
+	TValue  Token = "T.value"
+	Single  Token = "single"
+	List    Token = "list"
+	Set     Token = "set"
+	Incr    Token = "incr"
+	Decr    Token = "decr"
+	Asc     Token = "asc"
+	Desc    Token = "desc"
+	Shuffle Token = "shuffle"
+	Local   Token = "local"
+	Global  Token = "global"
+	Keys    Token = "keys"
+	Values  Token = "values"
+)
+
+// Step is a single step of a traversal with the arguments it was called with
+type Step struct {
+	Name string
+	Args []interface{}
+}
+
+// Traversal is a chain of steps. The step methods append to the traversal and return it, so a traversal
+// should not be shared between goroutines while it is built.
+type Traversal struct {
+	source string
+	steps  []Step
+}
+
+// Source spawns traversals, it is the g of g.V()
+type Source struct {
+	name string
+}
+
+// G returns the traversal source bound to g on the server
+func G() *Source {
+	return &Source{name: "g"}
+}
+
+// NewSource returns a traversal source bound to the passed name on the server
+func NewSource(name string) *Source {
+	return &Source{name: name}
+}
+
+// Anon starts an anonymous traversal, the __ of __.out(), to be passed as an argument of another step
+func Anon() *Traversal {
+	return &Traversal{source: "__"}
+}
+
+// spawn starts a traversal from the source with its first step
+func (s *Source) spawn(name string, args ...interface{}) *Traversal {
+	t := &Traversal{source: s.name}
+	return t.Add(name, args...)
+}
+
+// V starts a traversal over the vertices with the passed ids, all vertices when no id is passed
+func (s *Source) V(ids ...interface{}) *Traversal {
+	return s.spawn("V", ids...)
+}
+
+// E starts a traversal over the edges with the passed ids, all edges when no id is passed
+func (s *Source) E(ids ...interface{}) *Traversal {
+	return s.spawn("E", ids...)
+}
+
+// AddV starts a traversal adding a vertex with the passed label
+func (s *Source) AddV(label string) *Traversal {
+	return s.spawn("addV", label)
+}
+
+// AddE starts a traversal adding an edge with the passed label
+func (s *Source) AddE(label string) *Traversal {
+	return s.spawn("addE", label)
+}
+
+// Inject starts a traversal emitting the passed values
+func (s *Source) Inject(values ...interface{}) *Traversal {
+	return s.spawn("inject", values...)
+}
+
+// Add appends a step to the traversal, it allows steps this package has no method for
+func (t *Traversal) Add(name string, args ...interface{}) *Traversal {
+	t.steps = append(t.steps, Step{Name: name, Args: args})
+	return t
+}
+
+// Steps returns the steps of the traversal
+func (t *Traversal) Steps() []Step {
+	return t.steps
+}
+
+// Source returns the name of the source the traversal was spawned from, __ for anonymous traversals
+func (t *Traversal) Source() string {
+	return t.source
+}
+
+// Build returns the Gremlin script of the traversal and the bindings it references
+func (t *Traversal) Build() (string, map[string]interface{}) {
+	b := &builder{bindings: make(map[string]interface{})}
+	query := b.traversal(t)
+	return query, b.bindings
+}
+
+// String returns the Gremlin script of the traversal with binding names in place of the values
+func (t *Traversal) String() string {
+	query, _ := t.Build()
+	return query
+}
+
+// builder writes traversals to a script and collects the values they reference as bindings
+type builder struct {
+	bindings map[string]interface{}
+}
+
+// bind adds a value to the bindings and returns the name it is bound to
+func (b *builder) bind(v interface{}) string {
+	name := fmt.Sprintf("_p%d", len(b.bindings))
+	b.bindings[name] = v
+	return name
+}
+
+func (b *builder) traversal(t *Traversal) string {
+	var sb strings.Builder
+	sb.WriteString(t.source)
+	for _, step := range t.steps {
+		sb.WriteString(".")
+		sb.WriteString(step.Name)
+		sb.WriteString("(")
+		for i, arg := range step.Args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(b.arg(arg))
+		}
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
+
+func (b *builder) arg(v interface{}) string {
+	switch a := v.(type) {
+	case *Traversal:
+		return b.traversal(a)
+	case P:
+		return b.predicate(a)
+	case Token:
+		return string(a)
+	default:
+		return b.bind(v)
+	}
+}
+
+func (b *builder) predicate(p P) string {
+	switch p.Operator {
+	case "and", "or":
+		return fmt.Sprintf("%s.%s(%s)", b.arg(p.Values[0]), p.Operator, b.arg(p.Values[1]))
+	}
+	args := make([]string, len(p.Values))
+	for i, v := range p.Values {
+		args[i] = b.arg(v)
+	}
+	return fmt.Sprintf("%s(%s)", p.Operator, strings.Join(args, ", "))
+}
@@ -0,0 +1,109 @@
+package traversal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	assert := assert.New(t)
+
+	g := G()
+	query, bindings := g.V().HasLabel("person").Has("name", "marko").Out("knows").Limit(10).Build()
+	assert.Equal("g.V().hasLabel(_p0).has(_p1, _p2).out(_p3).limit(_p4)", query)
+	assert.Equal(map[string]interface{}{"_p0": "person", "_p1": "name", "_p2": "marko", "_p3": "knows", "_p4": 10}, bindings)
+
+	// the source spawns independent traversals
+	query, bindings = g.V("1").Values("name").Build()
+	assert.Equal("g.V(_p0).values(_p1)", query)
+	assert.Equal(map[string]interface{}{"_p0": "1", "_p1": "name"}, bindings)
+
+	// tokens are written as is
+	query, bindings = g.V().Order().By("age", Decr).Limit(Local, 1).Build()
+	assert.Equal("g.V().order().by(_p0, decr).limit(local, _p1)", query)
+	assert.Equal(map[string]interface{}{"_p0": "age", "_p1": 1}, bindings)
+
+	query, _ = g.AddV("person").Property(List, "nick", "mo").Build()
+	assert.Equal("g.addV(_p0).property(list, _p1, _p2)", query)
+}
+
+func TestBuildInjection(t *testing.T) {
+	assert := assert.New(t)
+
+	// values never end up in the script
+	evil := "x').drop().V('"
+	query, bindings := G().V(evil).Build()
+	assert.Equal("g.V(_p0)", query)
+	assert.Equal(evil, bindings["_p0"])
+}
+
+func TestBuildAnonymous(t *testing.T) {
+	assert := assert.New(t)
+
+	g := G()
+	query, bindings := g.V().Has("person", "name", "marko").
+		Fold().
+		Coalesce(Anon().Unfold(), Anon().AddV("person").Property("name", "marko")).
+		Build()
+	assert.Equal("g.V().has(_p0, _p1, _p2).fold().coalesce(__.unfold(), __.addV(_p3).property(_p4, _p5))", query)
+	assert.Equal(6, len(bindings))
+	assert.Equal("person", bindings["_p3"])
+
+	query, _ = g.V("a").AddE("knows").To(g.V("b")).Build()
+	assert.Equal("g.V(_p0).addE(_p1).to(g.V(_p2))", query)
+
+	query, _ = g.V().Repeat(Anon().Out()).Times(2).Path().Build()
+	assert.Equal("g.V().repeat(__.out()).times(_p0).path()", query)
+
+	assert.Equal("g.V().outE(_p0).where(__.inV().hasId(_p1)).drop()", g.V().OutE("e").Where(Anon().InV().HasId("b")).Drop().String())
+}
+
+func TestBuildAdd(t *testing.T) {
+	assert := assert.New(t)
+
+	tr := NewSource("t").V().Add("hasNot", "age")
+	assert.Equal("t", tr.Source())
+	assert.Equal([]Step{{Name: "V"}, {Name: "hasNot", Args: []interface{}{"age"}}}, tr.Steps())
+	assert.Equal("t.V().hasNot(_p0)", tr.String())
+}
+
+func TestBytecode(t *testing.T) {
+	assert := assert.New(t)
+
+	b := G().V().Has("age", Gt(30).And(Lt(40))).Where(Anon().Out("knows")).Order().By("age", Decr).Bytecode()
+	assert.Equal(&Bytecode{Steps: []Step{
+		{Name: "V", Args: []interface{}{}},
+		{Name: "has", Args: []interface{}{"age", P{Operator: "and", Values: []interface{}{Gt(30), Lt(40)}}}},
+		{Name: "where", Args: []interface{}{&Bytecode{Steps: []Step{{Name: "out", Args: []interface{}{"knows"}}}}}},
+		{Name: "order", Args: []interface{}{}},
+		{Name: "by", Args: []interface{}{"age", Decr}},
+	}}, b)
+
+	// nested traversals inside predicates become bytecode too
+	b = Anon().Is(P{Operator: "eq", Values: []interface{}{Anon().Count()}}).Bytecode()
+	assert.Equal(&Bytecode{Steps: []Step{{Name: "count", Args: []interface{}{}}}}, b.Steps[0].Args[0].(P).Values[0])
+}
+
+func TestTokenEnum(t *testing.T) {
+	assert := assert.New(t)
+
+	for token, enum := range map[Token][2]string{
+		TId:    {"T", "id"},
+		TLabel: {"T", "label"},
+		Single: {"Cardinality", "single"},
+		Decr:   {"Order", "decr"},
+		Local:  {"Scope", "local"},
+		Keys:   {"Column", "keys"},
+	} {
+		typ, name := token.Enum()
+		assert.Equal(enum, [2]string{typ, name})
+	}
+
+	name, text := TextContains("a").Predicate()
+	assert.Equal("containing", name)
+	assert.True(text)
+	name, text = Gt(1).Predicate()
+	assert.Equal("gt", name)
+	assert.False(text)
+}
@@ -2,6 +2,7 @@ package gremgoser
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -12,6 +13,11 @@ import (
 
 var (
 	ErrorInterfaceHasNoIdField       = errors.New("gremgoser: the passed interface must have an Id field")
+	ErrorNotStructPointer            = errors.New("gremgoser: the passed interface must be a pointer to a struct")
+	ErrorEdgeHasNoLabel              = errors.New("gremgoser: the passed edge must have a string field tagged label")
+	ErrorEdgeHasNoVertices           = errors.New("gremgoser: the passed edge must have fields tagged outV and inV")
+	ErrorNotSlice                    = errors.New("gremgoser: the passed interface must be a slice")
+	ErrorUnsupportedIdType           = errors.New("gremgoser: the id must be a uuid, string, integer or text marshaler")
 	ErrorNoGraphTags                 = errors.New("gremgoser: the passed interface has no graph tags")
 	ErrorUnsupportedPropertyMap      = errors.New("gremgoser: unsupported property map")
 	ErrorCannotCastProperty          = errors.New("gremgoser: passed property cannot be cast")
@@ -19,6 +25,14 @@ var (
 	ErrorWSConnectionNil             = errors.New("gremgoser: error websocket connection nil")
 	ErrorConnectionDisposed          = errors.New("gremgoser: you cannot write on a disposed connection")
 	ErrorInvalidURI                  = errors.New("gremgoser: invalid uri supplied in config")
+	ErrorNoConnection                = errors.New("gremgoser: no connection available in the pool")
+	ErrorMissedPong                  = errors.New("gremgoser: server did not answer the ping")
+	ErrorReconnectFailed             = errors.New("gremgoser: connection lost and reconnecting failed")
+	ErrorConnectionLost              = errors.New("gremgoser: the connection was lost while the request was in flight")
+	ErrorResponseTimeout             = errors.New("gremgoser: timed out waiting for the response")
+	ErrorNotFound                    = errors.New("gremgoser: the query returned no result")
+	ErrorSessionClosed               = errors.New("gremgoser: the session is closed")
+	ErrorSessionLost                 = errors.New("gremgoser: the connection of the session was lost")
 	ErrorNoAuth                      = errors.New("gremgoser: client does not have a secure dialer for authentication with the server")
 	Error401Unauthorized             = errors.New("gremgoser: UNAUTHORIZED")
 	Error407Authenticate             = errors.New("gremgoser: AUTHENTICATE")
@@ -33,29 +47,89 @@ var (
 
 // ClientConfig configs a client
 type ClientConfig struct {
-	URI          string
-	AuthReq      *GremlinRequest
-	Debug        bool
-	Verbose      bool
-	VeryVerbose  bool
-	Timeout      time.Duration
-	PingInterval time.Duration
-	WritingWait  time.Duration
-	ReadingWait  time.Duration
-	Logger       *logger.Logger
+	URI                 string
+	AuthReq             *GremlinRequest
+	Debug               bool
+	Verbose             bool
+	VeryVerbose         bool
+	Timeout             time.Duration
+	PingInterval        time.Duration
+	WritingWait         time.Duration
+	ReadingWait         time.Duration
+	PoolSize            int
+	MinIdle             int
+	MaxInFlight         int
+	ReconnectBackoff    time.Duration
+	ReconnectMaxBackoff time.Duration
+	ReconnectAttempts   int
+	ReplayIdempotent    bool
+	DisableBindings     bool
+	IdToken             bool
+	Serializer          Serializer
+	TimeFormat          TimeFormat
+	BatchSize           int
+	BatchConcurrency    int
+	OnStateChange       func(ConnectionState)
+	Logger              *logger.Logger
+}
+
+// Serializer is the format requests and responses are exchanged in with Gremlin Server, named by its mime type
+type Serializer string
+
+const (
+	SerializerGraphSONv2  Serializer = "application/vnd.gremlin-v2.0+json"
+	SerializerGraphSONv3  Serializer = "application/vnd.gremlin-v3.0+json"
+	SerializerGraphBinary Serializer = "application/vnd.graphbinary-v1.0"
+)
+
+// TimeFormat is how the struct helpers write time.Time fields tagged with the time option
+type TimeFormat int
+
+const (
+	TimeFormatMillis  TimeFormat = iota + 1 // milliseconds since the Unix epoch, the default
+	TimeFormatRFC3339                       // RFC 3339 strings with nanoseconds
+)
+
+// ConnectionState is the state of the client connections reported to ClientConfig.OnStateChange
+type ConnectionState int
+
+const (
+	StateConnecting ConnectionState = iota + 1
+	StateConnected
+	StateReconnecting
+	StateDisconnected
+	StateClosed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateDisconnected:
+		return "disconnected"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
 }
 
 // Client is a container for the gremgoser client.
 type Client struct {
 	conf             *ClientConfig
-	conn             dialer
-	requests         chan []byte
-	responses        chan []byte
+	pool             *pool
 	errs             chan error
 	results          *sync.Map
+	values           *sync.Map // values holds the GraphSON decoded results next to the raw results
+	diagnostics      *sync.Map // diagnostics holds the status attributes of the frames of a request aggregated
+	charge           requestCharge
 	responseNotifier *sync.Map // responseNotifier notifies the requester that a response has arrived for the request
 	respMutex        *sync.Mutex
-	Errored          bool
+	Errored          bool // Errored is set under the lock of the pool once a connection failed, read it with HasErrored
 }
 
 // Ws is the dialer for a WebSocket connection
@@ -70,7 +144,10 @@ type Ws struct {
 	writingWait  time.Duration
 	readingWait  time.Duration
 	timeout      time.Duration
+	lastPong     time.Time
+	handling     bool // handling is set while the reader handles a message and cannot read pongs
 	quit         chan struct{}
+	writeMutex   sync.Mutex // writeMutex serializes writes, the websocket conn supports a single concurrent writer
 	sync.RWMutex
 	logger *logger.Logger
 }
@@ -96,33 +173,122 @@ type GremlinStatus struct {
 }
 
 type GremlinStatusAttributes struct {
-	XMsStatusCode         int       `json:"x-ms-status-code"`
-	XMsRequestCharge      float32   `json:"x-ms-request-charge"`
-	XMsTotalRequestCharge float32   `json:"x-ms-total-request-charge"`
-	XMsServerTimeMs       float32   `json:"x-ms-server-time-ms"`
-	XMsTotalServerTimeMs  float32   `json:"x-ms-total-server-time-ms"`
-	XMsActivityId         uuid.UUID `json:"x-ms-activity-id"`
+	XMsStatusCode         int                    `json:"x-ms-status-code"`
+	XMsRequestCharge      float32                `json:"x-ms-request-charge"`
+	XMsTotalRequestCharge float32                `json:"x-ms-total-request-charge"`
+	XMsServerTimeMs       float32                `json:"x-ms-server-time-ms"`
+	XMsTotalServerTimeMs  float32                `json:"x-ms-total-server-time-ms"`
+	XMsActivityId         uuid.UUID              `json:"x-ms-activity-id"`
+	Raw                   map[string]interface{} `json:"-"` // Raw holds every attribute sent by the server
+}
+
+// Result is the response to a request with the diagnostics Cosmos DB sends in the status attributes, aggregated
+// over the partial frames of the response
+type Result struct {
+	Data          []*GremlinRespData
+	Values        []interface{} // Values holds the results with GraphSON types decoded like ExecuteValues returns them
+	RequestCharge float64       // request units charged for the request
+	ServerTimeMs  float64       // time the server spent on the request in milliseconds
+	ActivityId    uuid.UUID     // activity id of the request for Cosmos DB support, the last one sent
+	StatusCode    int           // x-ms-status-code of the last frame, the Gremlin status code when it is not sent
+	Frames        int           // number of frames the response was sent in
+}
+
+// BatchResult is the outcome of one element of AddVBatch or AddEBatch
+type BatchResult struct {
+	Index int              // index of the element in the passed slice
+	Data  *GremlinRespData // the added element as returned by the server, nil when Err is set
+	Err   error
+}
+
+// GremlinError is returned when Gremlin Server answers a request with an error status. It wraps the sentinel
+// error of the status code, so errors.Is(err, Error597ScriptEvaluationError) keeps working.
+type GremlinError struct {
+	Code       int
+	Message    string
+	RequestId  uuid.UUID
+	Attributes GremlinStatusAttributes
+	err        error
+}
+
+func (e *GremlinError) Error() string {
+	if e.Message == "" {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.err, e.Message)
+}
+
+// Unwrap returns the sentinel error of the status code
+func (e *GremlinError) Unwrap() error {
+	return e.err
 }
 
 type GremlinResult struct {
-	Data []*GremlinRespData `json:"data"`
-	Meta interface{}        `json:"meta"`
+	Data   []*GremlinRespData `json:"data"`
+	Meta   interface{}        `json:"meta"`
+	Values []interface{}      `json:"-"` // Values holds every result with GraphSON types decoded to Go types
 }
 
 type GremlinRespData map[string]interface{}
 
+// GremlinData is an element of a response, the ids are kept as sent by the server since graphs use strings,
+// numbers or typed GraphSON values for them
 type GremlinData struct {
-	Id         uuid.UUID              `json:"id"`
+	Id         interface{}            `json:"id"`
 	Label      string                 `json:"label"`
 	Type       string                 `json:"type"`
 	InVLabel   string                 `json:"inVLabel"`
 	OutVLabel  string                 `json:"outVLabel"`
-	InV        uuid.UUID              `json"inV"`
-	OutV       uuid.UUID              `json"outV"`
+	InV        interface{}            `json:"inV"`
+	OutV       interface{}            `json:"outV"`
 	Properties map[string]interface{} `json:"properties"`
 }
 
 type GremlinProperty struct {
-	Id    uuid.UUID   `json:"id"`
+	Id    interface{} `json:"id"`
 	Value interface{} `json:"value"`
 }
+
+// Vertex is a vertex decoded from a GraphSON g:Vertex
+type Vertex struct {
+	Id         interface{}
+	Label      string
+	Properties map[string][]*VertexProperty
+}
+
+// Edge is an edge decoded from a GraphSON g:Edge
+type Edge struct {
+	Id         interface{}
+	Label      string
+	InV        interface{}
+	InVLabel   string
+	OutV       interface{}
+	OutVLabel  string
+	Properties map[string]*Property
+}
+
+// VertexProperty is a vertex property decoded from a GraphSON g:VertexProperty, Properties holds its meta properties
+type VertexProperty struct {
+	Id         interface{}
+	Label      string
+	Value      interface{}
+	Properties map[string]interface{}
+}
+
+// Property is an edge or meta property decoded from a GraphSON g:Property
+type Property struct {
+	Key   string
+	Value interface{}
+}
+
+// Path is a path decoded from a GraphSON g:Path, Labels holds the step labels of each object
+type Path struct {
+	Labels  [][]string
+	Objects []interface{}
+}
+
+// Traverser is a GraphSON g:Traverser, a value that was reached Bulk times
+type Traverser struct {
+	Bulk  int64
+	Value interface{}
+}
@@ -0,0 +1,149 @@
+package gremgoser
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type Tenant struct {
+	Id     uuid.UUID `graph:"id,string"`
+	Pk     string    `graph:"pk,partitionKey"`
+	Name   string    `graph:"name,string"`
+	Tags   []string  `graph:"tags,[]string"`
+	Visits int       `graph:"visits,number"`
+}
+
+func TestUpsertVScript(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(nil)
+	c.conf = &ClientConfig{}
+	c.conf.SetDisableBindings()
+
+	v := Tenant{Id: uuid.MustParse("64795211-c4a1-4eac-9e0a-b674ced77461"), Pk: "p", Name: "it's", Tags: []string{"a", "b"}, Visits: 3}
+	q := c.newScript()
+	id, err := q.id(v.Id)
+	assert.Nil(err)
+	assert.Nil(codecOf(reflect.TypeOf(v)).upsertV(q, "tenant", id, reflect.ValueOf(v)))
+	assert.Equal(`g.V('64795211-c4a1-4eac-9e0a-b674ced77461').has('pk', 'p').fold().coalesce(unfold(), addV('tenant').property('id', '64795211-c4a1-4eac-9e0a-b674ced77461').property('pk', 'p')).property('name', 'it\'s').sideEffect(properties('tags').drop()).property(list, 'tags', 'a').property(list, 'tags', 'b').property('visits', 3)`, q.String())
+
+	// the id is bound once and vertices without partition key are keyed on their id only
+	c.conf.DisableBindings = false
+	q = c.newScript()
+	id, err = q.id(42)
+	assert.Nil(err)
+	assert.Nil(codecOf(reflect.TypeOf(IntPerson{})).upsertV(q, "person", id, reflect.ValueOf(IntPerson{Key: 42, Name: "ann"})))
+	assert.Equal("g.V(_p0).fold().coalesce(unfold(), addV(_p1).property('id', _p0)).property('name', _p2)", q.String())
+	assert.Equal(map[string]interface{}{"_p0": int64(42), "_p1": "person", "_p2": "ann"}, q.params())
+
+	// servers like TinkerGraph get the id with the id token
+	c.conf.SetIdToken()
+	q = c.newScript()
+	id, err = q.id(42)
+	assert.Nil(err)
+	assert.Nil(codecOf(reflect.TypeOf(IntPerson{})).upsertV(q, "person", id, reflect.ValueOf(IntPerson{Key: 42, Name: "ann"})))
+	assert.Equal("g.V(_p0).fold().coalesce(unfold(), addV(_p1).property(id, _p0)).property('name', _p2)", q.String())
+}
+
+func TestUpsertV(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newIDMock()
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	// Convert http://127.0.0.1 to ws://127.0.0.
+	u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	g, errs := NewClient(NewClientConfig(u))
+	assert.NotNil(g)
+	defer g.Close()
+
+	// setup err channel
+	go func(chan error) {
+		err := <-errs
+		assert.Nil(err)
+	}(errs)
+
+	// the resulting vertex is decoded into the struct
+	p := &IntPerson{Key: 42, Name: "bob"}
+	assert.Nil(g.UpsertV("person", p))
+	assert.Equal(IntPerson{Key: 42, Name: "ann"}, *p)
+
+	m.mu.Lock()
+	assert.Equal([]string{"g.V(42).fold().coalesce(unfold(), addV('person').property('id', 42)).property('name', 'bob')"}, m.scripts)
+	m.mu.Unlock()
+
+	// the struct is passed by pointer and needs an id
+	assert.Equal(ErrorNotStructPointer, g.UpsertV("person", IntPerson{Key: 42}))
+	assert.Equal(ErrorNotStructPointer, g.UpsertV("person", (*IntPerson)(nil)))
+	assert.Equal(ErrorInterfaceHasNoIdField, g.UpsertV("person", &IntPerson{Name: "new"}))
+	assert.Equal(ErrorInterfaceHasNoIdField, g.UpsertV("person", &struct{ A string }{}))
+}
+
+// tinkerMock answers upserts like TinkerGraph, g.V(id) only finds vertices whose id was set with T.id while the
+// 'id' property adds a vertex with an id of its own
+func tinkerMock(vertices map[string]bool) *recordingMock {
+	var mu sync.Mutex
+	next := 100
+	lookup := regexp.MustCompile(`^g\.V\((\d+)\)`)
+	return &recordingMock{answer: func(gremlin string) (int, []interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		id := lookup.FindStringSubmatch(gremlin)[1]
+		if !vertices[id] {
+			if !strings.Contains(gremlin, ".property(id, "+id+")") {
+				next++
+				id = strconv.Itoa(next)
+			}
+			vertices[id] = true
+		}
+		return 200, []interface{}{map[string]interface{}{"id": json.Number(id), "label": "person", "type": "vertex"}}
+	}}
+}
+
+// TestUpsertVIdToken tests that upserting twice on a server taking ids from T.id adds the vertex once
+func TestUpsertVIdToken(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, idToken := range []bool{false, true} {
+		vertices := map[string]bool{}
+		s := httptest.NewServer(tinkerMock(vertices))
+
+		// Convert http://127.0.0.1 to ws://127.0.0.
+		u := "ws" + strings.TrimPrefix(s.URL, "http")
+
+		conf := NewClientConfig(u)
+		conf.SetDisableBindings()
+		if idToken {
+			conf.SetIdToken()
+		}
+		g, errs := NewClient(conf)
+		assert.NotNil(g)
+
+		// setup err channel
+		go func(chan error) {
+			err := <-errs
+			assert.Nil(err)
+		}(errs)
+
+		assert.Nil(g.UpsertV("person", &IntPerson{Key: 42, Name: "ann"}))
+		assert.Nil(g.UpsertV("person", &IntPerson{Key: 42, Name: "ann"}))
+		if idToken {
+			assert.Equal(map[string]bool{"42": true}, vertices)
+		} else {
+			assert.Equal(2, len(vertices), "the 'id' property duplicates the vertex")
+		}
+		g.Close()
+		s.Close()
+	}
+}